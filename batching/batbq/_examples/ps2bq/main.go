@@ -37,6 +37,9 @@ func NewClickMessage(m *pubsub.Message) (*ClickMessage, error) {
 // Ack acks the underlying pubsub.Message.
 func (c *ClickMessage) Ack() { c.m.Ack() }
 
+// Key returns the pubsub message ID to deduplicate redelivered messages.
+func (c *ClickMessage) Key() string { return c.m.ID }
+
 // Nack prints the error.
 func (c *ClickMessage) Nack(err error) {
 	if err != nil {
@@ -89,6 +92,7 @@ func main() {
 		table       = flag.String("table", "clicks", "Table Name")
 		dry         = flag.Bool("dry", false, "setup pipeline but do not run the batcher")
 		stats       = flag.Bool("stats", false, "print metrics on the console")
+		record      = flag.String("record", "", "append metrics recordings to a file, requires -stats")
 		cap         = flag.Int("cap", 1000, "batch capacity")
 		concurrency = flag.Int("c", 1, "number of independent batchers")
 	)
@@ -137,10 +141,16 @@ func main() {
 		batcherIDs = append(batcherIDs, "click")
 	}
 
-	mb := multibatcher.NewMultiBatcher(batcherIDs, batbq.Config(cfg))
+	mb := multibatcher.NewMultiBatcher(batcherIDs, batbq.Config(cfg), batbq.DedupConfig{})
 
 	if *stats {
-		metrics.Watch(ctx, mb.Metrics)
+		opts := []metrics.WatchOption{metrics.ClearScreen(true)}
+		if *record != "" {
+			opts = append(opts, metrics.RecordFile(*record))
+		}
+		if err := metrics.Watch(ctx, mb.Metrics, opts...); err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	if *dry {
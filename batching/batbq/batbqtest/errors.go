@@ -0,0 +1,34 @@
+package batbqtest
+
+import (
+	"net/http"
+
+	"google.golang.org/api/googleapi"
+)
+
+// QuotaExceeded returns an error as returned by BigQuery if the streaming insert quota is exceeded.
+func QuotaExceeded() error {
+	return &googleapi.Error{
+		Code:    http.StatusForbidden,
+		Message: "Exceeded rate limits: too many rows present in the request",
+		Errors:  []googleapi.ErrorItem{{Reason: "quotaExceeded"}},
+	}
+}
+
+// RateLimitExceeded returns an error as returned by BigQuery if the API rate limit is exceeded.
+func RateLimitExceeded() error {
+	return &googleapi.Error{
+		Code:    http.StatusForbidden,
+		Message: "Exceeded rate limits: too many api requests per user per method",
+		Errors:  []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}},
+	}
+}
+
+// Unavailable returns an error as returned by BigQuery if the backend is unavailable.
+func Unavailable() error {
+	return &googleapi.Error{
+		Code:    http.StatusServiceUnavailable,
+		Message: "Service unavailable",
+		Errors:  []googleapi.ErrorItem{{Reason: "backendError"}},
+	}
+}
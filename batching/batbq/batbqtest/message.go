@@ -0,0 +1,158 @@
+package batbqtest
+
+import (
+	"sort"
+	"sync"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/batching/batbq"
+)
+
+// Message implements the `batbq.Message` and the `bigquery.ValueSaver` and reports its
+// confirmation to the Recorder that created it.
+type Message struct {
+	ID     string
+	Values map[string]bigquery.Value
+	rec    *Recorder
+}
+
+// Data returns the message itself as ValueSaver.
+func (m *Message) Data() bigquery.ValueSaver { return m }
+
+// Key returns the message ID as key for the `batbq.KeyedMessage`.
+func (m *Message) Key() string { return m.ID }
+
+// Save implements the `bigquery.ValueSaver` using the message ID as insert ID.
+func (m *Message) Save() (map[string]bigquery.Value, string, error) {
+	return m.Values, m.ID, nil
+}
+
+// Ack records the acknowledgement.
+func (m *Message) Ack() { m.rec.confirm(m.ID, true, nil) }
+
+// Nack records the negative acknowledgement and the error.
+func (m *Message) Nack(err error) { m.rec.confirm(m.ID, false, err) }
+
+// Confirmation stores how a message was confirmed.
+type Confirmation struct {
+	Acks  int
+	Nacks int
+	Err   error // error of the last Nack
+}
+
+// Recorder creates messages and records their confirmations.
+type Recorder struct {
+	mu      sync.Mutex
+	msgs    []*Message
+	results map[string]*Confirmation
+}
+
+// NewRecorder returns a new Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{results: make(map[string]*Confirmation)}
+}
+
+// Message creates a new message. Messages with the same ID are considered as redelivered
+// messages and share their Confirmation.
+func (r *Recorder) Message(id string, values map[string]bigquery.Value) *Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m := &Message{ID: id, Values: values, rec: r}
+	r.msgs = append(r.msgs, m)
+	if _, ok := r.results[id]; !ok {
+		r.results[id] = &Confirmation{}
+	}
+	return m
+}
+
+func (r *Recorder) confirm(id string, ack bool, err error) {
+	if r == nil {
+		// allow using messages without a Recorder
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.results[id]
+	if !ok {
+		c = &Confirmation{}
+		r.results[id] = c
+	}
+	if ack {
+		c.Acks++
+		return
+	}
+	c.Nacks++
+	c.Err = err
+}
+
+// Confirmation returns the confirmation status of a message.
+func (r *Recorder) Confirmation(id string) Confirmation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.results[id]; ok {
+		return *c
+	}
+	return Confirmation{}
+}
+
+func (r *Recorder) filter(match func(c *Confirmation) bool) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := make([]string, 0)
+	for id, c := range r.results {
+		if match(c) {
+			res = append(res, id)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Acked returns the sorted IDs of all acked messages.
+func (r *Recorder) Acked() []string {
+	return r.filter(func(c *Confirmation) bool { return c.Acks > 0 })
+}
+
+// Nacked returns the sorted IDs of all nacked messages.
+func (r *Recorder) Nacked() []string {
+	return r.filter(func(c *Confirmation) bool { return c.Nacks > 0 })
+}
+
+// Unconfirmed returns the sorted IDs of all messages that were neither acked nor nacked.
+func (r *Recorder) Unconfirmed() []string {
+	return r.filter(func(c *Confirmation) bool { return c.Acks+c.Nacks == 0 })
+}
+
+// Chan returns a closed, buffered input channel that provides all created messages.
+func (r *Recorder) Chan() <-chan batbq.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ch := make(chan batbq.Message, len(r.msgs))
+	for _, m := range r.msgs {
+		ch <- m
+	}
+	close(ch)
+	return ch
+}
+
+// AssertAcked asserts that exactly the given messages were acked.
+func (r *Recorder) AssertAcked(t assert.TestingT, ids ...string) bool {
+	return assert.ElementsMatch(t, ids, r.Acked(), "acked messages")
+}
+
+// AssertNacked asserts that exactly the given messages were nacked.
+func (r *Recorder) AssertNacked(t assert.TestingT, ids ...string) bool {
+	return assert.ElementsMatch(t, ids, r.Nacked(), "nacked messages")
+}
+
+// AssertConfirmedOnce asserts that every created message was either acked or nacked exactly once.
+func (r *Recorder) AssertConfirmedOnce(t assert.TestingT) bool {
+	ok := true
+	for _, id := range r.filter(func(c *Confirmation) bool { return c.Acks+c.Nacks != 1 }) {
+		c := r.Confirmation(id)
+		ok = assert.Fail(t, "message not confirmed exactly once",
+			"id=%s acks=%d nacks=%d", id, c.Acks, c.Nacks)
+	}
+	return ok
+}
@@ -0,0 +1,236 @@
+// Package batbqtest provides an in-process BigQuery stand-in for testing batbq pipelines.
+package batbqtest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/ubntc/go/batching/batbq"
+)
+
+// Row stores an inserted row.
+type Row struct {
+	InsertID string
+	Values   map[string]bigquery.Value
+}
+
+// Putter is a fake `bigquery.Inserter` that stores rows in memory.
+//
+// The exported fields must be set before the Putter is used. Faults can also be injected
+// later using `FailNext`, which is safe for concurrent use.
+type Putter struct {
+	Table    string
+	Schema   bigquery.Schema        // validates rows if set, invalid rows fail with a PutMultiError
+	Latency  time.Duration          // simulated insert latency
+	RowError func(row Row) error    // optional, fails single rows with a PutMultiError
+	OnPut    func(rows []Row) error // optional, called before storing the rows
+
+	mu       sync.Mutex
+	rows     []Row
+	faults   []error
+	calls    int
+	rejected int
+}
+
+// NewPutter returns a Putter for the given table and optional schema.
+func NewPutter(table string, schema bigquery.Schema) *Putter {
+	return &Putter{Table: table, Schema: schema}
+}
+
+// Put implements the batbq.Putter. It accepts a `bigquery.ValueSaver` or a slice of them.
+func (p *Putter) Put(ctx context.Context, src any) error {
+	rows, err := saveRows(src)
+	if err != nil {
+		return err
+	}
+
+	if p.Latency > 0 {
+		timer := time.NewTimer(p.Latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+
+	if len(p.faults) > 0 {
+		err := p.faults[0]
+		p.faults = p.faults[1:]
+		if err != nil {
+			p.rejected += len(rows)
+			return err
+		}
+	}
+
+	if p.OnPut != nil {
+		if err := p.OnPut(rows); err != nil {
+			p.rejected += len(rows)
+			return err
+		}
+	}
+
+	var errs bigquery.PutMultiError
+	for i, row := range rows {
+		var rowErrs bigquery.MultiError
+		if p.Schema != nil {
+			rowErrs = append(rowErrs, Validate(p.Schema, row.Values)...)
+		}
+		if p.RowError != nil {
+			if err := p.RowError(row); err != nil {
+				rowErrs = append(rowErrs, err)
+			}
+		}
+		if len(rowErrs) > 0 {
+			errs = append(errs, bigquery.RowInsertionError{
+				RowIndex: i,
+				InsertID: row.InsertID,
+				Errors:   rowErrs,
+			})
+			continue
+		}
+		p.rows = append(p.rows, row)
+	}
+
+	if len(errs) > 0 {
+		p.rejected += len(errs)
+		return errs
+	}
+	return nil
+}
+
+// FailNext makes the next `n` calls to `Put` fail with the given error without storing any rows.
+// Use a `context.Canceled` error to simulate a canceled insert or `QuotaExceeded()` to simulate
+// a rejected insert.
+func (p *Putter) FailNext(n int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := 0; i < n; i++ {
+		p.faults = append(p.faults, err)
+	}
+}
+
+// Rows returns a copy of the stored rows.
+func (p *Putter) Rows() []Row {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Row(nil), p.rows...)
+}
+
+// Len returns the number of stored rows.
+func (p *Putter) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.rows)
+}
+
+// InsertIDs returns the insert IDs of the stored rows in insertion order.
+func (p *Putter) InsertIDs() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ids := make([]string, len(p.rows))
+	for i, r := range p.rows {
+		ids[i] = r.InsertID
+	}
+	return ids
+}
+
+// Calls returns the number of calls to `Put`.
+func (p *Putter) Calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+// Rejected returns the number of rows that were not stored due to errors.
+func (p *Putter) Rejected() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.rejected
+}
+
+func saveRows(src any) ([]Row, error) {
+	var savers []bigquery.ValueSaver
+	switch v := src.(type) {
+	case bigquery.ValueSaver:
+		savers = []bigquery.ValueSaver{v}
+	case []bigquery.ValueSaver:
+		savers = v
+	default:
+		return nil, fmt.Errorf("batbqtest: %T is not a ValueSaver or []ValueSaver", src)
+	}
+	rows := make([]Row, len(savers))
+	for i, s := range savers {
+		values, insertID, err := s.Save()
+		if err != nil {
+			return nil, err
+		}
+		rows[i] = Row{InsertID: insertID, Values: values}
+	}
+	return rows, nil
+}
+
+// Dataset stores fake tables by name.
+type Dataset struct {
+	mu     sync.Mutex
+	schema map[string]bigquery.Schema
+	tables map[string]*Putter
+}
+
+// NewDataset returns an empty Dataset.
+func NewDataset() *Dataset {
+	return &Dataset{
+		schema: make(map[string]bigquery.Schema),
+		tables: make(map[string]*Putter),
+	}
+}
+
+// SetSchema sets the schema for a table. It must be called before the table is first used.
+func (ds *Dataset) SetSchema(table string, schema bigquery.Schema) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.schema[table] = schema
+}
+
+// Table returns the Putter for a table and creates it if needed.
+func (ds *Dataset) Table(table string) *Putter {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	p, ok := ds.tables[table]
+	if !ok {
+		p = NewPutter(table, ds.schema[table])
+		ds.tables[table] = p
+	}
+	return p
+}
+
+// Output returns the Putter for a table as batbq.Putter.
+// It implements the `multibatcher.OutputGetter`.
+func (ds *Dataset) Output(table string) batbq.Putter {
+	return ds.Table(table)
+}
+
+// Tables returns the sorted names of all created tables.
+func (ds *Dataset) Tables() []string {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	names := make([]string, 0, len(ds.tables))
+	for name := range ds.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Rows returns a copy of the rows stored in a table.
+func (ds *Dataset) Rows(table string) []Row {
+	return ds.Table(table).Rows()
+}
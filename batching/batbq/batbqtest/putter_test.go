@@ -0,0 +1,178 @@
+package batbqtest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/batching/batbq"
+	"github.com/ubntc/go/batching/batbq/batbqtest"
+	"github.com/ubntc/go/batching/batbq/config"
+)
+
+var testSchema = bigquery.Schema{
+	{Name: "name", Type: bigquery.StringFieldType, Required: true},
+	{Name: "val", Type: bigquery.IntegerFieldType},
+}
+
+var testConfig = batbq.Config(config.BatcherConfig{Capacity: 10, FlushInterval: 10 * time.Millisecond})
+
+func messages(rec *batbqtest.Recorder, n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprint("m", i)
+		rec.Message(ids[i], map[string]bigquery.Value{"name": ids[i], "val": i})
+	}
+	return ids
+}
+
+func process(rec *batbqtest.Recorder, p *batbqtest.Putter) {
+	ins := batbq.NewInsertBatcher("test", testConfig)
+	ins.Process(context.Background(), rec.Chan(), p)
+}
+
+func TestPutter(t *testing.T) {
+	rec := batbqtest.NewRecorder()
+	ids := messages(rec, 25)
+	p := batbqtest.NewPutter("table", testSchema)
+
+	process(rec, p)
+
+	assert.Equal(t, 25, p.Len())
+	assert.Equal(t, 3, p.Calls())
+	assert.ElementsMatch(t, ids, p.InsertIDs())
+	rec.AssertAcked(t, ids...)
+	rec.AssertNacked(t)
+	rec.AssertConfirmedOnce(t)
+}
+
+func TestRowErrors(t *testing.T) {
+	rec := batbqtest.NewRecorder()
+	messages(rec, 5)
+	p := batbqtest.NewPutter("table", testSchema)
+	p.RowError = func(row batbqtest.Row) error {
+		if row.InsertID == "m3" {
+			return errors.New("row error")
+		}
+		return nil
+	}
+
+	process(rec, p)
+
+	assert.Equal(t, 4, p.Len())
+	assert.Equal(t, 1, p.Rejected())
+	rec.AssertAcked(t, "m0", "m1", "m2", "m4")
+	rec.AssertNacked(t, "m3")
+	assert.Error(t, rec.Confirmation("m3").Err)
+}
+
+func TestSchemaValidation(t *testing.T) {
+	rec := batbqtest.NewRecorder()
+	rec.Message("ok", map[string]bigquery.Value{"name": "ok", "val": 1})
+	rec.Message("missing", map[string]bigquery.Value{"val": 1})
+	rec.Message("unknown", map[string]bigquery.Value{"name": "x", "foo": 1})
+	rec.Message("type", map[string]bigquery.Value{"name": "x", "val": "one"})
+	p := batbqtest.NewPutter("table", testSchema)
+
+	process(rec, p)
+
+	rec.AssertAcked(t, "ok")
+	rec.AssertNacked(t, "missing", "unknown", "type")
+
+	var bqErr *bigquery.Error
+	assert.ErrorAs(t, rec.Confirmation("type").Err.(bigquery.MultiError)[0], &bqErr)
+	assert.Equal(t, "val", bqErr.Location)
+	assert.Equal(t, "invalid", bqErr.Reason)
+}
+
+func TestValidate(t *testing.T) {
+	schema := bigquery.Schema{
+		{Name: "tags", Type: bigquery.StringFieldType, Repeated: true},
+		{Name: "rec", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+			{Name: "ts", Type: bigquery.TimestampFieldType, Required: true},
+		}},
+		{Name: "opt", Type: bigquery.FloatFieldType},
+	}
+	row := map[string]bigquery.Value{
+		"tags": []string{"a", "b"},
+		"rec":  map[string]bigquery.Value{"ts": time.Now()},
+		"opt":  bigquery.NullFloat64{},
+	}
+	assert.Empty(t, batbqtest.Validate(schema, row))
+	row["rec"] = map[string]bigquery.Value{"ts": "2024-01-02T03:04:05.000006Z"}
+	assert.Empty(t, batbqtest.Validate(schema, row), "timestamps can be RFC 3339 strings")
+	row["rec"] = map[string]bigquery.Value{"ts": "yesterday"}
+	assert.Len(t, batbqtest.Validate(schema, row), 1)
+
+	row["tags"] = []any{"a", 1}
+	row["rec"] = map[string]bigquery.Value{}
+	errs := batbqtest.Validate(schema, row)
+	assert.Len(t, errs, 2)
+}
+
+func TestFaults(t *testing.T) {
+	cases := map[string]struct {
+		err    error
+		expErr error
+	}{
+		"quota":    {batbqtest.QuotaExceeded(), batbqtest.QuotaExceeded()},
+		"canceled": {context.Canceled, nil},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			rec := batbqtest.NewRecorder()
+			ids := messages(rec, 10)
+			p := batbqtest.NewPutter("table", nil)
+			p.FailNext(1, c.err)
+
+			process(rec, p)
+
+			assert.Equal(t, 0, p.Len())
+			assert.Equal(t, 10, p.Rejected())
+			rec.AssertNacked(t, ids...)
+			rec.AssertAcked(t)
+			assert.Equal(t, c.expErr, rec.Confirmation(ids[0]).Err)
+
+			// the fault is consumed and the next batch is stored
+			rec = batbqtest.NewRecorder()
+			ids = messages(rec, 10)
+			process(rec, p)
+			assert.Equal(t, 10, p.Len())
+			rec.AssertAcked(t, ids...)
+		})
+	}
+}
+
+func TestLatency(t *testing.T) {
+	p := batbqtest.NewPutter("table", nil)
+	p.Latency = time.Second
+	rec := batbqtest.NewRecorder()
+	m := rec.Message("m", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := p.Put(ctx, []bigquery.ValueSaver{m})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 0, p.Calls())
+}
+
+func TestDataset(t *testing.T) {
+	ds := batbqtest.NewDataset()
+	ds.SetSchema("a", testSchema)
+
+	err := ds.Output("a").Put(context.Background(), []bigquery.ValueSaver{
+		&batbqtest.Message{ID: "1", Values: map[string]bigquery.Value{"name": "x"}},
+	})
+	assert.NoError(t, err)
+	err = ds.Output("b").Put(context.Background(), &batbqtest.Message{ID: "2"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"a", "b"}, ds.Tables())
+	assert.Len(t, ds.Rows("a"), 1)
+	assert.Len(t, ds.Rows("b"), 1)
+	assert.Equal(t, testSchema, ds.Table("a").Schema)
+}
@@ -0,0 +1,172 @@
+package batbqtest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// Validate checks the row values against the schema and returns the errors as reported
+// by BigQuery for invalid rows. Value types that cannot be checked without the BigQuery backend,
+// such as DATE, TIME or GEOGRAPHY values, are accepted as is.
+func Validate(schema bigquery.Schema, values map[string]bigquery.Value) bigquery.MultiError {
+	return validate("", schema, values)
+}
+
+func invalid(location, format string, a ...any) error {
+	return &bigquery.Error{Location: location, Message: fmt.Sprintf(format, a...), Reason: "invalid"}
+}
+
+func validate(prefix string, schema bigquery.Schema, values map[string]bigquery.Value) bigquery.MultiError {
+	var errs bigquery.MultiError
+	known := make(map[string]struct{}, len(schema))
+	for _, f := range schema {
+		known[f.Name] = struct{}{}
+		loc := prefix + f.Name
+		v, ok := values[f.Name]
+		if !ok || isNull(v) {
+			if f.Required {
+				errs = append(errs, invalid(loc, "missing required field: %s", loc))
+			}
+			continue
+		}
+		if !f.Repeated {
+			errs = append(errs, validateValue(loc, f, v)...)
+			continue
+		}
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			errs = append(errs, invalid(loc, "repeated field %s requires an array, got %T", loc, v))
+			continue
+		}
+		for i := 0; i < rv.Len(); i++ {
+			errs = append(errs, validateValue(fmt.Sprintf("%s[%d]", loc, i), f, rv.Index(i).Interface())...)
+		}
+	}
+	for name := range values {
+		if _, ok := known[name]; !ok {
+			errs = append(errs, invalid(prefix+name, "no such field: %s", prefix+name))
+		}
+	}
+	return errs
+}
+
+func validateValue(loc string, f *bigquery.FieldSchema, v any) bigquery.MultiError {
+	if f.Type == bigquery.RecordFieldType {
+		m, ok := v.(map[string]bigquery.Value)
+		if !ok {
+			return bigquery.MultiError{invalid(loc, "record field %s requires a map, got %T", loc, v)}
+		}
+		return validate(loc+".", f.Schema, m)
+	}
+	if !matchesType(f.Type, v) {
+		return bigquery.MultiError{invalid(loc, "cannot convert %T to %s for field %s", v, f.Type, loc)}
+	}
+	return nil
+}
+
+var (
+	typeOfTime = reflect.TypeOf(time.Time{})
+	typeOfRat  = reflect.TypeOf(&big.Rat{})
+
+	typeOfNullInt64 = reflect.TypeOf(bigquery.NullInt64{})
+)
+
+// matchesType reports whether the Go value can be stored in a field of the given type.
+func matchesType(t bigquery.FieldType, v any) bool {
+	switch v.(type) {
+	case bigquery.NullString, bigquery.NullGeography:
+		return t == bigquery.StringFieldType || t == bigquery.GeographyFieldType
+	case bigquery.NullInt64:
+		return t == bigquery.IntegerFieldType
+	case bigquery.NullFloat64:
+		return t == bigquery.FloatFieldType
+	case bigquery.NullBool:
+		return t == bigquery.BooleanFieldType
+	case bigquery.NullTimestamp:
+		return t == bigquery.TimestampFieldType
+	}
+
+	if n, ok := v.(json.Number); ok {
+		// numbers decoded from JSON, e.g., when replaying spooled rows
+		switch t {
+		case bigquery.IntegerFieldType:
+			_, err := n.Int64()
+			return err == nil
+		case bigquery.FloatFieldType, bigquery.NumericFieldType, bigquery.BigNumericFieldType:
+			_, err := n.Float64()
+			return err == nil
+		}
+		return false
+	}
+
+	if s, ok := v.(string); ok {
+		// JSON forms accepted by BigQuery, e.g., when replaying spooled rows
+		switch t {
+		case bigquery.TimestampFieldType:
+			_, err := time.Parse(time.RFC3339Nano, s)
+			return err == nil
+		case bigquery.BytesFieldType:
+			_, err := base64.StdEncoding.DecodeString(s)
+			return err == nil
+		}
+	}
+
+	rv := reflect.ValueOf(v)
+	switch t {
+	case bigquery.StringFieldType:
+		return rv.Kind() == reflect.String
+	case bigquery.BytesFieldType:
+		return rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8
+	case bigquery.IntegerFieldType:
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint8, reflect.Uint16, reflect.Uint32:
+			return true
+		}
+		return false
+	case bigquery.FloatFieldType:
+		switch rv.Kind() {
+		case reflect.Float32, reflect.Float64, reflect.Int, reflect.Int64:
+			return true
+		}
+		return false
+	case bigquery.BooleanFieldType:
+		return rv.Kind() == reflect.Bool
+	case bigquery.TimestampFieldType:
+		return rv.Type() == typeOfTime
+	case bigquery.NumericFieldType, bigquery.BigNumericFieldType:
+		if s, ok := v.(string); ok {
+			// BigQuery accepts decimal strings but no fractions as written by big.Rat.String
+			_, valid := new(big.Rat).SetString(s)
+			return valid && !strings.Contains(s, "/")
+		}
+		return rv.Type() == typeOfRat
+	}
+	return true
+}
+
+// isNull reports whether `v` is nil or an invalid bigquery.Null* value.
+func isNull(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	case reflect.Struct:
+		if rv.Type().PkgPath() != typeOfNullInt64.PkgPath() {
+			return false
+		}
+		valid := rv.FieldByName("Valid")
+		return valid.IsValid() && valid.Kind() == reflect.Bool && !valid.Bool()
+	}
+	return false
+}
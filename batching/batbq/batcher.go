@@ -3,10 +3,13 @@ package batbq
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
 	"sync"
 
 	"github.com/ubntc/go/batching/batbq/config"
 	"github.com/ubntc/go/batching/batbq/scaling"
+	"github.com/ubntc/go/batching/batbq/spool"
 )
 
 // Putter provides a `Put` func as used by the `bigquery.Inserter`.
@@ -22,7 +25,13 @@ type InsertBatcher struct {
 	input   <-chan Message
 	output  Putter
 	scaling scaling.Status
+	spool   *spool.Spool
 	mu      *sync.Mutex
+
+	spoolFactory SpoolFactory
+
+	dedupCfg *DedupConfig
+	dedup    *dedupWindow
 }
 
 // NewInsertBatcher returns an InsertBatcher.
@@ -38,6 +47,9 @@ func NewInsertBatcher(id string, opt ...BatcherOption) *InsertBatcher {
 	if ins.metrics == nil {
 		ins.metrics = NewMetrics()
 	}
+	if ins.dedupCfg != nil {
+		ins.dedup = newDedupWindow(*ins.dedupCfg, ins.metrics, id)
+	}
 	return ins
 }
 
@@ -60,6 +72,34 @@ func (ins *InsertBatcher) Process(ctx context.Context, input <-chan Message, out
 	ins.input = input
 	ins.output = output
 
+	if ins.spoolFactory != nil {
+		s, err := ins.spoolFactory(ins.id)
+		if err != nil {
+			return fmt.Errorf("failed to open spool of batcher %s: %w", ins.id, err)
+		}
+		ins.spool = s
+		defer func() {
+			if err := s.Close(); err != nil {
+				log.Printf("failed to close spool of batcher %s: %v", ins.id, err)
+			}
+			ins.spool = nil
+		}()
+	}
+
+	if ins.spool != nil {
+		// replay spooled rows until all workers are stopped
+		rctx, stop := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			ins.spool.Replay(rctx, output)
+		}()
+		defer func() {
+			stop()
+			<-done
+		}()
+	}
+
 	if ins.cfg.AutoScale {
 		scaling.Autoscale(ctx, &ins.cfg, &ins.scaling, ins.worker)
 		return nil
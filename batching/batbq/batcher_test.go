@@ -3,15 +3,20 @@ package batbq_test
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"cloud.google.com/go/bigquery"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/ubntc/go/batching/batbq"
 
 	dummy "github.com/ubntc/go/batching/batbq/_examples/simple/dummy"
+	"github.com/ubntc/go/batching/batbq/batbqtest"
 	"github.com/ubntc/go/batching/batbq/config"
+	"github.com/ubntc/go/batching/batbq/spool"
+	"github.com/ubntc/go/metrics/metricstest"
 )
 
 type timing struct {
@@ -175,3 +180,124 @@ func TestDefaults(t *testing.T) {
 	assert.Equal(t, config.DefaultMinWorkers, def.MinWorkers)
 
 }
+
+func TestRetries(t *testing.T) {
+	rec := batbqtest.NewRecorder()
+	rec.Message("m1", nil)
+	rec.Message("m2", nil)
+	p := batbqtest.NewPutter("table", nil)
+	p.FailNext(2, batbqtest.Unavailable())
+
+	cfg := testConfig
+	cfg.Retries = 2
+	cfg.RetryInterval = time.Millisecond
+	ins := batbq.NewInsertBatcher("test", batbq.Config(cfg))
+	mtx := metricstest.NewRecorder(t, ins.Metrics())
+	ins.Process(context.Background(), rec.Chan(), p)
+
+	assert.Equal(t, 3, p.Calls())
+	rec.AssertAcked(t, "m1", "m2")
+	rec.AssertNacked(t)
+
+	batcher := prometheus.Labels{batbq.Batcher: "test"}
+	mtx.AssertIncreased(t, "batbq_insert_retries_total", batcher, 2)
+	mtx.AssertIncreased(t, "batbq_processed_messages_total", batcher, 2)
+	mtx.AssertObserved(t, "batbq_insert_latency_seconds", batcher, 3) // one per insert call
+}
+
+func TestRetriesStopOnShutdown(t *testing.T) {
+	rec := batbqtest.NewRecorder()
+	rec.Message("m1", nil)
+	p := batbqtest.NewPutter("table", nil)
+	p.FailNext(100, batbqtest.Unavailable())
+
+	cfg := testConfig
+	cfg.Retries = 10
+	cfg.RetryInterval = time.Hour
+	ins := batbq.NewInsertBatcher("test", batbq.Config(cfg))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	ins.Process(ctx, rec.Chan(), p)
+
+	assert.Less(t, time.Since(start), time.Second, "shutdown must not wait for the retries")
+	assert.Equal(t, 1, p.Calls())
+	rec.AssertNacked(t, "m1")
+}
+
+func TestSpool(t *testing.T) {
+	s, err := spool.Open("test", spool.Config{Dir: t.TempDir()}, nil)
+	assert.NoError(t, err)
+
+	rec := batbqtest.NewRecorder()
+	rec.Message("m1", map[string]bigquery.Value{"val": 1})
+	rec.Message("m2", map[string]bigquery.Value{"val": 2})
+	rec.Message("m3", map[string]bigquery.Value{"val": 3})
+	p := batbqtest.NewPutter("table", nil)
+	p.FailNext(2, batbqtest.Unavailable())
+
+	cfg := testConfig
+	cfg.Retries = 1
+	cfg.RetryInterval = time.Millisecond
+	ins := batbq.NewInsertBatcher("test", batbq.Config(cfg), batbq.WithSpool(s))
+	mtx := metricstest.NewRecorder(t, ins.Metrics())
+	ins.Process(context.Background(), rec.Chan(), p)
+
+	// the messages are acked after spooling the batch
+	rec.AssertAcked(t, "m1", "m2", "m3")
+	rec.AssertNacked(t)
+	mtx.AssertIncreased(t, "batbq_spooled_messages_total", prometheus.Labels{batbq.Batcher: "test"}, 3)
+	mtx.AssertIncreased(t, "batbq_insert_retries_total", prometheus.Labels{batbq.Batcher: "test"}, 1)
+	assert.Equal(t, 0, p.Len())
+	assert.Equal(t, 1, s.Segments())
+
+	// the putter recovered and the spooled rows can be replayed
+	n, err := s.Drain(context.Background(), p)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.ElementsMatch(t, []string{"m1", "m2", "m3"}, p.InsertIDs())
+}
+
+func TestSpoolFactory(t *testing.T) {
+	dir := t.TempDir()
+	opts := []batbq.BatcherOption{batbq.Config(testConfig), batbq.WithSpoolFactory(spool.PerName(spool.Config{Dir: dir}, nil))}
+
+	for _, id := range []string{"a", "b"} {
+		rec := batbqtest.NewRecorder()
+		rec.Message(id+"1", map[string]bigquery.Value{"val": 1})
+		p := batbqtest.NewPutter(id, nil)
+		p.FailNext(100, batbqtest.Unavailable()) // also fail the background replay
+
+		ins := batbq.NewInsertBatcher(id, opts...)
+		assert.NoError(t, ins.Process(context.Background(), rec.Chan(), p))
+		rec.AssertAcked(t, id+"1")
+	}
+
+	// each batcher spooled into its own spool, which was closed after processing
+	for _, id := range []string{"a", "b"} {
+		s, err := spool.Open(id, spool.Config{Dir: filepath.Join(dir, id)}, nil)
+		assert.NoError(t, err)
+		p := batbqtest.NewPutter(id, nil)
+		n, err := s.Drain(context.Background(), p)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, []string{id + "1"}, p.InsertIDs())
+	}
+}
+
+func TestSpoolFull(t *testing.T) {
+	s, err := spool.Open("test", spool.Config{Dir: t.TempDir(), MaxBytes: 10}, nil)
+	assert.NoError(t, err)
+
+	rec := batbqtest.NewRecorder()
+	rec.Message("m1", map[string]bigquery.Value{"val": 1})
+	p := batbqtest.NewPutter("table", nil)
+	p.FailNext(1, batbqtest.Unavailable())
+
+	ins := batbq.NewInsertBatcher("test", batbq.Config(testConfig), batbq.WithSpool(s))
+	ins.Process(context.Background(), rec.Chan(), p)
+
+	rec.AssertNacked(t, "m1")
+	assert.Equal(t, 0, s.Segments())
+}
@@ -8,6 +8,7 @@ const (
 	DefaultFlushInterval = time.Second     // when to send partially filled batches
 	DefaultMinWorkers    = 1
 	DefaultMaxWorkers    = 10
+	DefaultRetryInterval = 100 * time.Millisecond // wait time between retries of failed batches
 )
 
 // WorkerConfig defines how many workers to use.
@@ -18,11 +19,18 @@ type WorkerConfig struct {
 	ScaleInterval time.Duration
 }
 
+// RetryConfig defines how often to retry failed batches.
+type RetryConfig struct {
+	Retries       int
+	RetryInterval time.Duration
+}
+
 // BatcherConfig stores InsertBatcher paramaters.
 type BatcherConfig struct {
 	Capacity      int
 	FlushInterval time.Duration
 	WorkerConfig
+	RetryConfig
 }
 
 // WithDefaults copies the config by value, sets missing defaults values returns the copy.
@@ -36,6 +44,9 @@ func (cfg BatcherConfig) WithDefaults() BatcherConfig {
 	if cfg.MaxWorkers <= 0 {
 		cfg.MaxWorkers = DefaultMaxWorkers
 	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = DefaultRetryInterval
+	}
 	if cfg.ScaleInterval <= 0 {
 		cfg.ScaleInterval = DefaultScaleInterval
 	}
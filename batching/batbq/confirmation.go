@@ -60,3 +60,21 @@ func handleErrors(messages []Message, err error) (index map[int]struct{}) {
 	}
 	return nacked
 }
+
+// failedRows returns an index of the rows that were not inserted due to the `error`.
+func failedRows(numRows int, err error) map[int]struct{} {
+	failed := make(map[int]struct{})
+	if err == nil {
+		return failed
+	}
+	if mulErr, isMulti := err.(bigquery.PutMultiError); isMulti {
+		for _, insErr := range mulErr {
+			failed[insErr.RowIndex] = struct{}{}
+		}
+		return failed
+	}
+	for i := 0; i < numRows; i++ {
+		failed[i] = struct{}{}
+	}
+	return failed
+}
@@ -0,0 +1,202 @@
+package batbq
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Dedup defaults.
+const (
+	DefaultDedupWindow  = 10 * time.Minute
+	DefaultDedupMaxKeys = 100000
+
+	maxInsertIDLen = 128 // maximum length of a BigQuery insert ID
+)
+
+// KeyedMessage is a Message with a unique key, e.g., the ID of the upstream message.
+type KeyedMessage interface {
+	Message
+	Key() string
+}
+
+// DedupConfig enables deduplication of messages by key. It can be used as BatcherOption.
+//
+// Messages are deduplicated in two ways. First, the key is used to derive the insert ID of the
+// row, which allows BigQuery to drop duplicates on a best-effort basis. Second, the keys of inserted
+// messages are stored in a local time-bounded window and redelivered messages with a known key are
+// acked without inserting them again. Duplicates in the same batch or in concurrently inserted
+// batches are not dropped locally.
+type DedupConfig struct {
+	Window  time.Duration            // how long to remember inserted keys
+	MaxKeys int                      // maximum number of remembered keys
+	Key     func(msg Message) string // optional, defaults to `KeyedMessage.Key()`
+}
+
+// WithDefaults copies the config by value, sets missing defaults values returns the copy.
+func (cfg DedupConfig) WithDefaults() DedupConfig {
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultDedupWindow
+	}
+	if cfg.MaxKeys <= 0 {
+		cfg.MaxKeys = DefaultDedupMaxKeys
+	}
+	if cfg.Key == nil {
+		cfg.Key = messageKey
+	}
+	return cfg
+}
+
+func (cfg DedupConfig) apply(ins *InsertBatcher) {
+	c := cfg.WithDefaults()
+	ins.dedupCfg = &c
+}
+
+// messageKey returns the key of a KeyedMessage or an empty string.
+func messageKey(msg Message) string {
+	if m, ok := msg.(KeyedMessage); ok {
+		return m.Key()
+	}
+	return ""
+}
+
+// InsertID returns a BigQuery insert ID for a message key. Keys that exceed the maximum length
+// of an insert ID are hashed.
+func InsertID(key string) string {
+	if len(key) <= maxInsertIDLen {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// keyedSaver overrides the insert ID of a ValueSaver.
+type keyedSaver struct {
+	bigquery.ValueSaver
+	insertID string
+}
+
+func (s *keyedSaver) Save() (map[string]bigquery.Value, string, error) {
+	row, _, err := s.ValueSaver.Save()
+	return row, s.insertID, err
+}
+
+type dedupEntry struct {
+	key  string
+	time time.Time
+}
+
+// dedupWindow stores keys for a limited time and up to a maximum number of keys.
+type dedupWindow struct {
+	cfg   DedupConfig
+	mu    sync.Mutex
+	keys  map[string]time.Time
+	queue []dedupEntry // insertion order, may contain outdated entries of refreshed keys
+
+	checks    prometheus.Counter
+	hits      prometheus.Counter
+	evictions prometheus.Counter
+	size      prometheus.Gauge
+}
+
+func newDedupWindow(cfg DedupConfig, m *Metrics, name string) *dedupWindow {
+	return &dedupWindow{
+		cfg:       cfg,
+		keys:      make(map[string]time.Time),
+		checks:    m.DedupChecks.WithLabelValues(name),
+		hits:      m.DedupHits.WithLabelValues(name),
+		evictions: m.DedupEvictions.WithLabelValues(name),
+		size:      m.DedupKeys.WithLabelValues(name),
+	}
+}
+
+// pop removes the oldest entry and reports whether it was the current entry of its key.
+// It must be called with w.mu locked.
+func (w *dedupWindow) pop() bool {
+	e := w.queue[0]
+	w.queue[0] = dedupEntry{}
+	w.queue = w.queue[1:]
+	if t, ok := w.keys[e.key]; ok && t.Equal(e.time) {
+		delete(w.keys, e.key)
+		return true
+	}
+	return false
+}
+
+// expire removes outdated keys. It must be called with w.mu locked.
+func (w *dedupWindow) expire(now time.Time) {
+	for len(w.queue) > 0 && now.Sub(w.queue[0].time) > w.cfg.Window {
+		w.pop()
+	}
+	for len(w.keys) > w.cfg.MaxKeys && len(w.queue) > 0 {
+		if w.pop() {
+			w.evictions.Inc()
+		}
+	}
+	if len(w.queue) == 0 {
+		w.queue = nil // release the underlying array
+	}
+	w.size.Set(float64(len(w.keys)))
+}
+
+// filter acks and removes known messages. It returns the remaining messages and their keys.
+func (w *dedupWindow) filter(messages []Message) ([]Message, []string) {
+	if w == nil {
+		// allow running without deduplication
+		return messages, make([]string, len(messages))
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expire(time.Now())
+
+	res := messages[:0]
+	keys := make([]string, 0, len(messages))
+	for _, m := range messages {
+		key := w.cfg.Key(m)
+		if key != "" {
+			w.checks.Inc()
+			if _, ok := w.keys[key]; ok {
+				w.hits.Inc()
+				m.Ack()
+				continue
+			}
+		}
+		res = append(res, m)
+		keys = append(keys, key)
+	}
+	return res, keys
+}
+
+// commit stores the keys of all messages that were inserted, according to the insert error.
+func (w *dedupWindow) commit(keys []string, err error) {
+	if w == nil {
+		return
+	}
+	failed := failedRows(len(keys), err)
+	if len(failed) == len(keys) {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now()
+	for i, key := range keys {
+		if _, ok := failed[i]; ok || key == "" {
+			continue
+		}
+		w.keys[key] = now
+		w.queue = append(w.queue, dedupEntry{key, now})
+	}
+	w.expire(now)
+}
+
+// saver returns the ValueSaver for a message using the key as insert ID if available.
+func (w *dedupWindow) saver(m Message, key string) bigquery.ValueSaver {
+	if w == nil || key == "" {
+		return m.Data()
+	}
+	return &keyedSaver{m.Data(), InsertID(key)}
+}
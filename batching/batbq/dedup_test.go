@@ -0,0 +1,83 @@
+package batbq_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/batching/batbq"
+	"github.com/ubntc/go/batching/batbq/batbqtest"
+)
+
+func TestDedup(t *testing.T) {
+	p := batbqtest.NewPutter("table", nil)
+	ins := batbq.NewInsertBatcher("test", batbq.Config(testConfig), batbq.DedupConfig{})
+	mtx := ins.Metrics()
+
+	rec := batbqtest.NewRecorder()
+	rec.Message("m1", nil)
+	rec.Message("m2", nil)
+	ins.Process(context.Background(), rec.Chan(), p)
+
+	// redeliver m1 and fail the insert of m3
+	rec = batbqtest.NewRecorder()
+	rec.Message("m1", nil)
+	rec.Message("m3", nil)
+	p.FailNext(1, batbqtest.Unavailable())
+	ins.Process(context.Background(), rec.Chan(), p)
+	rec.AssertAcked(t, "m1")
+	rec.AssertNacked(t, "m3")
+
+	// redeliver m3, which was not inserted
+	rec = batbqtest.NewRecorder()
+	rec.Message("m3", nil)
+	ins.Process(context.Background(), rec.Chan(), p)
+	rec.AssertAcked(t, "m3")
+
+	assert.Equal(t, []string{"m1", "m2", "m3"}, p.InsertIDs())
+	assert.Equal(t, 5.0, testutil.ToFloat64(mtx.DedupChecks))
+	assert.Equal(t, 1.0, testutil.ToFloat64(mtx.DedupHits))
+	assert.Equal(t, 3.0, testutil.ToFloat64(mtx.DedupKeys))
+}
+
+func TestDedupWindowBounds(t *testing.T) {
+	p := batbqtest.NewPutter("table", nil)
+	cfg := batbq.DedupConfig{Window: 20 * time.Millisecond, MaxKeys: 2}
+	ins := batbq.NewInsertBatcher("test", batbq.Config(testConfig), cfg)
+	mtx := ins.Metrics()
+
+	rec := batbqtest.NewRecorder()
+	rec.Message("m1", nil)
+	rec.Message("m2", nil)
+	rec.Message("m3", nil)
+	ins.Process(context.Background(), rec.Chan(), p)
+	assert.Equal(t, 2.0, testutil.ToFloat64(mtx.DedupKeys))
+	assert.Equal(t, 1.0, testutil.ToFloat64(mtx.DedupEvictions))
+
+	// evicted and expired keys are inserted again
+	time.Sleep(30 * time.Millisecond)
+	rec = batbqtest.NewRecorder()
+	rec.Message("m1", nil)
+	rec.Message("m2", nil)
+	ins.Process(context.Background(), rec.Chan(), p)
+	assert.Equal(t, 5, p.Len())
+	assert.Equal(t, 0.0, testutil.ToFloat64(mtx.DedupHits))
+}
+
+func TestDedupKeyFunc(t *testing.T) {
+	p := batbqtest.NewPutter("table", nil)
+	key := func(m batbq.Message) string { return strings.Repeat("k", 200) + m.(*batbqtest.Message).ID }
+	ins := batbq.NewInsertBatcher("test", batbq.Config(testConfig), batbq.DedupConfig{Key: key})
+
+	rec := batbqtest.NewRecorder()
+	rec.Message("m1", nil)
+	ins.Process(context.Background(), rec.Chan(), p)
+
+	ids := p.InsertIDs()
+	assert.Len(t, ids, 1)
+	assert.Equal(t, batbq.InsertID(key(&batbqtest.Message{ID: "m1"})), ids[0])
+	assert.Len(t, ids[0], 64)
+}
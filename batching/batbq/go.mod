@@ -6,6 +6,7 @@ require (
 	cloud.google.com/go/bigquery v1.30.0
 	github.com/prometheus/client_golang v1.12.1
 	github.com/stretchr/testify v1.8.4
+	github.com/ubntc/go/metrics v0.0.0
 	google.golang.org/api v0.73.0
 )
 
@@ -39,3 +40,5 @@ require (
 	gopkg.in/check.v1 v1.0.0-20200227125254-8fa46927fb4f // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/ubntc/go/metrics => ../../metrics
@@ -23,6 +23,14 @@ type Metrics struct {
 	ProcessedMessages *prometheus.CounterVec
 	ProcessedBatches  *prometheus.CounterVec
 	InsertErrors      *prometheus.CounterVec
+	InsertRetries     *prometheus.CounterVec
+	SpooledMessages   *prometheus.CounterVec
+
+	// Deduplication
+	DedupKeys      *prometheus.GaugeVec
+	DedupChecks    *prometheus.CounterVec
+	DedupHits      *prometheus.CounterVec
+	DedupEvictions *prometheus.CounterVec
 
 	// Latencies
 	InsertLatency *prometheus.HistogramVec
@@ -64,6 +72,32 @@ func NewMetrics(prefix ...string) *Metrics {
 			Name:      "insert_errors_total",
 			Namespace: ns,
 		}, label),
+		InsertRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "insert_retries_total",
+			Namespace: ns,
+		}, label),
+		SpooledMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "spooled_messages_total",
+			Namespace: ns,
+		}, label),
+
+		// Deduplication
+		DedupKeys: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "dedup_keys",
+			Namespace: ns,
+		}, label),
+		DedupChecks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "dedup_checks_total",
+			Namespace: ns,
+		}, label),
+		DedupHits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "dedup_hits_total",
+			Namespace: ns,
+		}, label),
+		DedupEvictions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "dedup_evictions_total",
+			Namespace: ns,
+		}, label),
 
 		// Latencies
 		InsertLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
@@ -77,6 +111,18 @@ func NewMetrics(prefix ...string) *Metrics {
 	}
 }
 
+// Delete removes the metrics of a stopped batcher, e.g., of a retired pipeline.
+func (m *Metrics) Delete(batcher string) {
+	for _, v := range []interface{ DeleteLabelValues(...string) bool }{
+		m.NumWorkers, m.PendingMessages,
+		m.ReceivedMessages, m.ProcessedMessages, m.ProcessedBatches, m.InsertErrors, m.InsertRetries, m.SpooledMessages,
+		m.DedupKeys, m.DedupChecks, m.DedupHits, m.DedupEvictions,
+		m.InsertLatency, m.AckLatency,
+	} {
+		v.DeleteLabelValues(batcher)
+	}
+}
+
 // Register registers all metrics.
 func (m *Metrics) Register(reg prometheus.Registerer) {
 	reg.MustRegister(m.NumWorkers)
@@ -86,6 +132,13 @@ func (m *Metrics) Register(reg prometheus.Registerer) {
 	reg.MustRegister(m.ProcessedBatches)
 	reg.MustRegister(m.ProcessedMessages)
 	reg.MustRegister(m.InsertErrors)
+	reg.MustRegister(m.InsertRetries)
+	reg.MustRegister(m.SpooledMessages)
+
+	reg.MustRegister(m.DedupKeys)
+	reg.MustRegister(m.DedupChecks)
+	reg.MustRegister(m.DedupHits)
+	reg.MustRegister(m.DedupEvictions)
 
 	reg.MustRegister(m.InsertLatency)
 	reg.MustRegister(m.AckLatency)
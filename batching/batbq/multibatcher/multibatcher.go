@@ -2,6 +2,7 @@ package multibatcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
@@ -18,24 +19,28 @@ type MultiBatcher struct {
 
 // NewMultiBatcher returns a new MultiInsertBatcher
 func NewMultiBatcher(ids []string, opts ...batbq.BatcherOption) *MultiBatcher {
-	mb := &MultiBatcher{ids: ids, opts: opts}
+	mb := &MultiBatcher{ids: ids}
+	mb.opts, mb.Metrics = withMetrics(opts)
+	return mb
+}
 
+// errSharedSpool is returned if the batchers of a MultiBatcher or RoutingBatcher would share a spool.
+var errSharedSpool = errors.New("a spool cannot be shared by several batchers, use batbq.WithSpoolFactory")
+
+// withMetrics finds the metrics option or adds a new one. It returns the options and the metrics
+// that are shared by all batchers.
+func withMetrics(opts []batbq.BatcherOption) ([]batbq.BatcherOption, *batbq.Metrics) {
 	// find metrics option and assign it to the multibatcher
 	for _, opt := range opts {
 		switch opt.(type) {
 		case *batbq.Metrics:
-			mb.Metrics = opt.(*batbq.Metrics)
+			return opts, opt.(*batbq.Metrics)
 		}
 	}
 
 	// add missing metrics here and as option for the batchers
-	if mb.Metrics == nil {
-		mb.Metrics = batbq.NewMetrics()
-		mb.opts = append(mb.opts, mb.Metrics)
-	}
-
-	copy(mb.ids, ids)
-	return mb
+	m := batbq.NewMetrics()
+	return append(opts, m), m
 }
 
 // InputGetter returns an input channel for a given batcher ID.
@@ -49,6 +54,11 @@ func (mb *MultiBatcher) Process(ctx context.Context, input InputGetter, output O
 	batchers := make(map[string]*batbq.InsertBatcher)
 
 	errchan := make(chan error, len(mb.ids))
+	if len(mb.ids) > 1 && batbq.HasSpool(mb.opts) {
+		errchan <- errSharedSpool
+		close(errchan)
+		return errchan
+	}
 	var wg sync.WaitGroup
 	for _, id := range mb.ids {
 		ins := batbq.NewInsertBatcher(id, mb.opts...)
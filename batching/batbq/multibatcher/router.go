@@ -0,0 +1,254 @@
+package multibatcher
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ubntc/go/batching/batbq"
+)
+
+// RouterConfig defaults.
+const (
+	DefaultIdleTimeout  = time.Minute
+	DefaultMaxPipelines = 100
+	DefaultBufferSize   = 1000
+)
+
+// ErrNoRoute is sent via `Nack` to messages that cannot be routed to a pipeline.
+var ErrNoRoute = errors.New("no route for message")
+
+// Router returns the ID of the destination pipeline, e.g., a table name, for a message.
+// If it returns an empty ID the message is nacked.
+type Router func(msg batbq.Message) string
+
+// RouterConfig stores RoutingBatcher parameters.
+type RouterConfig struct {
+	IdleTimeout  time.Duration // stop pipelines that did not receive messages for this time
+	MaxPipelines int           // maximum number of active pipelines
+	BufferSize   int           // size of the input channel of each pipeline
+}
+
+// WithDefaults copies the config by value, sets missing defaults values returns the copy.
+func (cfg RouterConfig) WithDefaults() RouterConfig {
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = DefaultIdleTimeout
+	}
+	if cfg.MaxPipelines <= 0 {
+		cfg.MaxPipelines = DefaultMaxPipelines
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = DefaultBufferSize
+	}
+	return cfg
+}
+
+type pipeline struct {
+	input    chan batbq.Message
+	done     chan struct{}
+	lastUsed time.Time
+}
+
+// RoutingBatcher streams data from a single input to dynamically created pipelines.
+// Pipelines are created for new destinations and stopped if they are idle or if the number of
+// active pipelines exceeds the configured maximum.
+type RoutingBatcher struct {
+	cfg     RouterConfig
+	opts    []batbq.BatcherOption
+	Metrics *batbq.Metrics
+
+	mu        sync.Mutex
+	pipelines map[string]*pipeline
+	stopping  map[string]*pipeline // stopped pipelines that are still flushing
+}
+
+// NewRoutingBatcher returns a new RoutingBatcher.
+func NewRoutingBatcher(cfg RouterConfig, opts ...batbq.BatcherOption) *RoutingBatcher {
+	rb := &RoutingBatcher{cfg: cfg.WithDefaults()}
+	rb.opts, rb.Metrics = withMetrics(opts)
+	return rb
+}
+
+// Pipelines returns the sorted IDs of the active pipelines.
+func (rb *RoutingBatcher) Pipelines() []string {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	ids := make([]string, 0, len(rb.pipelines))
+	for id := range rb.pipelines {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// start creates a new pipeline. It must be called with rb.mu locked.
+func (rb *RoutingBatcher) start(ctx context.Context, id string, out batbq.Putter, wg *sync.WaitGroup) *pipeline {
+	p := &pipeline{
+		input: make(chan batbq.Message, rb.cfg.BufferSize),
+		done:  make(chan struct{}),
+	}
+	rb.pipelines[id] = p
+	ins := batbq.NewInsertBatcher(id, rb.opts...)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(p.done)
+		if err := ins.Process(ctx, p.input, out); err != nil {
+			log.Printf("failed to process pipeline %s: %v", id, err)
+		}
+		// nack the messages that were not processed, e.g., after the context was canceled
+		for msg := range p.input {
+			msg.Nack(nil)
+		}
+		rb.retire(id, p)
+	}()
+	return p
+}
+
+// retire removes a stopped pipeline and its metrics. The pipeline must not be restarted before
+// it is done, see pipeline.
+func (rb *RoutingBatcher) retire(id string, p *pipeline) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	if rb.stopping[id] == p {
+		delete(rb.stopping, id)
+	}
+	rb.Metrics.Delete(id)
+}
+
+// stop closes the input of a pipeline and keeps it as stopping until it is done.
+// It must be called with rb.mu locked.
+func (rb *RoutingBatcher) stop(id string) *pipeline {
+	p := rb.pipelines[id]
+	delete(rb.pipelines, id)
+	rb.stopping[id] = p
+	close(p.input)
+	return p
+}
+
+// evict stops the least recently used pipeline and returns it.
+// It must be called with rb.mu locked.
+func (rb *RoutingBatcher) evict() *pipeline {
+	var lru string
+	var oldest time.Time
+	for id, p := range rb.pipelines {
+		if lru == "" || p.lastUsed.Before(oldest) {
+			lru, oldest = id, p.lastUsed
+		}
+	}
+	log.Printf("stopping least recently used pipeline %s", lru)
+	return rb.stop(lru)
+}
+
+// retireIdle stops all pipelines that were idle for more than the idle timeout.
+func (rb *RoutingBatcher) retireIdle(now time.Time) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	for id, p := range rb.pipelines {
+		if now.Sub(p.lastUsed) > rb.cfg.IdleTimeout {
+			log.Printf("stopping idle pipeline %s", id)
+			rb.stop(id)
+		}
+	}
+}
+
+// pipeline returns the pipeline for the given id and creates it if needed.
+// Before creating it, it waits until a stopping pipeline of the same id is done and, if the
+// maximum number of pipelines is reached, until the evicted pipeline is done. It does not hold
+// the lock while waiting.
+func (rb *RoutingBatcher) pipeline(ctx context.Context, id string, output OutputGetter, wg *sync.WaitGroup) *pipeline {
+	rb.mu.Lock()
+	if p, ok := rb.pipelines[id]; ok {
+		p.lastUsed = time.Now()
+		rb.mu.Unlock()
+		return p
+	}
+	if old, ok := rb.stopping[id]; ok {
+		rb.mu.Unlock()
+		<-old.done
+		rb.mu.Lock()
+	}
+	out := output(id)
+	if out == nil {
+		rb.mu.Unlock()
+		return nil
+	}
+	var evicted *pipeline
+	if len(rb.pipelines) >= rb.cfg.MaxPipelines {
+		evicted = rb.evict()
+	}
+	rb.mu.Unlock()
+
+	if evicted != nil {
+		<-evicted.done
+	}
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	p := rb.start(ctx, id, out, wg)
+	p.lastUsed = time.Now()
+	return p
+}
+
+// Process routes the messages from the input to the pipelines until the input is closed or the
+// context is canceled. It waits for all pipelines to stop before returning.
+func (rb *RoutingBatcher) Process(ctx context.Context, input <-chan batbq.Message, route Router, output OutputGetter) error {
+	if input == nil {
+		return errors.New("input channel must not be nil")
+	}
+	if route == nil || output == nil {
+		return errors.New("router and output getter must not be nil")
+	}
+	if batbq.HasSpool(rb.opts) {
+		return errSharedSpool
+	}
+
+	var wg sync.WaitGroup
+	rb.mu.Lock()
+	rb.pipelines = make(map[string]*pipeline)
+	rb.stopping = make(map[string]*pipeline)
+	rb.mu.Unlock()
+
+	defer func() {
+		rb.mu.Lock()
+		for id := range rb.pipelines {
+			rb.stop(id)
+		}
+		rb.mu.Unlock()
+		wg.Wait()
+	}()
+
+	ticker := time.NewTicker(rb.cfg.IdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			rb.retireIdle(now)
+		case msg, more := <-input:
+			if !more {
+				return nil
+			}
+			id := route(msg)
+			if id == "" {
+				msg.Nack(ErrNoRoute)
+				continue
+			}
+			p := rb.pipeline(ctx, id, output, &wg)
+			if p == nil {
+				msg.Nack(ErrNoRoute)
+				continue
+			}
+			select {
+			case p.input <- msg:
+			case <-ctx.Done():
+				msg.Nack(nil)
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package multibatcher_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/batching/batbq"
+	"github.com/ubntc/go/batching/batbq/batbqtest"
+	"github.com/ubntc/go/batching/batbq/config"
+	mb "github.com/ubntc/go/batching/batbq/multibatcher"
+	"github.com/ubntc/go/batching/batbq/spool"
+)
+
+// routeByTable demonstrates how to implement a Router.
+func routeByTable(msg batbq.Message) string {
+	table, _ := msg.(*batbqtest.Message).Values["table"].(string)
+	return table
+}
+
+func TestRoutingBatcher(t *testing.T) {
+	rec := batbqtest.NewRecorder()
+	var ids []string
+	for i := 0; i < 30; i++ {
+		id := fmt.Sprint("m", i)
+		ids = append(ids, id)
+		rec.Message(id, map[string]bigquery.Value{"table": fmt.Sprint("t", i%3)})
+	}
+	rec.Message("no_route", map[string]bigquery.Value{})
+
+	ds := batbqtest.NewDataset()
+	rb := mb.NewRoutingBatcher(
+		mb.RouterConfig{MaxPipelines: 2},
+		batbq.Config(config.BatcherConfig{Capacity: 5, FlushInterval: 10 * time.Millisecond}),
+	)
+
+	err := rb.Process(context.Background(), rec.Chan(), routeByTable, ds.Output)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"t0", "t1", "t2"}, ds.Tables())
+	for _, table := range ds.Tables() {
+		assert.Len(t, ds.Rows(table), 10)
+	}
+	rec.AssertAcked(t, ids...)
+	rec.AssertNacked(t, "no_route")
+	assert.Equal(t, mb.ErrNoRoute, rec.Confirmation("no_route").Err)
+	assert.Empty(t, rb.Pipelines())
+	assert.Equal(t, 0, testutil.CollectAndCount(rb.Metrics.ReceivedMessages), "metrics of retired pipelines are removed")
+}
+
+func TestSharedSpool(t *testing.T) {
+	s, err := spool.Open("shared", spool.Config{Dir: t.TempDir()}, nil)
+	assert.NoError(t, err)
+	defer s.Close()
+
+	rb := mb.NewRoutingBatcher(mb.RouterConfig{}, batbq.WithSpool(s))
+	err = rb.Process(context.Background(), make(chan batbq.Message), routeByTable, batbqtest.NewDataset().Output)
+	assert.Error(t, err, "pipelines must not share a spool")
+
+	ds := batbqtest.NewDataset()
+	multi := mb.NewMultiBatcher([]string{"a", "b"}, batbq.WithSpool(s))
+	err = multi.MustProcess(context.Background(), func(string) <-chan batbq.Message { return nil }, ds.Output)
+	assert.Error(t, err, "pipelines must not share a spool")
+}
+
+func TestRoutingBatcherIdleTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	input := make(chan batbq.Message)
+	rec := batbqtest.NewRecorder()
+	ds := batbqtest.NewDataset()
+	rb := mb.NewRoutingBatcher(mb.RouterConfig{IdleTimeout: 10 * time.Millisecond})
+
+	done := make(chan error)
+	go func() { done <- rb.Process(ctx, input, routeByTable, ds.Output) }()
+
+	input <- rec.Message("m1", map[string]bigquery.Value{"table": "a"})
+	input <- rec.Message("m2", map[string]bigquery.Value{"table": "b"})
+
+	assert.Eventually(t, func() bool { return len(rb.Pipelines()) == 0 }, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool { return len(rec.Acked()) == 2 }, time.Second, time.Millisecond)
+
+	// retired pipelines are recreated on demand
+	input <- rec.Message("m3", map[string]bigquery.Value{"table": "a"})
+	close(input)
+	assert.NoError(t, <-done)
+	assert.Len(t, ds.Rows("a"), 2)
+	rec.AssertAcked(t, "m1", "m2", "m3")
+}
+
+func TestRoutingBatcherShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	input := make(chan batbq.Message)
+	rec := batbqtest.NewRecorder()
+	release := make(chan struct{})
+	// the pipeline does not read its input until the spool fails to open
+	failingSpool := func(string) (*spool.Spool, error) {
+		<-release
+		return nil, errors.New("no spool")
+	}
+	rb := mb.NewRoutingBatcher(mb.RouterConfig{}, batbq.WithSpoolFactory(failingSpool))
+
+	done := make(chan error)
+	go func() { done <- rb.Process(ctx, input, routeByTable, batbqtest.NewDataset().Output) }()
+	for i := 0; i < 5; i++ {
+		input <- rec.Message(fmt.Sprint("m", i), map[string]bigquery.Value{"table": "a"})
+	}
+
+	cancel()
+	close(release)
+	assert.NoError(t, <-done)
+	assert.Empty(t, rec.Unconfirmed(), "buffered messages are nacked on shutdown")
+	assert.Len(t, rec.Nacked(), 5)
+	rec.AssertConfirmedOnce(t)
+}
+
+func TestRoutingBatcherRestartAfterIdle(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	input := make(chan batbq.Message)
+	rec := batbqtest.NewRecorder()
+	release := make(chan struct{})
+	var running, maxRunning atomic.Int32
+	ds := batbqtest.NewDataset()
+	output := func(id string) batbq.Putter { return countingPutter{ds.Output(id), &running, &maxRunning, release} }
+	rb := mb.NewRoutingBatcher(mb.RouterConfig{IdleTimeout: 10 * time.Millisecond},
+		batbq.Config(config.BatcherConfig{Capacity: 1}))
+
+	done := make(chan error)
+	go func() { done <- rb.Process(ctx, input, routeByTable, output) }()
+
+	// the first pipeline is retired while its Put is blocked
+	input <- rec.Message("m1", map[string]bigquery.Value{"table": "a"})
+	assert.Eventually(t, func() bool { return len(rb.Pipelines()) == 0 }, time.Second, time.Millisecond)
+	input <- rec.Message("m2", map[string]bigquery.Value{"table": "a"})
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	close(input)
+	assert.NoError(t, <-done)
+	rec.AssertAcked(t, "m1", "m2")
+	assert.Equal(t, int32(1), maxRunning.Load(), "pipelines of the same id must not overlap")
+}
+
+// countingPutter tracks the number of concurrent Puts and blocks them until it is released.
+type countingPutter struct {
+	batbq.Putter
+	running, max *atomic.Int32
+	release      chan struct{}
+}
+
+func (p countingPutter) Put(ctx context.Context, src any) error {
+	n := p.running.Add(1)
+	defer p.running.Add(-1)
+	for {
+		m := p.max.Load()
+		if n <= m || p.max.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	<-p.release
+	return p.Putter.Put(ctx, src)
+}
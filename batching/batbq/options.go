@@ -1,6 +1,9 @@
 package batbq
 
-import "github.com/ubntc/go/batching/batbq/config"
+import (
+	"github.com/ubntc/go/batching/batbq/config"
+	"github.com/ubntc/go/batching/batbq/spool"
+)
 
 // BatcherOption configures the batcher.
 type BatcherOption interface {
@@ -18,3 +21,44 @@ func (cfg Config) apply(ins *InsertBatcher) {
 func (m *Metrics) apply(ins *InsertBatcher) {
 	ins.metrics = m
 }
+
+type spoolOption struct{ *spool.Spool }
+
+func (o spoolOption) apply(ins *InsertBatcher) {
+	ins.spool = o.Spool
+}
+
+// WithSpool returns a BatcherOption that stores batches in the spool if they cannot be inserted
+// after the configured retries. Spooled messages are acked and the spooled rows are replayed into
+// the batcher's Putter in the background.
+//
+// The spool must not be shared by several batchers. Use WithSpoolFactory for batchers
+// that are created with the same options, e.g., by a MultiBatcher or RoutingBatcher.
+func WithSpool(s *spool.Spool) BatcherOption {
+	return spoolOption{s}
+}
+
+// HasSpool tells if the options contain a spool set by WithSpool.
+// Such options must not be used for more than one batcher.
+func HasSpool(opts []BatcherOption) bool {
+	for _, o := range opts {
+		if _, ok := o.(spoolOption); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// SpoolFactory opens the spool of the batcher with the given ID.
+type SpoolFactory func(id string) (*spool.Spool, error)
+
+func (f SpoolFactory) apply(ins *InsertBatcher) {
+	ins.spoolFactory = f
+}
+
+// WithSpoolFactory returns a BatcherOption like WithSpool that opens a separate spool per batcher.
+// The spool is opened when the batcher starts processing and closed when it stops,
+// see spool.PerName.
+func WithSpoolFactory(f SpoolFactory) BatcherOption {
+	return f
+}
@@ -0,0 +1,41 @@
+package spool
+
+import "time"
+
+// Config defaults.
+const (
+	DefaultMaxBytes       = 1 << 30         // 1 GiB of total disk usage
+	DefaultSegmentBytes   = 16 << 20        // 16 MiB per segment file
+	DefaultBatchSize      = 500             // rows per replayed insert
+	DefaultReplayInterval = 5 * time.Second // how often to retry replaying after errors
+)
+
+// Config stores Spool parameters.
+type Config struct {
+	Dir            string        // directory for the segment files
+	MaxBytes       int64         // total disk usage limit of all segments
+	SegmentBytes   int64         // size at which a new segment file is started
+	BatchSize      int           // number of rows per replayed insert
+	ReplayInterval time.Duration // wait time between replay attempts
+	Sync           bool          // sync segment files to disk after each append
+}
+
+// WithDefaults copies the config by value, sets missing defaults values returns the copy.
+func (cfg Config) WithDefaults() Config {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultMaxBytes
+	}
+	if cfg.SegmentBytes <= 0 {
+		cfg.SegmentBytes = DefaultSegmentBytes
+	}
+	if cfg.SegmentBytes > cfg.MaxBytes {
+		cfg.SegmentBytes = cfg.MaxBytes
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.ReplayInterval <= 0 {
+		cfg.ReplayInterval = DefaultReplayInterval
+	}
+	return cfg
+}
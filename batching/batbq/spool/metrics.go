@@ -0,0 +1,89 @@
+package spool
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metrics prefix, subsystem, and label name
+const (
+	Batbq     = "batbq"
+	Subsystem = "spool"
+	Label     = "spool"
+)
+
+// Metrics stores Spool Metrics.
+type Metrics struct {
+	// State
+	Bytes    *prometheus.GaugeVec
+	Segments *prometheus.GaugeVec
+
+	// Results
+	SpooledRows  *prometheus.CounterVec
+	ReplayedRows *prometheus.CounterVec
+	DroppedRows  *prometheus.CounterVec
+	RejectedRows *prometheus.CounterVec
+	ReplayErrors *prometheus.CounterVec
+}
+
+// NewMetrics create returns a new Metrics object.
+func NewMetrics(prefix ...string) *Metrics {
+	ns := strings.Join(prefix, "_")
+	if len(ns) == 0 {
+		ns = Batbq
+	}
+	label := []string{Label}
+	return &Metrics{
+		// State
+		Bytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "bytes",
+			Namespace: ns,
+			Subsystem: Subsystem,
+		}, label),
+		Segments: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "segments",
+			Namespace: ns,
+			Subsystem: Subsystem,
+		}, label),
+
+		// Results
+		SpooledRows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "spooled_rows_total",
+			Namespace: ns,
+			Subsystem: Subsystem,
+		}, label),
+		ReplayedRows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "replayed_rows_total",
+			Namespace: ns,
+			Subsystem: Subsystem,
+		}, label),
+		DroppedRows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "dropped_rows_total",
+			Namespace: ns,
+			Subsystem: Subsystem,
+		}, label),
+		RejectedRows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "rejected_rows_total",
+			Namespace: ns,
+			Subsystem: Subsystem,
+		}, label),
+		ReplayErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "replay_errors_total",
+			Namespace: ns,
+			Subsystem: Subsystem,
+		}, label),
+	}
+}
+
+// Register registers all metrics.
+func (m *Metrics) Register(reg prometheus.Registerer) {
+	reg.MustRegister(m.Bytes)
+	reg.MustRegister(m.Segments)
+
+	reg.MustRegister(m.SpooledRows)
+	reg.MustRegister(m.ReplayedRows)
+	reg.MustRegister(m.DroppedRows)
+	reg.MustRegister(m.RejectedRows)
+	reg.MustRegister(m.ReplayErrors)
+}
@@ -0,0 +1,433 @@
+// Package spool implements a durable write-ahead spool for rows that could not be inserted into
+// BigQuery. Spooled rows are stored in append-only segment files and replayed into a Putter as
+// soon as it accepts inserts again.
+package spool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// segment file name suffix
+const segmentExt = ".jsonl"
+
+// Spool errors.
+var (
+	ErrFull   = errors.New("spool: disk usage limit reached")
+	ErrClosed = errors.New("spool: closed")
+)
+
+// Putter provides a `Put` func as used by the `bigquery.Inserter`.
+type Putter interface {
+	Put(ctx context.Context, src any) error
+}
+
+// Row stores the saved values of a spooled `bigquery.ValueSaver`.
+type Row struct {
+	InsertID string                    `json:"insert_id,omitempty"`
+	Values   map[string]bigquery.Value `json:"row"`
+}
+
+// Save implements the `bigquery.ValueSaver`.
+func (r *Row) Save() (map[string]bigquery.Value, string, error) {
+	return r.Values, r.InsertID, nil
+}
+
+type segment struct {
+	seq  uint64
+	path string
+	size int64
+	done int // number of rows that were already replayed
+}
+
+// Spool stores rows in segment files and replays them.
+type Spool struct {
+	name     string
+	cfg      Config
+	mu       sync.Mutex
+	replayMu sync.Mutex
+
+	segments []*segment // sorted by seq, the last one is the current segment if file != nil
+	file     *os.File   // current segment, open for appending
+	size     int64
+	closed   bool
+
+	bytes        prometheus.Gauge
+	numSegments  prometheus.Gauge
+	spooledRows  prometheus.Counter
+	replayedRows prometheus.Counter
+	droppedRows  prometheus.Counter
+	rejectedRows prometheus.Counter
+	replayErrors prometheus.Counter
+}
+
+// Open opens or creates a spool in the configured directory. Segments from previous runs
+// are picked up and will be replayed. If `m` is nil a new Metrics object is created.
+func Open(name string, cfg Config, m *Metrics) (*Spool, error) {
+	cfg = cfg.WithDefaults()
+	if cfg.Dir == "" {
+		return nil, errors.New("spool: missing directory")
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	if m == nil {
+		m = NewMetrics()
+	}
+
+	s := &Spool{
+		name:         name,
+		cfg:          cfg,
+		bytes:        m.Bytes.WithLabelValues(name),
+		numSegments:  m.Segments.WithLabelValues(name),
+		spooledRows:  m.SpooledRows.WithLabelValues(name),
+		replayedRows: m.ReplayedRows.WithLabelValues(name),
+		droppedRows:  m.DroppedRows.WithLabelValues(name),
+		rejectedRows: m.RejectedRows.WithLabelValues(name),
+		replayErrors: m.ReplayErrors.WithLabelValues(name),
+	}
+
+	entries, err := os.ReadDir(cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), segmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(e.Name(), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		s.segments = append(s.segments, &segment{
+			seq:  seq,
+			path: filepath.Join(cfg.Dir, e.Name()),
+			size: info.Size(),
+		})
+		s.size += info.Size()
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].seq < s.segments[j].seq })
+	if len(s.segments) > 0 {
+		log.Printf("spool %s: found %d segments with %d bytes", name, len(s.segments), s.size)
+	}
+	s.updateState()
+	return s, nil
+}
+
+// PerName returns a func that opens a spool per name, e.g., per batcher ID, in a sub directory
+// of the configured directory. All spools share the metrics. If `m` is nil a new Metrics
+// object is created.
+func PerName(cfg Config, m *Metrics) func(name string) (*Spool, error) {
+	if m == nil {
+		m = NewMetrics()
+	}
+	return func(name string) (*Spool, error) {
+		sub := cfg
+		sub.Dir = filepath.Join(cfg.Dir, url.PathEscape(name))
+		return Open(name, sub, m)
+	}
+}
+
+// Size returns the disk usage of all segments.
+func (s *Spool) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// Segments returns the number of segments.
+func (s *Spool) Segments() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.segments)
+}
+
+// Close closes the current segment. The spooled data remains on disk.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return s.closeFile()
+}
+
+// updateState updates the state metrics. It must be called with s.mu locked.
+func (s *Spool) updateState() {
+	s.bytes.Set(float64(s.size))
+	s.numSegments.Set(float64(len(s.segments)))
+}
+
+// closeFile closes the current segment file. It must be called with s.mu locked.
+func (s *Spool) closeFile() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// rotate starts a new segment. It must be called with s.mu locked.
+func (s *Spool) rotate() error {
+	if err := s.closeFile(); err != nil {
+		return err
+	}
+	var seq uint64 = 1
+	if n := len(s.segments); n > 0 {
+		seq = s.segments[n-1].seq + 1
+	}
+	path := filepath.Join(s.cfg.Dir, fmt.Sprintf("%016d%s", seq, segmentExt))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	s.file = f
+	s.segments = append(s.segments, &segment{seq: seq, path: path})
+	return nil
+}
+
+// Append stores the rows in the current segment. It returns ErrFull if the rows exceed the
+// configured disk usage limit, in which case no rows are stored.
+func (s *Spool) Append(rows []bigquery.ValueSaver) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range rows {
+		values, insertID, err := r.Save()
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(Row{InsertID: insertID, Values: encodeValues(values)}); err != nil {
+			return err
+		}
+	}
+	n := int64(buf.Len())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	if s.size+n > s.cfg.MaxBytes {
+		s.rejectedRows.Add(float64(len(rows)))
+		return ErrFull
+	}
+	if s.file == nil || (s.current().size > 0 && s.current().size+n > s.cfg.SegmentBytes) {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	cur := s.current()
+	written, err := s.file.Write(buf.Bytes())
+	cur.size += int64(written)
+	s.size += int64(written)
+	if err == nil && s.cfg.Sync {
+		err = s.file.Sync()
+	}
+	s.updateState()
+	if err != nil {
+		return err
+	}
+	s.spooledRows.Add(float64(len(rows)))
+	return nil
+}
+
+// encodeValues returns a copy of the values with NUMERIC values encoded as decimal strings,
+// since the JSON encoding of a `*big.Rat` is a fraction ("a/b") that BigQuery rejects.
+func encodeValues(values map[string]bigquery.Value) map[string]bigquery.Value {
+	res := make(map[string]bigquery.Value, len(values))
+	for k, v := range values {
+		res[k] = encodeValue(v)
+	}
+	return res
+}
+
+func encodeValue(v bigquery.Value) bigquery.Value {
+	switch v := v.(type) {
+	case *big.Rat:
+		return bigquery.NumericString(v)
+	case map[string]bigquery.Value:
+		return encodeValues(v)
+	case []bigquery.Value:
+		res := make([]bigquery.Value, len(v))
+		for i, e := range v {
+			res[i] = encodeValue(e)
+		}
+		return res
+	}
+	return v
+}
+
+// current returns the current segment. It must be called with s.mu locked.
+func (s *Spool) current() *segment {
+	if s.file == nil || len(s.segments) == 0 {
+		return nil
+	}
+	return s.segments[len(s.segments)-1]
+}
+
+// next returns the oldest segment or nil if there is nothing to replay. If the oldest segment
+// is the current segment, it is closed and new rows are appended to a new segment.
+func (s *Spool) next() *segment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.segments) == 0 {
+		return nil
+	}
+	seg := s.segments[0]
+	if seg == s.current() {
+		if seg.size == 0 {
+			return nil
+		}
+		if err := s.closeFile(); err != nil {
+			log.Printf("spool %s: failed to close segment %s: %v", s.name, seg.path, err)
+		}
+	}
+	return seg
+}
+
+// remove deletes a fully replayed segment.
+func (s *Spool) remove(seg *segment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i, v := range s.segments {
+		if v == seg {
+			s.segments = append(s.segments[:i], s.segments[i+1:]...)
+			break
+		}
+	}
+	s.size -= seg.size
+	s.updateState()
+	return nil
+}
+
+// readSegment reads all rows of a segment. Lines that cannot be decoded, e.g., a partially
+// written last line after a crash, are dropped.
+func (s *Spool) readSegment(seg *segment) ([]bigquery.ValueSaver, error) {
+	data, err := os.ReadFile(seg.path)
+	if err != nil {
+		return nil, err
+	}
+	var rows []bigquery.ValueSaver
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		row := &Row{}
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+		if err := dec.Decode(row); err != nil {
+			log.Printf("spool %s: dropping corrupted row in %s: %v", s.name, seg.path, err)
+			s.droppedRows.Inc()
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// replaySegment puts the not yet replayed rows of a segment in batches of `cfg.BatchSize`.
+func (s *Spool) replaySegment(ctx context.Context, seg *segment, out Putter) (int, error) {
+	rows, err := s.readSegment(seg)
+	if err != nil {
+		return 0, err
+	}
+	replayed := 0
+	for seg.done < len(rows) {
+		end := seg.done + s.cfg.BatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		batch := rows[seg.done:end]
+		err := out.Put(ctx, batch)
+		var mulErr bigquery.PutMultiError
+		switch {
+		case err == nil:
+		case errors.As(err, &mulErr):
+			// row errors are permanent and retrying will not help
+			for _, rowErr := range mulErr {
+				log.Printf("spool %s: dropping row: %v", s.name, &rowErr)
+			}
+			s.droppedRows.Add(float64(len(mulErr)))
+		default:
+			s.replayErrors.Inc()
+			return replayed, err
+		}
+		n := len(batch) - len(mulErr)
+		s.replayedRows.Add(float64(n))
+		replayed += n
+		seg.done = end
+	}
+	return replayed, nil
+}
+
+// Drain replays all spooled rows into the Putter and returns the number of replayed rows.
+// It stops at the first failed insert and continues from there on the next call.
+//
+// Rows of partially replayed segments may be replayed again after a restart.
+// Use insert IDs to allow BigQuery to deduplicate them.
+func (s *Spool) Drain(ctx context.Context, out Putter) (int, error) {
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+	total := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		seg := s.next()
+		if seg == nil {
+			return total, nil
+		}
+		n, err := s.replaySegment(ctx, seg, out)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if err := s.remove(seg); err != nil {
+			return total, err
+		}
+	}
+}
+
+// Replay periodically drains the spool into the Putter until the context is canceled.
+func (s *Spool) Replay(ctx context.Context, out Putter) {
+	ticker := time.NewTicker(s.cfg.ReplayInterval)
+	defer ticker.Stop()
+	for {
+		n, err := s.Drain(ctx, out)
+		if n > 0 {
+			log.Printf("spool %s: replayed %d rows", s.name, n)
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Printf("spool %s: replay failed: %v", s.name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
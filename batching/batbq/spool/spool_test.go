@@ -0,0 +1,175 @@
+package spool_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/batching/batbq/batbqtest"
+	"github.com/ubntc/go/batching/batbq/spool"
+)
+
+func rows(prefix string, n int) []bigquery.ValueSaver {
+	res := make([]bigquery.ValueSaver, n)
+	for i := range res {
+		res[i] = &spool.Row{
+			InsertID: fmt.Sprint(prefix, i),
+			Values:   map[string]bigquery.Value{"name": prefix, "val": i},
+		}
+	}
+	return res
+}
+
+var testSchema = bigquery.Schema{
+	{Name: "name", Type: bigquery.StringFieldType, Required: true},
+	{Name: "val", Type: bigquery.IntegerFieldType},
+}
+
+func TestAppendAndDrain(t *testing.T) {
+	s, err := spool.Open("test", spool.Config{Dir: t.TempDir(), BatchSize: 4}, nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.Append(rows("a", 5)))
+	assert.NoError(t, s.Append(rows("b", 5)))
+	assert.Equal(t, 1, s.Segments())
+	assert.Greater(t, s.Size(), int64(0))
+
+	p := batbqtest.NewPutter("table", testSchema)
+	n, err := s.Drain(context.Background(), p)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, n)
+	assert.Equal(t, 10, p.Len())
+	assert.Equal(t, 3, p.Calls())
+	assert.Equal(t, []string{"a0", "a1", "a2", "a3", "a4", "b0", "b1", "b2", "b3", "b4"}, p.InsertIDs())
+	assert.Equal(t, 0, s.Segments())
+	assert.Equal(t, int64(0), s.Size())
+
+	// new rows are appended to a new segment
+	assert.NoError(t, s.Append(rows("c", 1)))
+	assert.Equal(t, 1, s.Segments())
+}
+
+func TestRotateAndReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := spool.Open("test", spool.Config{Dir: dir, SegmentBytes: 100}, nil)
+	assert.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, s.Append(rows(fmt.Sprint("r", i, "_"), 2)))
+	}
+	assert.Equal(t, 5, s.Segments())
+	assert.NoError(t, s.Close())
+	assert.ErrorIs(t, s.Append(rows("x", 1)), spool.ErrClosed)
+
+	// simulate a crash while writing the last segment
+	files, _ := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	assert.Len(t, files, 5)
+	f, err := os.OpenFile(files[4], os.O_APPEND|os.O_WRONLY, 0o644)
+	assert.NoError(t, err)
+	f.WriteString(`{"insert_id":"broken","row":{"na`)
+	f.Close()
+
+	s, err = spool.Open("test", spool.Config{Dir: dir}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, s.Segments())
+
+	p := batbqtest.NewPutter("table", testSchema)
+	n, err := s.Drain(context.Background(), p)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, n)
+	assert.Equal(t, "r0_0", p.InsertIDs()[0])
+	assert.Equal(t, "r4_1", p.InsertIDs()[9])
+	files, _ = filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	assert.Empty(t, files)
+}
+
+func TestDiskLimit(t *testing.T) {
+	s, err := spool.Open("test", spool.Config{Dir: t.TempDir(), MaxBytes: 200}, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Append(rows("a", 2)))
+	assert.ErrorIs(t, s.Append(rows("b", 10)), spool.ErrFull)
+	assert.LessOrEqual(t, s.Size(), int64(200))
+}
+
+func TestReplayErrors(t *testing.T) {
+	s, err := spool.Open("test", spool.Config{Dir: t.TempDir(), BatchSize: 2}, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Append(rows("a", 4)))
+
+	p := batbqtest.NewPutter("table", testSchema)
+	p.RowError = func(row batbqtest.Row) error {
+		if row.InsertID == "a0" {
+			return errors.New("invalid row")
+		}
+		return nil
+	}
+	p.FailNext(1, nil)
+	p.FailNext(1, batbqtest.Unavailable())
+
+	// the first batch is partially stored, the second batch fails
+	n, err := s.Drain(context.Background(), p)
+	assert.Error(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 1, s.Segments())
+
+	// replay continues with the failed batch and drops the invalid row
+	n, err = s.Drain(context.Background(), p)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"a1", "a2", "a3"}, p.InsertIDs())
+	assert.Equal(t, 0, s.Segments())
+}
+
+func TestNumericValues(t *testing.T) {
+	s, err := spool.Open("test", spool.Config{Dir: t.TempDir()}, nil)
+	assert.NoError(t, err)
+
+	row := &spool.Row{InsertID: "n1", Values: map[string]bigquery.Value{
+		"amount":  big.NewRat(1, 4),
+		"amounts": []bigquery.Value{big.NewRat(3, 2)},
+	}}
+	assert.NoError(t, s.Append([]bigquery.ValueSaver{row}))
+
+	p := batbqtest.NewPutter("table", bigquery.Schema{
+		{Name: "amount", Type: bigquery.NumericFieldType},
+		{Name: "amounts", Type: bigquery.NumericFieldType, Repeated: true},
+	})
+	n, err := s.Drain(context.Background(), p)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n, "NUMERIC values must be replayed as decimal strings")
+	if assert.Len(t, p.Rows(), 1) {
+		assert.Equal(t, "0.250000000", p.Rows()[0].Values["amount"])
+		assert.Equal(t, []any{"1.500000000"}, p.Rows()[0].Values["amounts"])
+	}
+}
+
+func TestTimestampAndBytesValues(t *testing.T) {
+	s, err := spool.Open("test", spool.Config{Dir: t.TempDir()}, nil)
+	assert.NoError(t, err)
+
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)
+	row := &spool.Row{InsertID: "t1", Values: map[string]bigquery.Value{
+		"created": ts,
+		"payload": []byte("data"),
+	}}
+	assert.NoError(t, s.Append([]bigquery.ValueSaver{row}))
+
+	p := batbqtest.NewPutter("table", bigquery.Schema{
+		{Name: "created", Type: bigquery.TimestampFieldType},
+		{Name: "payload", Type: bigquery.BytesFieldType},
+	})
+	n, err := s.Drain(context.Background(), p)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n, "TIMESTAMP and BYTES values are replayed in their JSON form")
+	assert.Equal(t, 0, p.Rejected())
+	if assert.Len(t, p.Rows(), 1) {
+		assert.Equal(t, ts.Format(time.RFC3339Nano), p.Rows()[0].Values["created"])
+		assert.Equal(t, "ZGF0YQ==", p.Rows()[0].Values["payload"])
+	}
+}
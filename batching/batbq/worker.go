@@ -2,6 +2,7 @@ package batbq
 
 import (
 	"context"
+	"errors"
 	"log"
 	"sync"
 	"time"
@@ -19,6 +20,7 @@ func (ins *InsertBatcher) worker(ctx context.Context, num int) {
 		cfg    = ins.cfg
 		input  = ins.input
 		output = ins.output
+		dedup  = ins.dedup
 		name   = string(ins.id)
 
 		workers       = ins.metrics.NumWorkers.WithLabelValues(name)
@@ -29,6 +31,8 @@ func (ins *InsertBatcher) worker(ctx context.Context, num int) {
 		batchCount    = ins.metrics.ProcessedBatches.WithLabelValues(name)
 		successCount  = ins.metrics.ProcessedMessages.WithLabelValues(name)
 		pendingSize   = ins.metrics.PendingMessages.WithLabelValues(name)
+		retryCount    = ins.metrics.InsertRetries.WithLabelValues(name)
+		spoolCount    = ins.metrics.SpooledMessages.WithLabelValues(name)
 	)
 
 	workers.Inc()
@@ -45,17 +49,38 @@ func (ins *InsertBatcher) worker(ctx context.Context, num int) {
 		batchCount.Add(1)
 	}
 
-	put := func(messages []Message) error {
-		tStart := time.Now()
-
-		rows := make([]bigquery.ValueSaver, len(messages))
-		for i, m := range messages {
-			rows[i] = m.Data()
+	// putCtx is not canceled on shutdown to insert the last batches,
+	// but retries stop on shutdown and the failed batches are spooled or nacked.
+	putCtx := context.WithoutCancel(ctx)
+	put := func(rows []bigquery.ValueSaver) error {
+		for i := 0; ; i++ {
+			tStart := time.Now()
+			err := output.Put(putCtx, rows)
+			insertLatency.Observe(time.Now().Sub(tStart).Seconds())
+
+			if !retryable(err) || i >= cfg.Retries {
+				return err
+			}
+			retryCount.Inc()
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(cfg.RetryInterval):
+			}
 		}
-		err := output.Put(context.Background(), rows)
+	}
 
-		insertLatency.Observe(time.Now().Sub(tStart).Seconds())
-		return err
+	// spoolBatch stores rows of failed batches and returns nil if the messages can be acked.
+	spoolBatch := func(rows []bigquery.ValueSaver, err error) error {
+		if ins.spool == nil || !retryable(err) {
+			return err
+		}
+		if spoolErr := ins.spool.Append(rows); spoolErr != nil {
+			log.Printf("failed to spool batch after insert error: %v, spool error: %v", err, spoolErr)
+			return err
+		}
+		spoolCount.Add(float64(len(rows)))
+		return nil
 	}
 
 	flush := func() {
@@ -72,8 +97,19 @@ func (ins *InsertBatcher) worker(ctx context.Context, num int) {
 		wg.Add(1) // Ensure we wait for pending puts and (n)acks after the batcher stops.
 		go func(messages []Message) {
 			defer wg.Done() // Allow the batcher to stop after the last batch was processed.
-			err := put(messages)
+			// drop known messages and use the message keys as insert IDs
+			messages, keys := dedup.filter(messages)
+			if len(messages) == 0 {
+				return
+			}
+			rows := make([]bigquery.ValueSaver, len(messages))
+			for i, m := range messages {
+				rows[i] = dedup.saver(m, keys[i])
+			}
+			err := put(rows)
+			err = spoolBatch(rows, err)
 			confirm(messages, err)
+			dedup.commit(keys, err)
 		}(batch)
 
 		batch = make([]Message, 0, cfg.Capacity) // create a new slice to allow immediate refill
@@ -106,3 +142,13 @@ func (ins *InsertBatcher) worker(ctx context.Context, num int) {
 		}
 	}
 }
+
+// retryable returns true if the error failed the whole batch and was not caused by a shutdown.
+// Row errors reported via a `bigquery.PutMultiError` are not retryable.
+func retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	_, isMulti := err.(bigquery.PutMultiError)
+	return !isMulti
+}
@@ -25,6 +25,11 @@ type BatchedSubscription struct {
 	Receiver
 	capacity      int
 	flushInterval time.Duration
+	retries       int
+	retryInterval time.Duration
+	keyFunc       KeyFunc
+	maxInFlight   int
+	maxBytes      int
 	metrics       *Metrics
 }
 
@@ -40,9 +45,17 @@ func NewBatchedSubscription(receiver Receiver, opt ...Option) *BatchedSubscripti
 	if b.flushInterval == 0 {
 		b.flushInterval = DefaultFlushInterval
 	}
+	if b.retryInterval == 0 {
+		b.retryInterval = DefaultRetryInterval
+	}
 	return b
 }
 
+// Metrics returns the metrics.
+func (sub *BatchedSubscription) Metrics() *Metrics {
+	return sub.metrics
+}
+
 // BatchFunc handles a batch of messages.
 type BatchFunc func(ctx context.Context, messages []*pubsub.Message)
 
@@ -76,30 +89,32 @@ func (sub *BatchedSubscription) ReceiveBatches(ctx context.Context, f BatchFunc)
 	var wg sync.WaitGroup
 
 	var (
-		id        = sub.ID()
-		pending   = sub.metrics.PendingMessages.WithLabelValues(id)
-		processed = sub.metrics.ProcessedMessages.WithLabelValues(id)
-		flushed   = sub.metrics.ProcessedBatches.WithLabelValues(id)
-		latency   = sub.metrics.ProcessingLatency.WithLabelValues(id)
+		id      = sub.ID()
+		pending = sub.metrics.PendingMessages.WithLabelValues(id)
+		process = sub.processor(ctx, f)
+		flow    = sub.flowController()
 	)
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 
+		if sub.keyFunc != nil {
+			sub.batchByKey(ch, process, flow)
+			return
+		}
+
 		var batch []*pubsub.Message
 		flush := func() {
 			if len(batch) == 0 {
 				return
 			}
+			flow.acquireBatch()
 			wg.Add(1) // ensure we wait for pending flushes
 			go func(batch []*pubsub.Message) {
 				defer wg.Done()
-				f(ctx, batch)
-				// track progress after batch is completed
-				latency.Observe(float64(time.Since(time.Now())))
-				flushed.Add(float64(len(batch)))
-				processed.Add(float64(len(batch)))
+				defer flow.release(batch)
+				process(batch)
 			}(batch)
 
 			batch = make([]*pubsub.Message, 0, sub.capacity)
@@ -129,7 +144,8 @@ func (sub *BatchedSubscription) ReceiveBatches(ctx context.Context, f BatchFunc)
 
 	// The receiver will block until it is stopped via the external context.
 	// After it stopped, no more messages must be sent to the channel.
-	err := sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) { ch <- msg })
+	// The flow controller blocks the receiver if too many bytes are outstanding.
+	err := sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) { flow.receive(ctx, msg, ch) })
 
 	// The channel can now be closed safely to stop the batching goroutine.
 	close(ch)
@@ -143,3 +159,28 @@ func (sub *BatchedSubscription) ReceiveBatches(ctx context.Context, f BatchFunc)
 
 	return nil
 }
+
+// processor returns a func that calls f with a batch and tracks the progress.
+func (sub *BatchedSubscription) processor(ctx context.Context, f BatchFunc) func(batch []*pubsub.Message) {
+	var (
+		id        = sub.ID()
+		processed = sub.metrics.ProcessedMessages.WithLabelValues(id)
+		flushed   = sub.metrics.ProcessedBatches.WithLabelValues(id)
+		latency   = sub.metrics.ProcessingLatency.WithLabelValues(id)
+		e2e       = sub.metrics.EndToEndLatency.WithLabelValues(id)
+	)
+	return func(batch []*pubsub.Message) {
+		tStart := time.Now()
+		f(ctx, batch)
+		// track progress after batch is completed
+		tEnd := time.Now()
+		latency.Observe(tEnd.Sub(tStart).Seconds())
+		for _, m := range batch {
+			if !m.PublishTime.IsZero() {
+				e2e.Observe(tEnd.Sub(m.PublishTime).Seconds())
+			}
+		}
+		flushed.Inc()
+		processed.Add(float64(len(batch)))
+	}
+}
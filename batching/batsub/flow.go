@@ -0,0 +1,134 @@
+package batsub
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MaxInFlightBatches limits the number of concurrently processed batches.
+// Flushing a batch blocks until a running batch is completed. Zero means no limit.
+type MaxInFlightBatches int
+
+func (opt MaxInFlightBatches) apply(sub *BatchedSubscription) {
+	sub.maxInFlight = int(opt)
+}
+
+// MaxOutstandingBytes limits the size of the message data held by the batcher, including the
+// messages of batches that are still processed. Receiving more messages blocks until processed
+// messages are released. Zero means no limit.
+type MaxOutstandingBytes int
+
+func (opt MaxOutstandingBytes) apply(sub *BatchedSubscription) {
+	sub.maxBytes = int(opt)
+}
+
+// MaxExtension sets the maximum time the PubSub client extends the ack deadline of messages that
+// are held by the batcher. It is only applied if the receiver is a `*pubsub.Subscription`.
+type MaxExtension time.Duration
+
+func (opt MaxExtension) apply(sub *BatchedSubscription) {
+	if s, ok := sub.Receiver.(*pubsub.Subscription); ok {
+		s.ReceiveSettings.MaxExtension = time.Duration(opt)
+	}
+}
+
+// flowController limits the number of in-flight batches and outstanding bytes.
+type flowController struct {
+	maxBytes int
+	slots    chan struct{} // nil if the number of in-flight batches is not limited
+
+	mu       sync.Mutex
+	bytes    int
+	released chan struct{} // closed and replaced whenever bytes are released
+
+	inFlight     prometheus.Gauge
+	outstanding  prometheus.Gauge
+	recvBlocked  prometheus.Counter
+	flushBlocked prometheus.Counter
+}
+
+func (sub *BatchedSubscription) flowController() *flowController {
+	id := sub.ID()
+	fc := &flowController{
+		maxBytes:     sub.maxBytes,
+		released:     make(chan struct{}),
+		inFlight:     sub.metrics.InFlightBatches.WithLabelValues(id),
+		outstanding:  sub.metrics.OutstandingBytes.WithLabelValues(id),
+		recvBlocked:  sub.metrics.ReceiveBlocked.WithLabelValues(id),
+		flushBlocked: sub.metrics.FlushBlocked.WithLabelValues(id),
+	}
+	if sub.maxInFlight > 0 {
+		fc.slots = make(chan struct{}, sub.maxInFlight)
+	}
+	return fc
+}
+
+// acquireBytes blocks until n bytes are available or the context is done.
+// A message that exceeds the limit is admitted if no other bytes are outstanding.
+func (fc *flowController) acquireBytes(ctx context.Context, n int) error {
+	for {
+		fc.mu.Lock()
+		if fc.maxBytes <= 0 || fc.bytes == 0 || fc.bytes+n <= fc.maxBytes {
+			fc.bytes += n
+			fc.outstanding.Set(float64(fc.bytes))
+			fc.mu.Unlock()
+			return nil
+		}
+		released := fc.released
+		fc.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-released:
+		}
+	}
+}
+
+func (fc *flowController) releaseBytes(n int) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.bytes -= n
+	fc.outstanding.Set(float64(fc.bytes))
+	close(fc.released)
+	fc.released = make(chan struct{})
+}
+
+// acquireBatch blocks until a batch can be processed.
+func (fc *flowController) acquireBatch() {
+	if fc.slots != nil {
+		t := time.Now()
+		fc.slots <- struct{}{}
+		fc.flushBlocked.Add(time.Since(t).Seconds())
+	}
+	fc.inFlight.Inc()
+}
+
+// release releases the batch and the bytes of its messages.
+func (fc *flowController) release(batch []*pubsub.Message) {
+	fc.inFlight.Dec()
+	if fc.slots != nil {
+		<-fc.slots
+	}
+	n := 0
+	for _, m := range batch {
+		n += len(m.Data)
+	}
+	fc.releaseBytes(n)
+}
+
+// receive sends the message to the channel and blocks until enough bytes are available.
+// Messages that cannot be admitted before the context is done are nacked.
+func (fc *flowController) receive(ctx context.Context, msg *pubsub.Message, ch chan<- *pubsub.Message) {
+	t := time.Now()
+	defer func() { fc.recvBlocked.Add(time.Since(t).Seconds()) }()
+	if err := fc.acquireBytes(ctx, len(msg.Data)); err != nil {
+		msg.Nack()
+		return
+	}
+	ch <- msg
+}
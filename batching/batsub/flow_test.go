@@ -0,0 +1,101 @@
+package batsub_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/batching/batsub"
+)
+
+func TestMaxInFlightBatches(t *testing.T) {
+	rec := &source{messages: testMessages(20), done: make(chan struct{})}
+	sub := batsub.NewBatchedSubscription(rec, batsub.Capacity(2), batsub.MaxInFlightBatches(2))
+	mtx := sub.Metrics()
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	err := sub.ReceiveBatches(context.Background(), func(ctx context.Context, messages []*pubsub.Message) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, maxInFlight)
+	assert.Equal(t, 10.0, testutil.ToFloat64(mtx.ProcessedBatches))
+	assert.Equal(t, 0.0, testutil.ToFloat64(mtx.InFlightBatches))
+	assert.Greater(t, testutil.ToFloat64(mtx.FlushBlocked), 0.0)
+}
+
+func TestMaxOutstandingBytes(t *testing.T) {
+	data := make([]*pubsub.Message, 20)
+	for i := range data {
+		data[i] = &pubsub.Message{ID: string(rune('a' + i)), Data: make([]byte, 10)}
+	}
+	rec := &source{messages: data, done: make(chan struct{})}
+	sub := batsub.NewBatchedSubscription(rec,
+		batsub.Capacity(2),
+		batsub.FlushInterval(5*time.Millisecond),
+		batsub.MaxOutstandingBytes(30),
+	)
+	mtx := sub.Metrics()
+
+	var mu sync.Mutex
+	maxBytes, numMessages := 0.0, 0
+	err := sub.ReceiveBatches(context.Background(), func(ctx context.Context, messages []*pubsub.Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		if v := testutil.ToFloat64(mtx.OutstandingBytes); v > maxBytes {
+			maxBytes = v
+		}
+		numMessages += len(messages)
+		time.Sleep(2 * time.Millisecond)
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 20, numMessages)
+	assert.LessOrEqual(t, maxBytes, 30.0)
+	assert.Equal(t, 0.0, testutil.ToFloat64(mtx.OutstandingBytes))
+	assert.Greater(t, testutil.ToFloat64(mtx.ReceiveBlocked), 0.0)
+}
+
+func TestFlowControlPartitions(t *testing.T) {
+	data := testMessages(30)
+	for i, m := range data {
+		m.OrderingKey = string(rune('a' + i%5))
+	}
+	rec := &source{messages: data, done: make(chan struct{})}
+	sub := batsub.NewBatchedSubscription(rec,
+		batsub.Capacity(3),
+		batsub.MaxInFlightBatches(1),
+		batsub.ByOrderingKey,
+	)
+
+	var mu sync.Mutex
+	inFlight, numMessages := 0, 0
+	err := sub.ReceiveBatches(context.Background(), func(ctx context.Context, messages []*pubsub.Message) {
+		mu.Lock()
+		inFlight++
+		assert.Equal(t, 1, inFlight)
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		inFlight--
+		numMessages += len(messages)
+		mu.Unlock()
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 30, numMessages)
+}
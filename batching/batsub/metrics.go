@@ -15,14 +15,27 @@ const (
 // Metrics stores Batcher Metrics.
 type Metrics struct {
 	// State
-	PendingMessages *prometheus.GaugeVec
+	PendingMessages  *prometheus.GaugeVec
+	InFlightBatches  *prometheus.GaugeVec
+	OutstandingBytes *prometheus.GaugeVec
+
+	// Flow Control
+	ReceiveBlocked *prometheus.CounterVec
+	FlushBlocked   *prometheus.CounterVec
 
 	// Results
 	ProcessedMessages *prometheus.CounterVec
 	ProcessedBatches  *prometheus.CounterVec
 
+	// Confirmations
+	AckedMessages       *prometheus.CounterVec
+	NackedMessages      *prometheus.CounterVec
+	RetriedMessages     *prometheus.CounterVec
+	UnconfirmedMessages *prometheus.CounterVec
+
 	// Latencies
 	ProcessingLatency *prometheus.HistogramVec
+	EndToEndLatency   *prometheus.HistogramVec
 }
 
 // NewMetrics returns prefixed metrics.
@@ -38,6 +51,27 @@ func NewMetrics(prefix ...string) *Metrics {
 			Name:      "pending_messages",
 			Namespace: ns,
 		}, label),
+		InFlightBatches: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "in_flight_batches",
+			Namespace: ns,
+		}, label),
+		OutstandingBytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "outstanding_bytes",
+			Namespace: ns,
+			Help:      "Size of the message data held by the batcher.",
+		}, label),
+
+		// Flow Control
+		ReceiveBlocked: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "receive_blocked_seconds_total",
+			Namespace: ns,
+			Help:      "Time the receiver was blocked by the batcher.",
+		}, label),
+		FlushBlocked: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "flush_blocked_seconds_total",
+			Namespace: ns,
+			Help:      "Time flushed batches waited for an in-flight slot.",
+		}, label),
 
 		// Results
 		ProcessedMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
@@ -49,18 +83,51 @@ func NewMetrics(prefix ...string) *Metrics {
 			Namespace: ns,
 		}, label),
 
+		// Confirmations
+		AckedMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "acked_messages_total",
+			Namespace: ns,
+		}, label),
+		NackedMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "nacked_messages_total",
+			Namespace: ns,
+		}, label),
+		RetriedMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "retried_messages_total",
+			Namespace: ns,
+		}, label),
+		UnconfirmedMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "unconfirmed_messages_total",
+			Namespace: ns,
+			Help:      "Messages without a result from the handler. They are nacked to be redelivered.",
+		}, label),
+
 		// Latencies
 		ProcessingLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
 			Name:      "processing_latency_seconds",
 			Namespace: ns,
 		}, label),
+		EndToEndLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:      "end_to_end_latency_seconds",
+			Namespace: ns,
+			Help:      "Time from publishing a message until its batch was processed.",
+		}, label),
 	}
 }
 
 // Register registers all metrics.
 func (m *Metrics) Register(reg prometheus.Registerer) {
 	reg.MustRegister(m.PendingMessages)
+	reg.MustRegister(m.InFlightBatches)
+	reg.MustRegister(m.OutstandingBytes)
+	reg.MustRegister(m.ReceiveBlocked)
+	reg.MustRegister(m.FlushBlocked)
 	reg.MustRegister(m.ProcessedBatches)
 	reg.MustRegister(m.ProcessedMessages)
+	reg.MustRegister(m.AckedMessages)
+	reg.MustRegister(m.NackedMessages)
+	reg.MustRegister(m.RetriedMessages)
+	reg.MustRegister(m.UnconfirmedMessages)
 	reg.MustRegister(m.ProcessingLatency)
+	reg.MustRegister(m.EndToEndLatency)
 }
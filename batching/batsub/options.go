@@ -24,3 +24,17 @@ type Capacity int
 func (opt Capacity) apply(sub *BatchedSubscription) {
 	sub.capacity = int(opt)
 }
+
+// Retries sets how often failed messages are retried by `ReceiveResults`.
+type Retries int
+
+func (opt Retries) apply(sub *BatchedSubscription) {
+	sub.retries = int(opt)
+}
+
+// RetryInterval sets the time between retries of failed messages.
+type RetryInterval time.Duration
+
+func (opt RetryInterval) apply(sub *BatchedSubscription) {
+	sub.retryInterval = time.Duration(opt)
+}
@@ -0,0 +1,149 @@
+package batsub
+
+import (
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// KeyFunc returns the partition key of a message. It can be used as Option to enable
+// key-partitioned batching.
+type KeyFunc func(m *pubsub.Message) string
+
+func (opt KeyFunc) apply(sub *BatchedSubscription) {
+	sub.keyFunc = opt
+}
+
+// ByOrderingKey partitions the batches by the PubSub ordering key.
+var ByOrderingKey KeyFunc = func(m *pubsub.Message) string { return m.OrderingKey }
+
+// partition stores the batches of a key.
+type partition struct {
+	key      string
+	batch    []*pubsub.Message   // current batch
+	gen      int                 // generation of the current batch to detect outdated timers
+	timer    *time.Timer         // flush timer of the current batch
+	queue    [][]*pubsub.Message // flushed batches waiting for the in-flight batch
+	inFlight bool
+}
+
+// idle returns true if the partition has no messages.
+func (p *partition) idle() bool {
+	return !p.inFlight && len(p.batch) == 0 && len(p.queue) == 0
+}
+
+// timeout identifies the batch of a partition that reached the flush interval.
+type timeout struct {
+	key string
+	gen int
+}
+
+// batchByKey batches messages from the channel per key until the channel is closed and all
+// batches are processed. Each key uses a separate capacity and flush timer. The batches of a key
+// are processed one after another in the order of the messages.
+func (sub *BatchedSubscription) batchByKey(ch <-chan *pubsub.Message, process func(batch []*pubsub.Message), flow *flowController) {
+	var (
+		partitions = make(map[string]*partition)
+		timeouts   = make(chan timeout)
+		finished   = make(chan string)
+		stop       = make(chan struct{})
+		pending    = sub.metrics.PendingMessages.WithLabelValues(sub.ID())
+		input      = ch
+	)
+	defer close(stop) // release pending timers
+
+	// start processes the next batch of an idle partition.
+	start := func(p *partition) {
+		if p.inFlight || len(p.queue) == 0 {
+			return
+		}
+		batch := p.queue[0]
+		p.queue = p.queue[1:]
+		p.inFlight = true
+		go func() {
+			flow.acquireBatch()
+			process(batch)
+			flow.release(batch)
+			finished <- p.key
+		}()
+	}
+
+	flush := func(p *partition) {
+		if p.timer != nil {
+			p.timer.Stop()
+			p.timer = nil
+		}
+		if len(p.batch) == 0 {
+			return
+		}
+		p.queue = append(p.queue, p.batch)
+		p.batch = make([]*pubsub.Message, 0, sub.capacity)
+		p.gen++
+		start(p)
+	}
+
+	add := func(msg *pubsub.Message) {
+		key := sub.keyFunc(msg)
+		p, ok := partitions[key]
+		if !ok {
+			p = &partition{key: key}
+			partitions[key] = p
+		}
+		p.batch = append(p.batch, msg)
+		if len(p.batch) >= sub.capacity {
+			flush(p)
+			pending.Set(float64(len(ch)))
+			return
+		}
+		if p.timer == nil {
+			t := timeout{key, p.gen}
+			p.timer = time.AfterFunc(sub.flushInterval, func() {
+				select {
+				case timeouts <- t:
+				case <-stop:
+				}
+			})
+		}
+	}
+
+	for {
+		if input == nil {
+			// The input is closed and all batches are flushed.
+			// Wait until all in-flight batches are processed.
+			done := true
+			for _, p := range partitions {
+				if !p.idle() {
+					done = false
+					break
+				}
+			}
+			if done {
+				return
+			}
+		}
+
+		select {
+		case msg, more := <-input:
+			if !more {
+				input = nil
+				for _, p := range partitions {
+					flush(p)
+				}
+				continue
+			}
+			add(msg)
+		case t := <-timeouts:
+			if p, ok := partitions[t.key]; ok && p.gen == t.gen {
+				p.timer = nil
+				flush(p)
+			}
+		case key := <-finished:
+			p := partitions[key]
+			p.inFlight = false
+			start(p)
+			if p.idle() {
+				delete(partitions, key)
+			}
+		}
+	}
+}
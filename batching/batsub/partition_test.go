@@ -0,0 +1,88 @@
+package batsub_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/batching/batsub"
+)
+
+func TestPartitionedBatches(t *testing.T) {
+	keys := []string{"a", "b", "c"}
+	var data []*pubsub.Message
+	for i := 0; i < 90; i++ {
+		key := keys[i%len(keys)]
+		data = append(data, &pubsub.Message{ID: fmt.Sprint(i), OrderingKey: key})
+	}
+	rec := &source{messages: data, done: make(chan struct{})}
+	sub := batsub.NewBatchedSubscription(rec,
+		batsub.Capacity(4),
+		batsub.FlushInterval(5*time.Millisecond),
+		batsub.ByOrderingKey,
+	)
+
+	var mu sync.Mutex
+	inFlight := make(map[string]bool)
+	results := make(map[string][]string)
+	numBatches := 0
+	err := sub.ReceiveBatches(context.Background(), func(ctx context.Context, messages []*pubsub.Message) {
+		key := messages[0].OrderingKey
+		mu.Lock()
+		assert.False(t, inFlight[key], "concurrent batches for key %s", key)
+		inFlight[key] = true
+		numBatches++
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		inFlight[key] = false
+		for _, m := range messages {
+			assert.Equal(t, key, m.OrderingKey, "mixed keys in batch")
+			results[key] = append(results[key], m.ID)
+		}
+	})
+	assert.NoError(t, err)
+
+	for i, key := range keys {
+		var expected []string
+		for j := i; j < 90; j += len(keys) {
+			expected = append(expected, fmt.Sprint(j))
+		}
+		assert.Equal(t, expected, results[key], "messages of key %s must be in order", key)
+	}
+	assert.GreaterOrEqual(t, numBatches, 24)
+}
+
+func TestPartitionFlushInterval(t *testing.T) {
+	data := []*pubsub.Message{
+		{ID: "1", Attributes: map[string]string{"tenant": "x"}},
+		{ID: "2", Attributes: map[string]string{"tenant": "y"}},
+		{ID: "3", Attributes: map[string]string{"tenant": "x"}},
+	}
+	rec := &source{messages: data, done: make(chan struct{}), sendDelay: 10 * time.Millisecond}
+	byTenant := batsub.KeyFunc(func(m *pubsub.Message) string { return m.Attributes["tenant"] })
+	sub := batsub.NewBatchedSubscription(rec, batsub.Capacity(10), batsub.FlushInterval(time.Second), byTenant)
+
+	var mu sync.Mutex
+	var batches [][]string
+	err := sub.ReceiveBatches(context.Background(), func(ctx context.Context, messages []*pubsub.Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		var ids []string
+		for _, m := range messages {
+			ids = append(ids, m.ID)
+		}
+		batches = append(batches, ids)
+	})
+	assert.NoError(t, err)
+
+	// all partial batches are flushed when the receiver stops
+	assert.ElementsMatch(t, [][]string{{"1", "3"}, {"2"}}, batches)
+}
@@ -0,0 +1,101 @@
+package batsub
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// DefaultRetryInterval defines the default time between retries of failed messages.
+var DefaultRetryInterval = 100 * time.Millisecond
+
+// ErrPermanent marks errors of messages that must not be retried.
+var ErrPermanent = errors.New("permanent error")
+
+type permanentError struct{ error }
+
+func (e permanentError) Unwrap() []error { return []error{e.error, ErrPermanent} }
+
+// Permanent wraps an error to prevent retries of the failed message.
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+// ResultFunc handles a batch of messages and returns an error for each message.
+// Returning a nil slice indicates that all messages were processed successfully.
+// Messages without a result, e.g., if the returned slice is too short, are considered as
+// unconfirmed. They are nacked and counted by the `UnconfirmedMessages` metric.
+type ResultFunc func(ctx context.Context, messages []*pubsub.Message) []error
+
+// ReceiveResults calls f with the outstanding batched messages from the subscription and
+// acks or nacks the messages according to the results of f.
+//
+// Failed messages are retried up to the configured number of `Retries` by calling f with the
+// subset of failed messages. Errors wrapped using `Permanent(err)` are not retried.
+//
+// Example:
+//
+//	err := sub.ReceiveResults(ctx, func(ctx context.Context, messages []*pubsub.Message) []error {
+//	    // handle batch of messages using a batch-processing library
+//	    return mylib.BatchProcessMessages(messages)
+//	})
+func (sub *BatchedSubscription) ReceiveResults(ctx context.Context, f ResultFunc) error {
+	return sub.ReceiveBatches(ctx, sub.confirm(f))
+}
+
+// confirm returns a BatchFunc that calls the ResultFunc, retries failed messages,
+// and acks or nacks the messages.
+func (sub *BatchedSubscription) confirm(f ResultFunc) BatchFunc {
+	var (
+		id          = sub.ID()
+		acked       = sub.metrics.AckedMessages.WithLabelValues(id)
+		nacked      = sub.metrics.NackedMessages.WithLabelValues(id)
+		unconfirmed = sub.metrics.UnconfirmedMessages.WithLabelValues(id)
+		retried     = sub.metrics.RetriedMessages.WithLabelValues(id)
+	)
+
+	return func(ctx context.Context, messages []*pubsub.Message) {
+		for attempt := 0; len(messages) > 0; attempt++ {
+			results := f(ctx, messages)
+			retry := attempt < sub.retries && ctx.Err() == nil
+
+			var failed []*pubsub.Message
+			for i, m := range messages {
+				switch {
+				case results == nil:
+					m.Ack()
+					acked.Inc()
+				case i >= len(results):
+					m.Nack()
+					unconfirmed.Inc()
+				case results[i] == nil:
+					m.Ack()
+					acked.Inc()
+				case retry && !errors.Is(results[i], ErrPermanent):
+					failed = append(failed, m)
+				default:
+					m.Nack()
+					nacked.Inc()
+				}
+			}
+
+			if len(failed) == 0 {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				// do not call f after shutdown, the messages are redelivered
+				for _, m := range failed {
+					m.Nack()
+				}
+				nacked.Add(float64(len(failed)))
+				return
+			case <-time.After(sub.retryInterval):
+			}
+			retried.Add(float64(len(failed)))
+			messages = failed
+		}
+	}
+}
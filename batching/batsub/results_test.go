@@ -0,0 +1,172 @@
+package batsub_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/batching/batsub"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+)
+
+// fakeSubscription returns a subscription of a fake PubSub server with `n` published messages.
+func fakeSubscription(t *testing.T, n int) (*pstest.Server, *pubsub.Subscription, []string) {
+	ctx := context.Background()
+	srv := pstest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+
+	conn, err := grpc.Dial(srv.Addr, grpc.WithInsecure())
+	assert.NoError(t, err)
+	client, err := pubsub.NewClient(ctx, "project", option.WithGRPCConn(conn))
+	assert.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	topic, err := client.CreateTopic(ctx, "topic")
+	assert.NoError(t, err)
+	sub, err := client.CreateSubscription(ctx, "sub", pubsub.SubscriptionConfig{Topic: topic})
+	assert.NoError(t, err)
+
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = srv.Publish("projects/project/topics/topic", []byte(fmt.Sprint(i)), nil)
+	}
+	return srv, sub, ids
+}
+
+func testMessages(n int) []*pubsub.Message {
+	res := make([]*pubsub.Message, n)
+	for i := range res {
+		res[i] = &pubsub.Message{ID: fmt.Sprint(i), Data: []byte(fmt.Sprint(i))}
+	}
+	return res
+}
+
+func TestReceiveResults(t *testing.T) {
+	srv, sub, ids := fakeSubscription(t, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bs := batsub.NewBatchedSubscription(sub, batsub.Capacity(10), batsub.FlushInterval(10*time.Millisecond))
+	mtx := bs.Metrics()
+
+	done := make(chan error)
+	go func() {
+		done <- bs.ReceiveResults(ctx, func(ctx context.Context, messages []*pubsub.Message) []error {
+			errs := make([]error, len(messages))
+			for i, m := range messages {
+				if v, _ := strconv.Atoi(string(m.Data)); v%2 == 1 {
+					errs[i] = errors.New("odd value")
+				}
+			}
+			return errs
+		})
+	}()
+
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(mtx.AckedMessages) == 5 && testutil.ToFloat64(mtx.NackedMessages) >= 5
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	assert.NoError(t, <-done)
+
+	for i, id := range ids {
+		assert.Equal(t, 1-i%2, srv.Message(id).Acks, "acks of message %d", i)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	rec := &source{messages: testMessages(10), done: make(chan struct{})}
+	sub := batsub.NewBatchedSubscription(rec,
+		batsub.Capacity(10),
+		batsub.Retries(2),
+		batsub.RetryInterval(time.Millisecond),
+	)
+	mtx := sub.Metrics()
+
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	err := sub.ReceiveResults(context.Background(), func(ctx context.Context, messages []*pubsub.Message) []error {
+		mu.Lock()
+		defer mu.Unlock()
+		errs := make([]error, len(messages))
+		for i, m := range messages {
+			attempts[m.ID]++
+			switch {
+			case m.ID == "0":
+				errs[i] = errors.New("always fails")
+			case m.ID == "1" && attempts[m.ID] == 1:
+				errs[i] = errors.New("fails once")
+			case m.ID == "2":
+				errs[i] = batsub.Permanent(errors.New("not retried"))
+			}
+		}
+		if len(messages) == 10 {
+			// leave the last message unconfirmed
+			return errs[:9]
+		}
+		return errs
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]int{
+		"0": 3, "1": 2, "2": 1, "3": 1, "4": 1, "5": 1, "6": 1, "7": 1, "8": 1, "9": 1,
+	}, attempts)
+	assert.Equal(t, 7.0, testutil.ToFloat64(mtx.AckedMessages))
+	assert.Equal(t, 2.0, testutil.ToFloat64(mtx.NackedMessages))
+	assert.Equal(t, 1.0, testutil.ToFloat64(mtx.UnconfirmedMessages))
+	assert.Equal(t, 3.0, testutil.ToFloat64(mtx.RetriedMessages))
+	assert.ErrorIs(t, batsub.Permanent(errors.New("err")), batsub.ErrPermanent)
+}
+
+func TestNoRetriesAfterShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rec := &source{messages: testMessages(10), done: make(chan struct{})}
+	sub := batsub.NewBatchedSubscription(rec,
+		batsub.Capacity(10),
+		batsub.Retries(2),
+		batsub.RetryInterval(time.Hour),
+	)
+	mtx := sub.Metrics()
+
+	calls := 0
+	err := sub.ReceiveResults(ctx, func(ctx context.Context, messages []*pubsub.Message) []error {
+		calls++
+		time.AfterFunc(10*time.Millisecond, cancel) // shut down during the retry wait
+		errs := make([]error, len(messages))
+		for i := range errs {
+			errs[i] = errors.New("failed")
+		}
+		return errs
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 10.0, testutil.ToFloat64(mtx.NackedMessages))
+	assert.Equal(t, 0.0, testutil.ToFloat64(mtx.RetriedMessages))
+}
+
+func TestEndToEndLatency(t *testing.T) {
+	msg := &pubsub.Message{ID: "1", PublishTime: time.Now().Add(-time.Second)}
+	rec := &source{messages: []*pubsub.Message{msg, {ID: "2"}}, done: make(chan struct{})}
+	sub := batsub.NewBatchedSubscription(rec, batsub.Capacity(10))
+	mtx := sub.Metrics()
+
+	err := sub.ReceiveBatches(context.Background(), func(ctx context.Context, messages []*pubsub.Message) {})
+	assert.NoError(t, err)
+
+	m := &dto.Metric{}
+	assert.NoError(t, mtx.EndToEndLatency.WithLabelValues("test").(interface{ Write(*dto.Metric) error }).Write(m))
+	assert.Equal(t, uint64(1), m.GetHistogram().GetSampleCount(), "messages without publish time are skipped")
+	assert.GreaterOrEqual(t, m.GetHistogram().GetSampleSum(), 1.0)
+	assert.Equal(t, 1.0, testutil.ToFloat64(mtx.ProcessedBatches))
+	assert.Equal(t, 2.0, testutil.ToFloat64(mtx.ProcessedMessages))
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArgType defines the type of a command argument.
+type ArgType int
+
+// Supported argument types.
+const (
+	StringArg ArgType = iota
+	IntArg
+	FloatArg
+	BoolArg
+	DurationArg
+)
+
+func (t ArgType) String() string {
+	switch t {
+	case IntArg:
+		return "int"
+	case FloatArg:
+		return "float"
+	case BoolArg:
+		return "bool"
+	case DurationArg:
+		return "duration"
+	default:
+		return "string"
+	}
+}
+
+// Arg defines a typed parameter of a command.
+type Arg struct {
+	Name     string
+	Type     ArgType
+	Optional bool
+	Values   []string // allowed values, also used for tab completion
+}
+
+// usage returns the argument formatted for help output.
+func (a Arg) usage() string {
+	s := a.Name
+	if len(a.Values) > 0 {
+		s = strings.Join(a.Values, "|")
+	}
+	if a.Optional {
+		return "[" + s + "]"
+	}
+	return "<" + s + ">"
+}
+
+// parse converts the string value to the argument type.
+func (a Arg) parse(s string) (any, error) {
+	if len(a.Values) > 0 && !contains(a.Values, s) {
+		return nil, fmt.Errorf("invalid %s %q, expected one of: %s", a.Name, s, strings.Join(a.Values, ", "))
+	}
+	var (
+		v   any
+		err error
+	)
+	switch a.Type {
+	case IntArg:
+		v, err = strconv.Atoi(s)
+	case FloatArg:
+		v, err = strconv.ParseFloat(s, 64)
+	case BoolArg:
+		v, err = strconv.ParseBool(s)
+	case DurationArg:
+		v, err = time.ParseDuration(s)
+	default:
+		v = s
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q, expected %s", a.Name, s, a.Type)
+	}
+	return v, nil
+}
+
+// Args stores the parsed arguments of a command by name.
+type Args map[string]any
+
+// String returns a string argument or "".
+func (a Args) String(name string) string { v, _ := a[name].(string); return v }
+
+// Int returns an int argument or 0.
+func (a Args) Int(name string) int { v, _ := a[name].(int); return v }
+
+// Float returns a float argument or 0.
+func (a Args) Float(name string) float64 { v, _ := a[name].(float64); return v }
+
+// Bool returns a bool argument or false.
+func (a Args) Bool(name string) bool { v, _ := a[name].(bool); return v }
+
+// Duration returns a duration argument or 0.
+func (a Args) Duration(name string) time.Duration { v, _ := a[name].(time.Duration); return v }
+
+// Has tells if the argument was given.
+func (a Args) Has(name string) bool { _, ok := a[name]; return ok }
+
+// ParseArgs parses the positional values according to the argument definitions.
+func ParseArgs(defs []Arg, values []string) (Args, error) {
+	if len(values) > len(defs) {
+		return nil, fmt.Errorf("too many arguments: %s", strings.Join(values[len(defs):], " "))
+	}
+	args := make(Args)
+	for i, def := range defs {
+		if i >= len(values) {
+			if !def.Optional {
+				return nil, fmt.Errorf("missing argument: %s", def.Name)
+			}
+			continue
+		}
+		v, err := def.parse(values[i])
+		if err != nil {
+			return nil, err
+		}
+		args[def.Name] = v
+	}
+	return args, nil
+}
+
+// SplitArgs splits a command line into words. Single and double quotes group words.
+func SplitArgs(line string) []string {
+	var (
+		words []string
+		word  strings.Builder
+		quote rune
+		open  bool // a word was started, even if empty
+	)
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, open = r, true
+		case r == ' ' || r == '\t':
+			if open {
+				words = append(words, word.String())
+				word.Reset()
+				open = false
+			}
+		default:
+			word.WriteRune(r)
+			open = true
+		}
+	}
+	if open {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
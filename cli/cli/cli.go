@@ -4,23 +4,15 @@ import (
 	"context"
 	"log"
 	"os"
-	"os/signal"
 	"sync"
-	"syscall"
 
 	"github.com/ubntc/go/cli/cli/config"
 )
 
-func newSigChan() <-chan os.Signal {
-	// size > 1 makes the channel non-blocking
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
-	return sig
-}
-
 // SigWait waits for OS signals SIGINT or SIGTERM or the termination of the given context.
 // It blocks until the context is canceled either by the awaited signal or externally.
 // It returns the received signal and the context's error.
+// While waiting, SIGHUP and SIGQUIT are handled by the global ShutdownManager.
 //
 // Usage:
 //
@@ -32,7 +24,8 @@ func newSigChan() <-chan os.Signal {
 //		 sig, err := cli.SigWait(ctx)
 //		 fmt.Println("stopping application")
 func SigWait(ctx context.Context) (os.Signal, error) {
-	sig := newSigChan()
+	sig, stopSignals := GetShutdownManager().Notify()
+	defer stopSignals()
 	var s os.Signal
 
 	// block until signal is received or context is cancelled
@@ -52,8 +45,11 @@ func SigWait(ctx context.Context) (os.Signal, error) {
 // StartTerm starts terminal session management and input handling (if configured)
 // and returns a context.Context to manage the corresponding goroutines or running i/o pipes.
 // It cancels the context on receiving a SIGINT or SIGTERM from the OS.
+// After the terminal is restored, it runs the hooks of the global ShutdownManager.
+// Use GetShutdownManager().Done() to wait for the hooks.
 func StartTerm(parent context.Context, cfg config.Config, cmds ...Command) (context.Context, context.CancelFunc) {
-	sig := newSigChan()
+	shutdown := GetShutdownManager()
+	sig, stopSignals := shutdown.Notify()
 	input := os.Stdin
 	// var interactive bool
 
@@ -82,6 +78,19 @@ func StartTerm(parent context.Context, cfg config.Config, cmds ...Command) (cont
 	// 2. the clock has stopped
 	var wg sync.WaitGroup
 
+	t := GetTerm()
+	t.SetColor(!cfg.NoColor)
+	t.SetASCII(cfg.ASCII)
+	t.SetWidth(cfg.Width)
+	if cfg.Width == 0 {
+		// track the width for clearing the status area after resizing the terminal
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t.TrackSize(inputCtx) // blocking
+		}()
+	}
+
 	// start clock separately
 	if opts.ShowClock {
 		wg.Add(1)
@@ -94,14 +103,51 @@ func StartTerm(parent context.Context, cfg config.Config, cmds ...Command) (cont
 
 	restoreStdio := func() {}
 	if cfg.PrependCR {
+		terminalLock.Lock()
 		os.Stderr = crPipeErr
 		os.Stdout = crPipeOut
+		terminalLock.Unlock()
 		restoreStdio = func() {
+			terminalLock.Lock()
+			defer terminalLock.Unlock()
 			os.Stderr = origStderr
 			os.Stdout = origStdout
 		}
 	}
 
+	// commands are canceled when the input processing stops
+	runner := GetRunner()
+	runner.SetContext(inputCtx)
+
+	if cfg.HistoryFile != "" {
+		h, err := NewHistory(cfg.HistoryFile)
+		if err != nil {
+			log.Println("failed to load history, error:", err)
+		}
+		GetTerm().SetHistory(h)
+	}
+
+	stopRecording := func() {}
+	if cfg.RecordFile != "" {
+		stop, err := startRecording(cfg.RecordFile)
+		if err != nil {
+			log.Println("failed to start recording, error:", err)
+		} else {
+			stopRecording = stop
+		}
+	}
+
+	if cfg.ControlAddr != "" {
+		SetCommands(commands)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := ServeControl(inputCtx, cfg.ControlAddr); err != nil {
+				log.Println("failed to serve control endpoint, error:", err)
+			}
+		}()
+	}
+
 	// start reading input separately
 	// manage the terminal only if there are some commands to handle
 	if len(commands) > 0 {
@@ -127,11 +173,27 @@ func StartTerm(parent context.Context, cfg config.Config, cmds ...Command) (cont
 
 			stopReadingInput() // ensure inputCtx is also canceled
 			stopClock()        // ensure clockCtx is also canceled
-			restoreStdio()     // ensure os.Stderr and os.Stderr are restored
+
+			timeout := cfg.CommandTimeout
+			if timeout == 0 {
+				timeout = DefaultCommandTimeout
+			}
+			debug("wait for running commands")
+			if err := runner.Shutdown(timeout); err != nil {
+				log.Println(err)
+			}
+			restoreStdio() // ensure os.Stderr and os.Stderr are restored
 
 			debug("wait for cleanup")
 			wg.Wait()
 			debug("cleanup finished")
+			stopRecording()
+
+			debug("run shutdown hooks")
+			if err := shutdown.Run(context.Background()); err != nil {
+				log.Println(err)
+			}
+			stopSignals()
 		}()
 		select {
 		case s := <-sig:
@@ -21,7 +21,8 @@ type displayTime struct {
 // nolint
 // unicode art clock spinners
 const (
-	asciiClock     = "/:﹣:\\:|"
+	asciiClock     = "/:-:\\:|"
+	asciiSpinner   = "|:/:-:\\"
 	clockClock     = "🕛:🕐:🕑:🕒:🕓:🕔:🕕:🕖:🕗:🕘:🕙:🕚"
 	brailleClock   = "⢎⡰:⢎⡡:⢎⡑:⢎⠱:⠎⡱:⢊⡱:⢌⡱:⢆⡱"
 	brailleSpinner = " ⠁: ⠑: ⠰: ⡰:⢀⡠:⢄⡠:⢆⡀:⢎⡀:⢎ :⠎ :⠊ :⠈ "
@@ -0,0 +1,232 @@
+package cli
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CommandLineKey starts the command-line mode if no command is bound to it.
+const CommandLineKey = ':'
+
+// Control keys of the command-line mode.
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyBackspace = 8
+	keyTab       = 9
+	keyNL        = 10
+	keyCtrlK     = 11
+	keyCR        = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEsc       = 27
+	keyDelete    = 127
+)
+
+// editState is the state of the lineEditor after processing a key.
+type editState int
+
+const (
+	editing editState = iota
+	submitted
+	canceled
+)
+
+// lineEditor implements the line editing of the command-line mode.
+type lineEditor struct {
+	commands Commands
+	history  []string
+	histPos  int    // position in the history while browsing, len(history) is the new line
+	saved    string // the new line while browsing the history
+	buf      []rune
+	pos      int // cursor position in buf
+	hint     string
+}
+
+func newLineEditor(commands Commands, history []string, line string) *lineEditor {
+	e := &lineEditor{commands: commands, history: history, histPos: len(history)}
+	e.set(line)
+	return e
+}
+
+// Line returns the current line.
+func (e *lineEditor) Line() string { return string(e.buf) }
+
+// set replaces the line and moves the cursor to the end.
+func (e *lineEditor) set(line string) {
+	e.buf = []rune(line)
+	e.pos = len(e.buf)
+}
+
+// Input processes a key.
+func (e *lineEditor) Input(ev KeyEvent) editState {
+	e.hint = ""
+	switch ev.Code {
+	case KeyEscape:
+		return canceled
+	case KeyUp:
+		e.browse(-1)
+	case KeyDown:
+		e.browse(1)
+	case KeyRight:
+		e.move(1)
+	case KeyLeft:
+		e.move(-1)
+	case KeyHome:
+		e.pos = 0
+	case KeyEnd:
+		e.pos = len(e.buf)
+	case KeyDelete:
+		e.delete(e.pos, e.pos+1)
+	}
+	r := ev.Key()
+	if r == 0 {
+		return editing
+	}
+
+	switch r {
+	case keyCR, keyNL:
+		return submitted
+	case keyCtrlC, keyCtrlG:
+		return canceled
+	case keyCtrlD:
+		if len(e.buf) == 0 {
+			return canceled
+		}
+		e.delete(e.pos, e.pos+1)
+	case keyBackspace, keyDelete:
+		if e.pos == 0 && len(e.buf) == 0 {
+			return canceled
+		}
+		e.delete(e.pos-1, e.pos)
+	case keyCtrlA:
+		e.pos = 0
+	case keyCtrlE:
+		e.pos = len(e.buf)
+	case keyCtrlB:
+		e.move(-1)
+	case keyCtrlF:
+		e.move(1)
+	case keyCtrlK:
+		e.delete(e.pos, len(e.buf))
+	case keyCtrlU:
+		e.delete(0, e.pos)
+	case keyCtrlW:
+		start := e.pos
+		for start > 0 && e.buf[start-1] == ' ' {
+			start--
+		}
+		for start > 0 && e.buf[start-1] != ' ' {
+			start--
+		}
+		e.delete(start, e.pos)
+	case keyCtrlP:
+		e.browse(-1)
+	case keyCtrlN:
+		e.browse(1)
+	case keyTab:
+		e.complete()
+	default:
+		if unicode.IsPrint(r) {
+			e.buf = append(e.buf[:e.pos], append([]rune{r}, e.buf[e.pos:]...)...)
+			e.pos++
+		}
+	}
+	return editing
+}
+
+func (e *lineEditor) move(n int) {
+	e.pos = min(max(e.pos+n, 0), len(e.buf))
+}
+
+func (e *lineEditor) delete(from, to int) {
+	from, to = max(from, 0), min(to, len(e.buf))
+	if from >= to {
+		return
+	}
+	e.buf = append(e.buf[:from], e.buf[to:]...)
+	e.pos = from
+}
+
+// browse moves through the history.
+func (e *lineEditor) browse(n int) {
+	pos := e.histPos + n
+	if pos < 0 || pos > len(e.history) {
+		return
+	}
+	if e.histPos == len(e.history) {
+		e.saved = e.Line()
+	}
+	e.histPos = pos
+	if pos == len(e.history) {
+		e.set(e.saved)
+		return
+	}
+	e.set(e.history[pos])
+}
+
+// complete completes the line up to the cursor using the commands.
+func (e *lineEditor) complete() {
+	line := string(e.buf[:e.pos])
+	cands := e.commands.Complete(line)
+	switch len(cands) {
+	case 0:
+		e.hint = "no completions"
+		return
+	case 1:
+		e.set(cands[0] + " " + string(e.buf[e.pos:]))
+		e.pos = len([]rune(cands[0])) + 1
+		return
+	}
+
+	// complete the common prefix and list the candidates of the current word
+	prefix := cands[0]
+	for _, c := range cands[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(prefix) > len(line) {
+		rest := string(e.buf[e.pos:])
+		e.set(prefix + rest)
+		e.pos = len([]rune(prefix))
+		line = prefix
+	}
+	n := len(SplitArgs(line))
+	if strings.HasSuffix(line, " ") || line == "" {
+		n++
+	}
+	var words []string
+	for _, c := range cands {
+		if w := SplitArgs(c); len(w) >= n {
+			words = append(words, w[n-1])
+		}
+	}
+	e.hint = strings.Join(words, " ")
+}
+
+// String renders the line with the cursor as inverted character.
+func (e *lineEditor) String() string {
+	var sb strings.Builder
+	sb.WriteRune(CommandLineKey)
+	sb.WriteString(string(e.buf[:e.pos]))
+	cursor := " "
+	if e.pos < len(e.buf) {
+		cursor = string(e.buf[e.pos])
+	}
+	sb.WriteString("\x1b[7m" + cursor + "\x1b[0m")
+	if e.pos < len(e.buf) {
+		sb.WriteString(string(e.buf[e.pos+1:]))
+	}
+	if e.hint != "" {
+		sb.WriteString("  [" + e.hint + "]")
+	}
+	return sb.String()
+}
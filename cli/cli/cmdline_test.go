@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func typeKeys(e *lineEditor, keys string) editState {
+	state := editing
+	for _, ev := range decodeKeys(keys) {
+		state = e.Input(ev)
+	}
+	return state
+}
+
+func decodeKeys(keys string) []KeyEvent {
+	var (
+		d   keyDecoder
+		res []KeyEvent
+	)
+	for _, r := range keys {
+		res = append(res, d.feed(r)...)
+	}
+	return append(res, d.flush()...)
+}
+
+func TestLineEditor(t *testing.T) {
+	e := newLineEditor(nil, []string{"first", "second"}, "")
+	assert.Equal(t, editing, typeKeys(e, "helo"))
+	typeKeys(e, "\x1b[D\x1b[Dl") // left, left, insert
+	assert.Equal(t, "hello", e.Line())
+	assert.Equal(t, ":hel\x1b[7ml\x1b[0mo", e.String())
+
+	typeKeys(e, "\x01x\x05!") // home, insert, end, insert
+	assert.Equal(t, "xhello!", e.Line())
+	typeKeys(e, " world\x17\x17") // delete two words
+	assert.Equal(t, "", e.Line())
+
+	typeKeys(e, "new\x1b[A") // up shows the last history line
+	assert.Equal(t, "second", e.Line())
+	typeKeys(e, "\x10\x10") // up (Ctrl-P) stops at the first line
+	assert.Equal(t, "first", e.Line())
+	typeKeys(e, "\x0e\x0e") // down (Ctrl-N) restores the new line
+	assert.Equal(t, "new", e.Line())
+
+	assert.Equal(t, submitted, typeKeys(e, "\r"))
+	assert.Equal(t, canceled, typeKeys(e, "\x15\x7f")) // clear line and backspace on empty line
+	assert.Equal(t, canceled, typeKeys(newLineEditor(nil, nil, ""), "\x03"))
+	assert.Equal(t, canceled, typeKeys(newLineEditor(nil, nil, "x"), "\x1b"))
+}
+
+func TestLineEditorComplete(t *testing.T) {
+	cmds := Commands{
+		{Name: "set log level", Args: []Arg{{Name: "level", Values: []string{"debug", "info"}}}},
+		{Name: "set log format", Args: []Arg{{Name: "format"}}},
+		{Name: "flush table", Args: []Arg{{Name: "table"}}},
+	}
+	e := newLineEditor(cmds, nil, "")
+	typeKeys(e, "fl\t")
+	assert.Equal(t, "flush table ", e.Line())
+
+	e = newLineEditor(cmds, nil, "s")
+	typeKeys(e, "\t")
+	assert.Equal(t, "set log ", e.Line())
+	assert.Equal(t, "format level", e.hint)
+	typeKeys(e, "l\td\t")
+	assert.Equal(t, "set log level debug ", e.Line())
+
+	typeKeys(e, "\x15x\t")
+	assert.Equal(t, "no completions", e.hint)
+}
+
+func TestRunLine(t *testing.T) {
+	var got Args
+	cmds := Commands{
+		{Name: "set log level", Args: []Arg{{Name: "level", Values: []string{"debug", "info"}}}},
+		{Name: "set", Args: []Arg{
+			{Name: "key"},
+			{Name: "n", Type: IntArg},
+			{Name: "ratio", Type: FloatArg, Optional: true},
+		}},
+	}
+	for i := range cmds {
+		cmds[i].Exec = func(ctx context.Context, args Args) error { got = args; return nil }
+	}
+	ctx := context.Background()
+
+	assert.NoError(t, cmds.RunLine(ctx, "set log level debug"))
+	assert.Equal(t, Args{"level": "debug"}, got)
+	assert.NoError(t, cmds.RunLine(ctx, `set "a b" 3 0.5`))
+	assert.Equal(t, "a b", got.String("key"))
+	assert.Equal(t, 3, got.Int("n"))
+	assert.Equal(t, 0.5, got.Float("ratio"))
+
+	assert.ErrorContains(t, cmds.RunLine(ctx, "set log level trace"), `invalid level "trace"`)
+	assert.ErrorContains(t, cmds.RunLine(ctx, "set x y"), `invalid n "y", expected int`)
+	assert.ErrorContains(t, cmds.RunLine(ctx, "set x"), "missing argument: n")
+	assert.ErrorContains(t, cmds.RunLine(ctx, "set x 1 2 3"), "too many arguments: 3")
+	assert.ErrorContains(t, cmds.RunLine(ctx, "unknown"), "Command not found")
+	assert.NoError(t, cmds.RunLine(ctx, "  "))
+
+	unbound := Commands{{Name: "refresh", Event: KeyEvent{Code: KeyF5}}}
+	assert.ErrorIs(t, unbound.RunLine(ctx, "refresh"), ErrNoFunc)
+	assert.NotPanics(t, func() { unbound[0].Run(ctx) })
+}
@@ -2,37 +2,106 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 )
 
+// ErrNoFunc is returned for commands without Fn and Exec func.
+var ErrNoFunc = errors.New("command has no Fn or Exec func")
+
 // Command define a command.
 type Command struct {
-	Name string
-	Key  rune
-	Fn   func(context.Context)
+	Name  string
+	Key   rune
+	Event KeyEvent // binds the command to a decoded key, e.g., KeyEvent{Code: KeyF5}
+	Fn    func(context.Context)
+	Args  []Arg                                      // parameters of the command in command-line mode
+	Exec  func(ctx context.Context, args Args) error // runs the command with the parsed Args
+	// Policy defines how the command is run if it is already running.
+	Policy Policy
 }
 
 // CommandInfoFormatter formats a command.
 type CommandInfoFormatter func(Command) string
 
 func helpFormatter(c Command) string {
-	return fmt.Sprintf("Key: %q, Command: %s", c.Key, c.Name)
+	if c.Key == 0 && c.Event.Code != KeyNone {
+		return fmt.Sprintf("Key: %s, Command: %s", c.Event, c.Usage())
+	}
+	if c.Key == 0 {
+		return fmt.Sprintf("Command: %c%s", CommandLineKey, c.Usage())
+	}
+	return fmt.Sprintf("Key: %q, Command: %s", c.Key, c.Usage())
 }
 
 func inlineFormatter(c Command) string {
-	if c.StartsWithKey() {
+	switch {
+	case c.Key == 0 && c.Event.Code != KeyNone:
+		return fmt.Sprintf("%s:%s", c.Name, c.Event)
+	case c.Key == 0:
+		return fmt.Sprintf("%c%s", CommandLineKey, c.Name)
+	case c.StartsWithKey():
 		return fmt.Sprintf("(%s)%s", c.Name[0:1], c.Name[1:])
 	}
 	return fmt.Sprintf("%s:%q", c.Name, c.Key)
 }
 
+// Usage returns the command name followed by its arguments.
+func (c *Command) Usage() string {
+	res := []string{c.Name}
+	for _, a := range c.Args {
+		res = append(res, a.usage())
+	}
+	return strings.Join(res, " ")
+}
+
 // Run runs the command and sets the Prompt to indicate that the command was run.
 func (c *Command) Run(ctx context.Context) {
+	if c.Fn == nil {
+		_ = c.RunArgs(ctx, Args{})
+		return
+	}
 	c.Fn(ctx)
+	if c.Key == 0 && c.Event.Code != KeyNone {
+		Prompt("Last command: %s (%s)", c.Name, c.Event)
+		return
+	}
 	Prompt("Last command: %s (%q)", c.Name, c.Key)
 }
 
+// RunArgs runs the command with the given Args and sets the Prompt to indicate that the command
+// was run or failed. Commands without Exec func ignore the Args.
+func (c *Command) RunArgs(ctx context.Context, args Args) error {
+	if c.Exec == nil && c.Fn == nil {
+		err := fmt.Errorf("%w: %s", ErrNoFunc, c.Name)
+		Prompt("Command %s failed: %v", c.Name, err)
+		return err
+	}
+	if c.Exec == nil {
+		c.Fn(ctx)
+		Prompt("Last command: %s", c.Name)
+		return nil
+	}
+	if err := c.Exec(ctx, args); err != nil {
+		Prompt("Command %s failed: %v", c.Name, err)
+		return err
+	}
+	Prompt("Last command: %s", c.Name)
+	return nil
+}
+
 // StartsWithKey tells if a command name starts with the command key.
 func (c *Command) StartsWithKey() bool {
 	return len(c.Name) > 0 && c.Name[0] == byte(c.Key)
 }
+
+// needsArgs tells if the command requires arguments that cannot be given by a key press.
+func (c *Command) needsArgs() bool {
+	for _, a := range c.Args {
+		if !a.Optional {
+			return true
+		}
+	}
+	return false
+}
@@ -14,13 +14,101 @@ type Commands []Command
 // Get returns a command or nil.
 func (c Commands) Get(r rune) *Command {
 	for _, cmd := range c {
-		if r == cmd.Key {
+		if r != 0 && r == cmd.Key {
 			return &cmd
 		}
 	}
 	return nil
 }
 
+// Lookup returns the command bound to the event or to the event's Key, or nil.
+func (c Commands) Lookup(ev KeyEvent) *Command {
+	for _, cmd := range c {
+		if cmd.Event.Matches(ev) {
+			return &cmd
+		}
+	}
+	return c.Get(ev.Key())
+}
+
+// Find returns the command with the longest name matching the first words
+// and the remaining words as arguments, or nil if no command matches.
+func (c Commands) Find(words []string) (*Command, []string) {
+	var (
+		res  *Command
+		size int
+	)
+	for i := range c {
+		name := strings.Fields(c[i].Name)
+		if len(name) > size && len(name) <= len(words) && strings.Join(name, " ") == strings.Join(words[:len(name)], " ") {
+			res, size = &c[i], len(name)
+		}
+	}
+	if res == nil {
+		return nil, words
+	}
+	cmd := *res
+	return &cmd, words[size:]
+}
+
+// RunLine parses the command line and runs the matching command with the parsed arguments.
+func (c Commands) RunLine(ctx context.Context, line string) error {
+	words := SplitArgs(line)
+	if len(words) == 0 {
+		return nil
+	}
+	cmd, rest := c.Find(words)
+	if cmd == nil {
+		return fmt.Errorf("Command not found: %s", words[0])
+	}
+	args, err := ParseArgs(cmd.Args, rest)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cmd.Name, err)
+	}
+	var runErr error
+	if err := GetRunner().Do(ctx, cmd, func(ctx context.Context) { runErr = cmd.RunArgs(ctx, args) }); err != nil {
+		return err
+	}
+	return runErr
+}
+
+// Complete returns the completed command lines for the given line,
+// including command names and allowed argument values.
+func (c Commands) Complete(line string) []string {
+	words := SplitArgs(line)
+	partial := ""
+	if len(words) > 0 && !strings.HasSuffix(line, " ") {
+		partial, words = words[len(words)-1], words[:len(words)-1]
+	}
+	prefix := strings.Join(append(append([]string{}, words...), partial), " ")
+	if partial == "" && len(words) > 0 {
+		prefix += " "
+	}
+
+	seen := make(map[string]bool)
+	var res []string
+	add := func(s string) {
+		if !seen[s] {
+			seen[s] = true
+			res = append(res, s)
+		}
+	}
+	for _, cmd := range c {
+		if name := strings.Join(strings.Fields(cmd.Name), " "); strings.HasPrefix(name, prefix) {
+			add(name)
+		}
+	}
+	if cmd, rest := c.Find(words); cmd != nil && len(rest) < len(cmd.Args) {
+		for _, v := range cmd.Args[len(rest)].Values {
+			if strings.HasPrefix(v, partial) {
+				add(strings.Join(append(append([]string{}, words...), v), " "))
+			}
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
 // String returns the command names and keys on a single line.
 func (c Commands) String() string {
 	return "Commands: " + strings.Join(c.Info(inlineFormatter), " ")
@@ -48,11 +136,11 @@ func (c Commands) Info(fn CommandInfoFormatter) []string {
 	return res
 }
 
-// Run runs a command or returns a NotFound error.
+// Run runs a command with the context of the Runner or returns a NotFound error.
 func (c Commands) Run(r rune) error {
 	if cmd := c.Get(r); cmd != nil {
-		cmd.Run(context.Background()) // TODO: cancel with parent
-		return nil
+		runner := GetRunner()
+		return runner.Do(runner.Context(), cmd, cmd.Run)
 	}
 	return fmt.Errorf("Command not found for Key=%q", r)
 }
@@ -94,9 +182,9 @@ func QuitCommands(done func()) Commands {
 		done()
 	}
 	return Commands{
-		Command{"quit", 'q', fn},
-		Command{"quit", 'Q', fn},
-		Command{"quit", 3, fn}, // CTRL-C
-		Command{"quit", 4, fn}, // CTRL-D
+		Command{Name: "quit", Key: 'q', Fn: fn},
+		Command{Name: "quit", Key: 'Q', Fn: fn},
+		Command{Name: "quit", Key: 3, Fn: fn}, // CTRL-C
+		Command{Name: "quit", Key: 4, Fn: fn}, // CTRL-D
 	}
 }
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	xterm "golang.org/x/term"
+)
+
+// Env vars to override the detection of Auto. They accept the values of Mode.
+const (
+	EnvTTY     = "CLI_TTY"     // interactive mode
+	EnvColor   = "CLI_COLOR"   // colored output, overrides NO_COLOR and TERM=dumb
+	EnvUnicode = "CLI_UNICODE" // unicode clock and spinners
+	EnvWidth   = "CLI_WIDTH"   // fixed terminal width, disables the resize tracking
+)
+
+// Mode is a detected setting that can be forced on or off.
+type Mode string
+
+// Supported modes. The empty Mode is ModeAuto.
+const (
+	ModeAuto Mode = "auto"
+	ModeOn   Mode = "on"
+	ModeOff  Mode = "off"
+)
+
+// String implements flag.Value.
+func (m Mode) String() string {
+	if m == "" {
+		return string(ModeAuto)
+	}
+	return string(m)
+}
+
+// Set implements flag.Value. It accepts auto, on, off, and boolean values.
+func (m *Mode) Set(s string) error {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case "", "auto":
+		*m = ModeAuto
+	case "on", "yes":
+		*m = ModeOn
+	case "off", "no":
+		*m = ModeOff
+	default:
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid mode %q, use auto, on, or off", s)
+		}
+		*m = ModeOff
+		if v {
+			*m = ModeOn
+		}
+	}
+	return nil
+}
+
+// resolve returns the forced value of the mode or the detected value.
+func (m Mode) resolve(detected bool) bool {
+	switch m {
+	case ModeOn:
+		return true
+	case ModeOff:
+		return false
+	}
+	return detected
+}
+
+// Overrides override the detection of Auto. They take precedence over the env vars.
+type Overrides struct {
+	TTY     Mode
+	Color   Mode
+	Unicode Mode
+	Width   int
+}
+
+// RegisterFlags registers the -tty, -color, -unicode, and -width flags.
+func (o *Overrides) RegisterFlags(fs *flag.FlagSet) {
+	fs.Var(&o.TTY, "tty", "interactive terminal mode: auto, on, or off")
+	fs.Var(&o.Color, "color", "colored output: auto, on, or off")
+	fs.Var(&o.Unicode, "unicode", "unicode clock and spinners: auto, on, or off")
+	fs.IntVar(&o.Width, "width", 0, "fixed terminal width, 0 detects the width")
+}
+
+// Auto returns the Interactive config if stdin, stdout, and stderr are terminals
+// and the Server config otherwise. See AutoWith.
+func Auto() Config {
+	return AutoWith(Overrides{})
+}
+
+// AutoWith returns the Interactive or the Server config depending on the detected terminal.
+// It disables colors if NO_COLOR is set, TERM is dumb, or stderr is not a terminal,
+// and uses the ASCII clock if the locale is not UTF-8.
+// The env vars override the detection and the given overrides take precedence over the env vars.
+func AutoWith(o Overrides) Config {
+	dumb := os.Getenv("TERM") == "dumb"
+	tty := isTerminal(os.Stdin) && isTerminal(os.Stdout) && isTerminal(os.Stderr)
+	noColor := os.Getenv("NO_COLOR") != ""
+
+	tty = o.TTY.resolve(envMode(EnvTTY).resolve(tty))
+	color := o.Color.resolve(envMode(EnvColor).resolve(isTerminal(os.Stderr) && !noColor && !dumb))
+	unicode := o.Unicode.resolve(envMode(EnvUnicode).resolve(isUTF8Locale()))
+
+	cfg := Default(tty)
+	cfg.ShowClock = cfg.ShowClock && !dumb
+	cfg.NoColor = !color
+	cfg.ASCII = !unicode
+	cfg.Width = o.Width
+	if cfg.Width == 0 {
+		cfg.Width, _ = strconv.Atoi(os.Getenv(EnvWidth))
+	}
+	return cfg
+}
+
+// envMode returns the Mode of the env var, invalid values are ModeAuto.
+func envMode(name string) Mode {
+	var m Mode
+	if err := m.Set(os.Getenv(name)); err != nil {
+		return ModeAuto
+	}
+	return m
+}
+
+func isTerminal(f *os.File) bool {
+	return xterm.IsTerminal(int(f.Fd()))
+}
+
+// isUTF8Locale tells if the first set locale variable uses UTF-8.
+func isUTF8Locale() bool {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := strings.ToLower(os.Getenv(name)); v != "" {
+			return strings.Contains(v, "utf-8") || strings.Contains(v, "utf8")
+		}
+	}
+	return false
+}
@@ -1,10 +1,25 @@
 package config
 
+import "time"
+
 type Config struct {
 	ShowClock   bool // ShowClock enables the default ascii/unicode clock in the last terminal line.
 	WithQuit    bool // WithQuit adds the default quit commands and enables user input.
 	PrependCR   bool
 	MakeTermRaw bool
+	HistoryFile string // HistoryFile persists the lines of the command-line mode.
+	// CommandTimeout is the max wait time for running commands on shutdown.
+	// The default is cli.DefaultCommandTimeout.
+	CommandTimeout time.Duration
+	// ControlAddr enables the remote control endpoint on a Unix socket ("unix:/path/app.sock")
+	// or a loopback address ("localhost:8123") to run the commands of headless services.
+	ControlAddr string
+	// RecordFile records the terminal session as asciicast v2 file, see cli.Recorder.
+	RecordFile string
+	NoColor    bool // NoColor removes color codes from the output written to the terminal.
+	ASCII      bool // ASCII uses the ASCII clock and spinners instead of unicode.
+	// Width sets a fixed terminal width. The default of 0 detects the width and tracks resizes.
+	Width int
 }
 
 func Default(interactive bool) Config {
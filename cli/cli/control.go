@@ -0,0 +1,299 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNonLocalAddr is returned for control addresses that are reachable from other hosts.
+var ErrNonLocalAddr = errors.New("control address must be a Unix socket or a loopback address")
+
+// ControlHeader must be set on all requests of the control endpoint. Browsers cannot send it
+// cross-origin without a CORS preflight, which the endpoint does not answer.
+const ControlHeader = "X-Cli-Control"
+
+// CommandInfo describes a command of the control endpoint.
+type CommandInfo struct {
+	Name   string `json:"name"`
+	Key    string `json:"key,omitempty"`
+	Usage  string `json:"usage"`
+	Policy Policy `json:"policy"`
+}
+
+// RunResult is the result of a command triggered via the control endpoint.
+type RunResult struct {
+	Command string `json:"command"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ExecutionInfo describes a running command of the control endpoint.
+type ExecutionInfo struct {
+	ID      int       `json:"id"`
+	Name    string    `json:"name"`
+	Started time.Time `json:"started"`
+}
+
+// ControlListen listens on the control address, which is either a Unix socket path prefixed with
+// "unix:" or a loopback TCP address, e.g., "unix:/tmp/app.sock" or "localhost:8123".
+func ControlListen(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		_ = os.Remove(path) // remove stale sockets of previous runs
+		l, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, err
+		}
+		return l, os.Chmod(path, 0o600)
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if !isLoopbackHost(host) {
+		return nil, fmt.Errorf("%w: %s", ErrNonLocalAddr, addr)
+	}
+	return net.Listen("tcp", addr)
+}
+
+func isLoopbackHost(host string) bool {
+	ip := net.ParseIP(host)
+	return host == "localhost" || (ip != nil && ip.IsLoopback())
+}
+
+// ServeControl serves the control endpoint on the address until the context is done.
+//
+// All requests must set the ControlHeader, must not set an Origin, and must use a loopback Host.
+// This rejects requests of web pages, including DNS rebinding attacks.
+//
+// Endpoints:
+//
+//	GET  /commands           lists the global commands
+//	POST /run?key=k          runs the command bound to the key k
+//	POST /run?line=...       runs a command line, e.g., "flush table x"
+//	GET  /jobs               lists the running commands
+//	GET  /logs               streams the log output
+func ServeControl(ctx context.Context, addr string) error {
+	l, err := ControlListen(addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: ControlHandler(ctx), BaseContext: func(net.Listener) context.Context { return ctx }}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// ControlHandler returns the http.Handler of the control endpoint.
+func ControlHandler(ctx context.Context) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /commands", func(w http.ResponseWriter, r *http.Request) {
+		var res []CommandInfo
+		for _, cmd := range GetCommands() {
+			info := CommandInfo{Name: cmd.Name, Usage: cmd.Usage(), Policy: cmd.Policy}
+			if cmd.Key != 0 {
+				info.Key = string(cmd.Key)
+			}
+			res = append(res, info)
+		}
+		writeJSON(w, http.StatusOK, res)
+	})
+	mux.HandleFunc("POST /run", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query() // form-encoded bodies are not accepted
+		res, status := runControl(q.Get("key"), q.Get("line"))
+		writeJSON(w, status, res)
+	})
+	mux.HandleFunc("GET /jobs", func(w http.ResponseWriter, r *http.Request) {
+		res := []ExecutionInfo{}
+		for _, e := range GetRunner().Running() {
+			res = append(res, ExecutionInfo{ID: e.ID, Name: e.Name, Started: e.Started})
+		}
+		writeJSON(w, http.StatusOK, res)
+	})
+	mux.HandleFunc("GET /logs", func(w http.ResponseWriter, r *http.Request) {
+		ch, stop := GetTerm().SubscribeOutput(100)
+		defer stop()
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		flusher, _ := w.(http.Flusher)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		for {
+			select {
+			case b := <-ch:
+				if _, err := w.Write(b); err != nil {
+					return
+				}
+				if flusher != nil {
+					flusher.Flush()
+				}
+			case <-r.Context().Done():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+	return guardControl(mux)
+}
+
+// guardControl rejects requests that may come from a web page.
+func guardControl(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		switch {
+		case r.Header.Get("Origin") != "":
+			writeJSON(w, http.StatusForbidden, RunResult{Error: "cross-origin requests are not allowed"})
+		case !isLoopbackHost(host):
+			writeJSON(w, http.StatusForbidden, RunResult{Error: "non-loopback host: " + r.Host})
+		case r.Header.Get(ControlHeader) == "":
+			writeJSON(w, http.StatusForbidden, RunResult{Error: "missing header " + ControlHeader})
+		default:
+			h.ServeHTTP(w, r)
+		}
+	})
+}
+
+// runControl runs a command by key or command line with the context of the Runner.
+func runControl(key, line string) (RunResult, int) {
+	runner := GetRunner()
+	if line != "" {
+		words := SplitArgs(line)
+		res := RunResult{Command: line}
+		if cmd, _ := GetCommands().Find(words); cmd != nil {
+			res.Command = cmd.Name
+		}
+		if err := GetCommands().RunLine(runner.Context(), line); err != nil {
+			res.Error = err.Error()
+			return res, http.StatusBadRequest
+		}
+		return res, http.StatusOK
+	}
+
+	runes := []rune(key)
+	if len(runes) != 1 {
+		return RunResult{Error: "expected a single key or a command line"}, http.StatusBadRequest
+	}
+	cmd := GetCommands().Get(runes[0])
+	if cmd == nil {
+		return RunResult{Error: fmt.Sprintf("Command not found for Key=%q", runes[0])}, http.StatusNotFound
+	}
+	res := RunResult{Command: cmd.Name}
+	if cmd.needsArgs() {
+		res.Error = "command requires arguments: " + cmd.Usage()
+		return res, http.StatusBadRequest
+	}
+	if err := runner.Do(runner.Context(), cmd, cmd.Run); err != nil {
+		res.Error = err.Error()
+		return res, http.StatusConflict
+	}
+	return res, http.StatusOK
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// ControlClient is a client of the control endpoint.
+type ControlClient struct {
+	client *http.Client
+}
+
+// NewControlClient returns a client for the control address.
+func NewControlClient(addr string) *ControlClient {
+	network := "tcp"
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		network, addr = "unix", path
+	}
+	var d net.Dialer
+	return &ControlClient{client: &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, network, addr)
+		},
+	}}}
+}
+
+func (c *ControlClient) request(ctx context.Context, method, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, "http://localhost"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(ControlHeader, "1")
+	return c.client.Do(req)
+}
+
+func (c *ControlClient) do(ctx context.Context, method, path string, v any) error {
+	resp, err := c.request(ctx, method, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Commands lists the commands of the remote process.
+func (c *ControlClient) Commands(ctx context.Context) ([]CommandInfo, error) {
+	var res []CommandInfo
+	return res, c.do(ctx, http.MethodGet, "/commands", &res)
+}
+
+// Jobs lists the running commands of the remote process.
+func (c *ControlClient) Jobs(ctx context.Context) ([]ExecutionInfo, error) {
+	var res []ExecutionInfo
+	return res, c.do(ctx, http.MethodGet, "/jobs", &res)
+}
+
+// RunKey runs the command bound to the key.
+func (c *ControlClient) RunKey(ctx context.Context, key rune) (RunResult, error) {
+	var res RunResult
+	return res, c.do(ctx, http.MethodPost, "/run?key="+url.QueryEscape(string(key)), &res)
+}
+
+// RunLine runs a command line.
+func (c *ControlClient) RunLine(ctx context.Context, line string) (RunResult, error) {
+	var res RunResult
+	return res, c.do(ctx, http.MethodPost, "/run?line="+url.QueryEscape(line), &res)
+}
+
+// Logs copies the log output of the remote process to w until the context is done.
+func (c *ControlClient) Logs(ctx context.Context, w io.Writer) error {
+	resp, err := c.request(ctx, http.MethodGet, "/logs")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	r := bufio.NewReader(resp.Body)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			if _, werr := w.Write(line); werr != nil {
+				return werr
+			}
+		}
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
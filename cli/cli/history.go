@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DefaultHistorySize is the max number of lines kept in a History.
+const DefaultHistorySize = 1000
+
+// History stores the lines of the command-line mode. If it has a path,
+// it loads the previous lines from the file and appends new lines to it.
+type History struct {
+	mu    sync.RWMutex
+	path  string
+	lines []string
+}
+
+// NewHistory returns a History that is persisted in the file at path.
+// An empty path creates an in-memory History.
+func NewHistory(path string) (*History, error) {
+	h := &History{path: path}
+	if path == "" {
+		return h, nil
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		h.add(scanner.Text())
+	}
+	return h, scanner.Err()
+}
+
+// Lines returns a copy of the lines, oldest first.
+func (h *History) Lines() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]string{}, h.lines...)
+}
+
+// Add adds a line and appends it to the history file.
+// Empty lines and repetitions of the last line are ignored.
+func (h *History) Add(line string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.add(line) || h.path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(line + "\n")
+	return err
+}
+
+func (h *History) add(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" || (len(h.lines) > 0 && h.lines[len(h.lines)-1] == line) {
+		return false
+	}
+	h.lines = append(h.lines, line)
+	if len(h.lines) > DefaultHistorySize {
+		h.lines = h.lines[len(h.lines)-DefaultHistorySize:]
+	}
+	return true
+}
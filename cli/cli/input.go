@@ -15,6 +15,9 @@ import (
 
 var terminalLock sync.RWMutex
 
+// claimedState is the state of the terminal before it was set to raw mode.
+var claimedState *xterm.State
+
 var (
 	origStdout   = os.Stdout
 	origStderr   = os.Stderr
@@ -27,7 +30,11 @@ func termMakeRaw() (*xterm.State, error) {
 	defer terminalLock.Unlock()
 	os.Stdout = crPipeOut
 	os.Stderr = crPipeErr
-	return xterm.MakeRaw(0)
+	state, err := xterm.MakeRaw(0)
+	if err == nil {
+		claimedState = state
+	}
+	return state, err
 }
 
 func termRestore(state *xterm.State) error {
@@ -35,9 +42,26 @@ func termRestore(state *xterm.State) error {
 	defer terminalLock.Unlock()
 	os.Stdout = origStdout
 	os.Stderr = origStderr
+	claimedState = nil
 	return xterm.Restore(0, state)
 }
 
+// RestoreTerminal restores the terminal if it was claimed and not yet restored.
+// It allows restoring the terminal without the RestoreFunc, e.g., on panics or forced exits.
+func RestoreTerminal() error {
+	terminalLock.RLock()
+	state := claimedState
+	terminalLock.RUnlock()
+	if state == nil {
+		return nil
+	}
+	if err := termRestore(state); err != nil {
+		return err
+	}
+	GetTerm().setRaw(false)
+	return nil
+}
+
 // RestoreFunc restores the terminal.
 type RestoreFunc func() error
 
@@ -110,7 +134,7 @@ func InputChan(file *os.File) <-chan rune {
 	return ch
 }
 
-// ProcessInput reads runes from input chan and executes the `commands` mapped to the received input keys.
+// ProcessInput reads the key events from the file and executes the `commands` bound to the events.
 func ProcessInput(ctx context.Context, file *os.File, commands Commands, termMakeRaw bool) {
 	debug("start processing input")
 	defer debug("input processing stopped")
@@ -126,12 +150,29 @@ func ProcessInput(ctx context.Context, file *os.File, commands Commands, termMak
 		}
 	}
 
-	input := InputChan(file)
+	runes := InputChan(file)
+	if rec := GetTerm().Recorder(); rec != nil {
+		runes = rec.RecordInput(runes)
+	}
+	processEvents(ctx, KeyEvents(runes, DefaultEscTimeout), commands)
+	<-ctx.Done()
+}
 
+// processEvents executes the `commands` bound to the key events
+// until the context is done or the input is closed.
+func processEvents(ctx context.Context, input <-chan KeyEvent, commands Commands) {
 	Prompt(commands.String())
 
+	t := GetTerm()
+	history := t.GetHistory()
+	var editor *lineEditor
+	startEditor := func(line string) {
+		editor = newLineEditor(commands, history.Lines(), line)
+		t.SetCommandLine(editor.String())
+	}
+
 	var prompt string
-	var char rune
+	var ev KeyEvent
 	var more bool
 	for {
 		select {
@@ -143,18 +184,47 @@ func ProcessInput(ctx context.Context, file *os.File, commands Commands, termMak
 				Prompt(prompt)
 				prompt = ""
 			}
-		case char, more = <-input:
+		case ev, more = <-input:
 			if !more {
-				<-ctx.Done()
-				debug("Quit (input closed + context done).")
+				debug("Quit (input closed).")
 				return
 			}
-			if cmd := commands.Get(char); cmd != nil {
+			if editor != nil {
+				switch editor.Input(ev) {
+				case editing:
+					t.SetCommandLine(editor.String())
+					continue
+				case submitted:
+					line := editor.Line()
+					if err := history.Add(line); err != nil {
+						debug("failed to store history, error=%v", err)
+					}
+					t.Runner().spawn(func() {
+						if err := commands.RunLine(ctx, line); err != nil {
+							Prompt("Error: %v", err)
+						}
+					})
+				}
+				editor = nil
+				t.SetCommandLine("")
+				continue
+			}
+			if cmd := commands.Lookup(ev); cmd != nil {
+				prompt = ""
+				if cmd.needsArgs() {
+					// commands with required args are completed in command-line mode
+					startEditor(cmd.Name + " ")
+					continue
+				}
+				t.Runner().Go(ctx, cmd, cmd.Run)
+				continue
+			}
+			if ev.Key() == CommandLineKey {
 				prompt = ""
-				go cmd.Run(ctx)
+				startEditor("")
 				continue
 			}
-			Prompt("Pressed key %q.", char)
+			Prompt("Pressed key %s.", ev)
 			prompt = commands.String()
 		}
 	}
@@ -0,0 +1,293 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultEscTimeout is the wait time after an ESC to tell the Escape key from an escape sequence.
+var DefaultEscTimeout = 50 * time.Millisecond
+
+// KeyCode identifies a key.
+type KeyCode int
+
+// Supported keys. KeyRune is used for all printable and control characters.
+const (
+	KeyNone KeyCode = iota
+	KeyRune
+	KeyEnter
+	KeyTab
+	KeyBackspace
+	KeyEscape
+	KeyUp
+	KeyDown
+	KeyRight
+	KeyLeft
+	KeyHome
+	KeyEnd
+	KeyInsert
+	KeyDelete
+	KeyPageUp
+	KeyPageDown
+	KeyF1
+	KeyF2
+	KeyF3
+	KeyF4
+	KeyF5
+	KeyF6
+	KeyF7
+	KeyF8
+	KeyF9
+	KeyF10
+	KeyF11
+	KeyF12
+)
+
+var keyNames = map[KeyCode]string{
+	KeyEnter: "Enter", KeyTab: "Tab", KeyBackspace: "Backspace", KeyEscape: "Esc",
+	KeyUp: "Up", KeyDown: "Down", KeyRight: "Right", KeyLeft: "Left",
+	KeyHome: "Home", KeyEnd: "End", KeyInsert: "Insert", KeyDelete: "Delete",
+	KeyPageUp: "PgUp", KeyPageDown: "PgDn",
+}
+
+func (k KeyCode) String() string {
+	if k >= KeyF1 && k <= KeyF12 {
+		return fmt.Sprintf("F%d", k-KeyF1+1)
+	}
+	return keyNames[k]
+}
+
+// Modifier is a bit mask of modifier keys.
+type Modifier int
+
+// Supported modifiers, using the bits of the xterm modifier parameter.
+const (
+	ModShift Modifier = 1
+	ModAlt   Modifier = 2
+	ModCtrl  Modifier = 4
+)
+
+// KeyEvent is a decoded key press. Control characters are decoded as lowercase
+// Rune with ModCtrl, e.g., Ctrl+C is KeyEvent{Code: KeyRune, Rune: 'c', Mod: ModCtrl}.
+type KeyEvent struct {
+	Code KeyCode
+	Rune rune // the character of KeyRune events
+	Mod  Modifier
+	raw  rune // the input rune of single-rune events
+}
+
+// Key returns the input rune of single-rune events without ModAlt, used for binding
+// events to Command.Key, or 0 for all other events.
+func (ev KeyEvent) Key() rune {
+	if ev.Mod&ModAlt != 0 {
+		return 0
+	}
+	return ev.raw
+}
+
+// Matches tells if the events describe the same key press.
+func (ev KeyEvent) Matches(other KeyEvent) bool {
+	return ev.Code != KeyNone && ev.Code == other.Code && ev.Rune == other.Rune && ev.Mod == other.Mod
+}
+
+func (ev KeyEvent) String() string {
+	var parts []string
+	for _, m := range []struct {
+		mod  Modifier
+		name string
+	}{{ModCtrl, "Ctrl"}, {ModAlt, "Alt"}, {ModShift, "Shift"}} {
+		if ev.Mod&m.mod != 0 {
+			parts = append(parts, m.name)
+		}
+	}
+	switch {
+	case ev.Code == KeyRune && ev.Rune == ' ':
+		parts = append(parts, "Space")
+	case ev.Code == KeyRune && ev.Mod&ModCtrl != 0:
+		parts = append(parts, strings.ToUpper(string(ev.Rune)))
+	case ev.Code == KeyRune:
+		parts = append(parts, string(ev.Rune))
+	default:
+		parts = append(parts, ev.Code.String())
+	}
+	return strings.Join(parts, "+")
+}
+
+// runeEvent returns the event of a single rune.
+func runeEvent(r rune) KeyEvent {
+	ev := KeyEvent{Code: KeyRune, Rune: r, raw: r}
+	switch {
+	case r == keyCR || r == keyNL:
+		ev.Code, ev.Rune = KeyEnter, 0
+	case r == keyTab:
+		ev.Code, ev.Rune = KeyTab, 0
+	case r == keyBackspace || r == keyDelete:
+		ev.Code, ev.Rune = KeyBackspace, 0
+	case r == keyEsc:
+		ev.Code, ev.Rune = KeyEscape, 0
+	case r == 0:
+		ev.Rune, ev.Mod = ' ', ModCtrl
+	case r < 27:
+		ev.Rune, ev.Mod = 'a'+r-1, ModCtrl
+	}
+	return ev
+}
+
+// Final bytes of CSI and SS3 sequences without parameters.
+var finalKeys = map[rune]KeyCode{
+	'A': KeyUp, 'B': KeyDown, 'C': KeyRight, 'D': KeyLeft, 'H': KeyHome, 'F': KeyEnd,
+	'P': KeyF1, 'Q': KeyF2, 'R': KeyF3, 'S': KeyF4,
+}
+
+// Final bytes of the Linux console sequences ESC [ [ A to ESC [ [ E.
+var consoleKeys = map[rune]KeyCode{'A': KeyF1, 'B': KeyF2, 'C': KeyF3, 'D': KeyF4, 'E': KeyF5}
+
+// Parameters of CSI sequences ending with '~'.
+var tildeKeys = map[int]KeyCode{
+	1: KeyHome, 2: KeyInsert, 3: KeyDelete, 4: KeyEnd, 5: KeyPageUp, 6: KeyPageDown, 7: KeyHome, 8: KeyEnd,
+	11: KeyF1, 12: KeyF2, 13: KeyF3, 14: KeyF4, 15: KeyF5, 17: KeyF6, 18: KeyF7, 19: KeyF8,
+	20: KeyF9, 21: KeyF10, 23: KeyF11, 24: KeyF12,
+}
+
+// maxSeqLen limits the length of escape sequences. Longer sequences are dropped.
+const maxSeqLen = 16
+
+// keyDecoder decodes runes to KeyEvents.
+type keyDecoder struct {
+	seq []rune // incomplete escape sequence
+}
+
+// pending tells if the decoder waits for the rest of an escape sequence.
+func (d *keyDecoder) pending() bool { return len(d.seq) > 0 }
+
+// feed decodes the next rune and returns the completed events.
+func (d *keyDecoder) feed(r rune) []KeyEvent {
+	switch {
+	case len(d.seq) == 0:
+		if r == keyEsc {
+			d.seq = []rune{r}
+			return nil
+		}
+		return []KeyEvent{runeEvent(r)}
+	case len(d.seq) == 1:
+		switch r {
+		case '[', 'O':
+			d.seq = append(d.seq, r)
+			return nil
+		case keyEsc:
+			// the first ESC was the Escape key
+			return []KeyEvent{runeEvent(keyEsc)}
+		}
+		d.seq = nil
+		ev := runeEvent(r)
+		ev.Mod |= ModAlt
+		return []KeyEvent{ev}
+	}
+
+	if r < 0x20 || r > 0x7e {
+		// not part of an escape sequence: drop the sequence and start over
+		d.seq = nil
+		return d.feed(r)
+	}
+	d.seq = append(d.seq, r)
+	if len(d.seq) == 3 && d.seq[1] == '[' && r == '[' {
+		// Linux console F1-F5 prefix, the next byte is final
+		return nil
+	}
+	if d.seq[1] == 'O' || r >= 0x40 {
+		// SS3 sequences have one final byte, CSI sequences end with a byte in 0x40-0x7e
+		seq := d.seq
+		d.seq = nil
+		if ev, ok := decodeSeq(seq); ok {
+			return []KeyEvent{ev}
+		}
+		return nil
+	}
+	if len(d.seq) >= maxSeqLen {
+		d.seq = nil
+	}
+	return nil
+}
+
+// flush completes an incomplete sequence after the ESC timeout. A single ESC is the Escape key,
+// ESC followed by '[' or 'O' is the Alt combo, and longer incomplete sequences are dropped.
+func (d *keyDecoder) flush() []KeyEvent {
+	seq := d.seq
+	d.seq = nil
+	switch len(seq) {
+	case 1:
+		return []KeyEvent{runeEvent(keyEsc)}
+	case 2:
+		ev := runeEvent(seq[1])
+		ev.Mod |= ModAlt
+		return []KeyEvent{ev}
+	}
+	return nil
+}
+
+// decodeSeq decodes a complete CSI or SS3 sequence.
+func decodeSeq(seq []rune) (KeyEvent, bool) {
+	final := seq[len(seq)-1]
+	params := strings.Split(string(seq[2:len(seq)-1]), ";")
+	param := func(i int) int {
+		if i >= len(params) {
+			return 0
+		}
+		n, _ := strconv.Atoi(params[i])
+		return n
+	}
+	var ev KeyEvent
+	switch {
+	case len(seq) == 4 && seq[2] == '[':
+		return KeyEvent{Code: consoleKeys[final]}, consoleKeys[final] != KeyNone
+	case final == '~':
+		ev.Code = tildeKeys[param(0)]
+	case final == 'Z' && seq[1] == '[':
+		ev.Code, ev.Mod = KeyTab, ModShift
+	default:
+		ev.Code = finalKeys[final]
+	}
+	if m := param(1); m > 1 {
+		ev.Mod |= Modifier(m-1) & (ModShift | ModAlt | ModCtrl)
+	}
+	return ev, ev.Code != KeyNone
+}
+
+// KeyEvents decodes the runes of the input channel to KeyEvents. An ESC that is not followed
+// by another rune within the timeout is sent as KeyEscape. The returned channel is closed
+// after the input channel is closed.
+func KeyEvents(input <-chan rune, timeout time.Duration) <-chan KeyEvent {
+	ch := make(chan KeyEvent, 10)
+	go func() {
+		defer close(ch)
+		var (
+			d     keyDecoder
+			timer <-chan time.Time
+		)
+		send := func(events []KeyEvent) {
+			for _, ev := range events {
+				ch <- ev
+			}
+		}
+		for {
+			select {
+			case r, more := <-input:
+				if !more {
+					send(d.flush())
+					return
+				}
+				send(d.feed(r))
+				timer = nil
+				if d.pending() {
+					timer = time.After(timeout)
+				}
+			case <-timer:
+				timer = nil
+				send(d.flush())
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeKeys(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"ab", []string{"a", "b"}},
+		{"\r\t\x7f\x03 ", []string{"Enter", "Tab", "Backspace", "Ctrl+C", "Space"}},
+		{"\x1b[A\x1b[B\x1b[C\x1b[D", []string{"Up", "Down", "Right", "Left"}},
+		{"\x1bOA\x1bOH\x1bOF\x1bOP", []string{"Up", "Home", "End", "F1"}},
+		{"\x1b[1~\x1b[3~\x1b[4~\x1b[5~\x1b[6~", []string{"Home", "Delete", "End", "PgUp", "PgDn"}},
+		{"\x1b[15~\x1b[24~", []string{"F5", "F12"}},
+		{"\x1b[[A\x1b[[E\x1b[[Zx", []string{"F1", "F5", "x"}}, // Linux console
+		{"\x1b[1;5A\x1b[1;2D\x1b[3;3~\x1b[Z", []string{"Ctrl+Up", "Shift+Left", "Alt+Delete", "Shift+Tab"}},
+		{"\x1bx\x1b\x01", []string{"Alt+x", "Ctrl+Alt+A"}},
+		{"\x1b\x1b", []string{"Esc", "Esc"}},
+		{"\x1b[", []string{"Alt+["}},
+		{"\x1b[99~x", []string{"x"}},           // unknown sequences are dropped
+		{"\x1b[1;\rx", []string{"Enter", "x"}}, // incomplete sequences are dropped
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var got []string
+			for _, ev := range decodeKeys(tt.input) {
+				got = append(got, ev.String())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLookupKeyEvent(t *testing.T) {
+	cmds := Commands{
+		{Name: "quit", Key: 'q'},
+		{Name: "refresh", Event: KeyEvent{Code: KeyF5}},
+		{Name: "ctrl", Key: keyCtrlC},
+	}
+	lookup := func(keys string) string {
+		evs := decodeKeys(keys)
+		if cmd := cmds.Lookup(evs[0]); cmd != nil {
+			return cmd.Name
+		}
+		return ""
+	}
+	assert.Equal(t, "quit", lookup("q"))
+	assert.Equal(t, "", lookup("\x1bq"), "Alt+q is not bound")
+	assert.Equal(t, "refresh", lookup("\x1b[15~"))
+	assert.Equal(t, "", lookup("\x1b[15;2~"), "Shift+F5 is not bound")
+	assert.Equal(t, "ctrl", lookup("\x03"))
+	assert.Equal(t, "Key: F5, Command: refresh", helpFormatter(cmds[1]))
+}
@@ -0,0 +1,253 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	runtimedebug "runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Policy defines how a command is run if it is already running.
+type Policy int
+
+// Supported command policies.
+const (
+	Parallel     Policy = iota // runs every call immediately (default)
+	SingleFlight               // skips calls while the command is running
+	Queue                      // runs calls one after another
+)
+
+// DefaultCommandTimeout is the max wait time for running commands on shutdown.
+var DefaultCommandTimeout = 5 * time.Second
+
+// Errors of the Runner.
+var (
+	ErrAlreadyRunning = errors.New("command is already running")
+	ErrCommandTimeout = errors.New("timeout waiting for running commands")
+)
+
+// CommandPanic is returned for commands that panicked.
+type CommandPanic struct {
+	Command string
+	Value   any
+	Stack   []byte
+}
+
+func (p *CommandPanic) Error() string {
+	return fmt.Sprintf("command %s panicked: %v", p.Command, p.Value)
+}
+
+// Execution describes a running command.
+type Execution struct {
+	ID      int
+	Name    string
+	Started time.Time
+	cancel  context.CancelFunc
+}
+
+func (e Execution) String() string {
+	return fmt.Sprintf("[%d] %s (%s)", e.ID, e.Name, time.Since(e.Started).Round(time.Millisecond))
+}
+
+// Runner runs and tracks commands according to their Policy.
+type Runner struct {
+	mu      sync.Mutex
+	ctx     context.Context
+	wg      sync.WaitGroup
+	nextID  int
+	running map[int]*Execution
+	active  map[string]int           // running and queued calls by command name
+	queues  map[string]chan struct{} // run locks of Queue commands
+}
+
+// Runner returns the global command runner.
+func (c *Term) Runner() *Runner {
+	return &c.runner
+}
+
+// GetRunner returns the global command runner.
+func GetRunner() *Runner {
+	return term.Runner()
+}
+
+// SetContext sets the context used by Commands.Run.
+func (r *Runner) SetContext(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ctx = ctx
+}
+
+// Context returns the context used by Commands.Run.
+func (r *Runner) Context() context.Context {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// Do runs fn as execution of the command and blocks until it is finished. It returns
+// ErrAlreadyRunning for running SingleFlight commands and a *CommandPanic if fn panics.
+func (r *Runner) Do(ctx context.Context, cmd *Command, fn func(context.Context)) (err error) {
+	r.mu.Lock()
+	if r.active == nil {
+		r.running = make(map[int]*Execution)
+		r.active = make(map[string]int)
+		r.queues = make(map[string]chan struct{})
+	}
+	if cmd.Policy == SingleFlight && r.active[cmd.Name] > 0 {
+		r.mu.Unlock()
+		return ErrAlreadyRunning
+	}
+	r.active[cmd.Name]++
+	queue := r.queues[cmd.Name]
+	if cmd.Policy == Queue && queue == nil {
+		queue = make(chan struct{}, 1)
+		r.queues[cmd.Name] = queue
+	}
+	r.wg.Add(1)
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.active[cmd.Name]--
+		r.mu.Unlock()
+		r.wg.Done()
+	}()
+
+	if cmd.Policy == Queue {
+		select {
+		case queue <- struct{}{}:
+			defer func() { <-queue }()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	id := r.track(cmd.Name, cancel)
+	defer r.untrack(id)
+
+	defer func() {
+		if v := recover(); v != nil {
+			p := &CommandPanic{Command: cmd.Name, Value: v, Stack: runtimedebug.Stack()}
+			log.Printf("%v\n%s", p, p.Stack)
+			Prompt("%v", p)
+			err = p
+		}
+	}()
+	fn(ctx)
+	return nil
+}
+
+// Go runs fn as execution of the command in a goroutine.
+// Errors are reported on the prompt.
+func (r *Runner) Go(ctx context.Context, cmd *Command, fn func(context.Context)) {
+	r.spawn(func() {
+		if err := r.Do(ctx, cmd, fn); errors.Is(err, ErrAlreadyRunning) {
+			Prompt("Command %s skipped: %v", cmd.Name, err)
+		}
+	})
+}
+
+// spawn runs fn in a goroutine that is registered before it starts, so that Wait
+// and Shutdown also wait for commands that are not yet running.
+func (r *Runner) spawn(fn func()) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		fn()
+	}()
+}
+
+func (r *Runner) track(name string, cancel context.CancelFunc) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	r.running[r.nextID] = &Execution{ID: r.nextID, Name: name, Started: time.Now(), cancel: cancel}
+	return r.nextID
+}
+
+func (r *Runner) untrack(id int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.running, id)
+}
+
+// Running returns the running commands ordered by ID.
+func (r *Runner) Running() []Execution {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := make([]Execution, 0, len(r.running))
+	for _, e := range r.running {
+		res = append(res, *e)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+// Cancel cancels the context of a running command.
+func (r *Runner) Cancel(id int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e, ok := r.running[id]; ok {
+		e.cancel()
+		return true
+	}
+	return false
+}
+
+// Shutdown cancels all running commands and waits for them until the timeout.
+func (r *Runner) Shutdown(timeout time.Duration) error {
+	for _, e := range r.Running() {
+		r.Cancel(e.ID)
+	}
+	return r.Wait(timeout)
+}
+
+// Wait waits for all running and queued commands until the timeout.
+func (r *Runner) Wait(timeout time.Duration) error {
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		var names []string
+		for _, e := range r.Running() {
+			names = append(names, e.String())
+		}
+		return fmt.Errorf("%w: %s", ErrCommandTimeout, strings.Join(names, ", "))
+	}
+}
+
+// JobsCommand returns a command that prints the running commands.
+func JobsCommand() Command {
+	return Command{Name: "jobs", Fn: func(context.Context) {
+		var lines []string
+		for _, e := range GetRunner().Running() {
+			lines = append(lines, e.String())
+		}
+		GetTerm().Println("Running commands:\n  " + strings.Join(lines, "\n  "))
+	}}
+}
+
+// KillCommand returns a command that cancels a running command by ID.
+func KillCommand() Command {
+	return Command{Name: "kill", Args: []Arg{{Name: "id", Type: IntArg}}, Exec: func(ctx context.Context, args Args) error {
+		if !GetRunner().Cancel(args.Int("id")) {
+			return fmt.Errorf("no running command with id %d", args.Int("id"))
+		}
+		return nil
+	}}
+}
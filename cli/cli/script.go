@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultExpectTimeout is the max wait time of the `expect` statement of a Script.
+var DefaultExpectTimeout = 2 * time.Second
+
+// Script is a parsed session script. Each line of a script is one of:
+//
+//	# comment
+//	keys <text>         sends the keys, <text> can be a quoted Go string, e.g., "c\r" or "\x1b[A"
+//	wait <duration>     pauses the script, e.g., wait 500ms
+//	expect <text>       waits until the output or the prompt contains the text
+//	timeout <duration>  sets the max wait time of the following expect statements
+//	repeat <n>          repeats the statements up to the matching `end` n times
+//	end
+type Script struct {
+	steps []scriptStep
+}
+
+type scriptStep struct {
+	line int
+	op   string
+	text string
+	dur  time.Duration
+	n    int
+	body []scriptStep
+}
+
+// ParseScript parses a session script.
+func ParseScript(s string) (*Script, error) {
+	var (
+		stack = [][]scriptStep{nil}
+		loops []scriptStep
+	)
+	for i, line := range strings.Split(s, "\n") {
+		num := i + 1
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		op, arg, _ := strings.Cut(line, " ")
+		arg = strings.TrimSpace(arg)
+		step := scriptStep{line: num, op: op}
+		var err error
+		switch op {
+		case "keys", "expect":
+			step.text, err = unquoteArg(arg)
+			if err == nil && step.text == "" {
+				err = errors.New("missing text")
+			}
+		case "wait", "timeout":
+			step.dur, err = time.ParseDuration(arg)
+		case "repeat":
+			step.n, err = strconv.Atoi(arg)
+			if err == nil && step.n < 0 {
+				err = errors.New("negative count")
+			}
+			if err == nil {
+				loops = append(loops, step)
+				stack = append(stack, nil)
+				continue
+			}
+		case "end":
+			if len(loops) == 0 {
+				return nil, fmt.Errorf("line %d: end without repeat", num)
+			}
+			step = loops[len(loops)-1]
+			step.body = stack[len(stack)-1]
+			loops, stack = loops[:len(loops)-1], stack[:len(stack)-1]
+		default:
+			err = errors.New("unknown statement")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", num, op, err)
+		}
+		stack[len(stack)-1] = append(stack[len(stack)-1], step)
+	}
+	if len(loops) > 0 {
+		return nil, fmt.Errorf("line %d: repeat without end", loops[len(loops)-1].line)
+	}
+	return &Script{steps: stack[0]}, nil
+}
+
+func unquoteArg(s string) (string, error) {
+	if strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "`") {
+		return strconv.Unquote(s)
+	}
+	return s, nil
+}
+
+// scriptRun is the state of a running script.
+type scriptRun struct {
+	keys    chan<- rune
+	output  <-chan []byte
+	seen    string // output after the last match
+	timeout time.Duration
+}
+
+// Run sends the keys of the script through the key processing of ProcessInput and checks the
+// expected output. It returns after the script is finished and the started commands are done.
+func (s *Script) Run(ctx context.Context, commands Commands) error {
+	output, stop := GetTerm().SubscribeOutput(1000)
+	defer stop()
+
+	keys := make(chan rune)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		processEvents(ctx, KeyEvents(keys, DefaultEscTimeout), commands)
+	}()
+
+	run := &scriptRun{keys: keys, output: output, timeout: DefaultExpectTimeout}
+	err := run.steps(ctx, s.steps)
+	close(keys)
+	<-done
+	if err != nil {
+		return err
+	}
+	return GetRunner().Wait(DefaultCommandTimeout)
+}
+
+func (r *scriptRun) steps(ctx context.Context, steps []scriptStep) error {
+	for _, step := range steps {
+		if err := r.step(ctx, step); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *scriptRun) step(ctx context.Context, step scriptStep) error {
+	switch step.op {
+	case "keys":
+		for _, k := range step.text {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case r.keys <- k:
+			}
+		}
+	case "wait":
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(step.dur):
+		}
+	case "timeout":
+		r.timeout = step.dur
+	case "expect":
+		if err := r.expect(ctx, step.text); err != nil {
+			return fmt.Errorf("line %d: expect %q: %w", step.line, step.text, err)
+		}
+	case "repeat":
+		for i := 0; i < step.n; i++ {
+			if err := r.steps(ctx, step.body); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// expect waits until the output or the current prompt message contains the text.
+// A match in the output consumes the output up to the end of the match.
+func (r *scriptRun) expect(ctx context.Context, text string) error {
+	timeout := time.After(r.timeout)
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if i := strings.Index(r.seen, text); i >= 0 {
+			r.seen = r.seen[i+len(text):]
+			return nil
+		}
+		if strings.Contains(GetTerm().GetMessage(), text) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("timeout after %s", r.timeout)
+		case b := <-r.output:
+			r.seen += string(b)
+		case <-ticker.C:
+		}
+	}
+}
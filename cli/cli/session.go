@@ -0,0 +1,263 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	xterm "golang.org/x/term"
+)
+
+// Event types of asciicast files.
+const (
+	CastOutput = "o"
+	CastInput  = "i"
+)
+
+// CastHeader is the header of an asciicast v2 file.
+type CastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// CastEvent is an event of an asciicast v2 file, stored as `[time, type, data]`.
+type CastEvent struct {
+	Time float64 // seconds since the start of the recording
+	Type string
+	Data string
+}
+
+// MarshalJSON implements json.Marshaler.
+func (e CastEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{e.Time, e.Type, e.Data})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (e *CastEvent) UnmarshalJSON(b []byte) error {
+	v := []any{&e.Time, &e.Type, &e.Data}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if len(v) != 3 {
+		return fmt.Errorf("invalid asciicast event: %s", b)
+	}
+	return nil
+}
+
+// Cast is a recorded terminal session.
+type Cast struct {
+	Header CastHeader
+	Events []CastEvent
+}
+
+// ReadCast reads an asciicast v2 file.
+func ReadCast(r io.Reader) (*Cast, error) {
+	var cast Cast
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	if !scanner.Scan() {
+		return nil, errors.New("missing asciicast header")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &cast.Header); err != nil {
+		return nil, fmt.Errorf("invalid asciicast header: %w", err)
+	}
+	if cast.Header.Version != 2 {
+		return nil, fmt.Errorf("unsupported asciicast version: %d", cast.Header.Version)
+	}
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e CastEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, err
+		}
+		cast.Events = append(cast.Events, e)
+	}
+	return &cast, scanner.Err()
+}
+
+// ReadCastFile reads an asciicast v2 file from the given path.
+func ReadCastFile(path string) (*Cast, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadCast(f)
+}
+
+// Input returns the recorded keys.
+func (c *Cast) Input() string {
+	var s string
+	for _, e := range c.Events {
+		if e.Type == CastInput {
+			s += e.Data
+		}
+	}
+	return s
+}
+
+// Replay replays the input events of the cast through the key processing of ProcessInput.
+// The speed multiplies the recorded timing, a speed of 0 replays without waiting.
+// It returns after all keys are processed and the started commands are finished.
+func (c *Cast) Replay(ctx context.Context, commands Commands, speed float64) error {
+	runes := make(chan rune)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		processEvents(ctx, KeyEvents(runes, DefaultEscTimeout), commands)
+	}()
+
+	start := time.Now()
+	var err error
+send:
+	for _, e := range c.Events {
+		if e.Type != CastInput {
+			continue
+		}
+		if speed > 0 {
+			wait := time.Duration(e.Time/speed*float64(time.Second)) - time.Since(start)
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+				break send
+			case <-time.After(wait):
+			}
+		}
+		for _, r := range e.Data {
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+				break send
+			case runes <- r:
+			}
+		}
+	}
+	close(runes)
+	<-done
+	if err != nil {
+		return err
+	}
+	return GetRunner().Wait(DefaultCommandTimeout)
+}
+
+// Recorder records the input and output of a terminal session as asciicast v2 file.
+type Recorder struct {
+	mu    sync.Mutex
+	w     io.Writer
+	start time.Time
+	err   error
+}
+
+// NewRecorder writes the asciicast header and returns a Recorder writing to w.
+func NewRecorder(w io.Writer, width, height int) (*Recorder, error) {
+	r := &Recorder{w: w, start: time.Now()}
+	header := CastHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: r.start.Unix(),
+		Env:       map[string]string{"TERM": os.Getenv("TERM"), "SHELL": os.Getenv("SHELL")},
+	}
+	if err := r.writeJSON(header); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Err returns the first write error.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *Recorder) writeJSON(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = r.w.Write(append(b, '\n'))
+	return err
+}
+
+// record writes an event. Write errors stop the recording.
+func (r *Recorder) record(typ, data string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	e := CastEvent{Time: time.Since(r.start).Seconds(), Type: typ, Data: data}
+	r.err = r.writeJSON(e)
+}
+
+// Write records the output written to the terminal.
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.record(CastOutput, string(p))
+	return len(p), nil
+}
+
+// RecordInput records the keys read from the input channel and forwards them to the returned channel.
+func (r *Recorder) RecordInput(input <-chan rune) <-chan rune {
+	ch := make(chan rune, cap(input))
+	go func() {
+		defer close(ch)
+		for k := range input {
+			r.record(CastInput, string(k))
+			ch <- k
+		}
+	}()
+	return ch
+}
+
+// startRecording records the session of the global term to the file.
+func startRecording(path string) (stop func(), err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, h, err := xterm.GetSize(0)
+	if err != nil {
+		w, h = defaultWidth, defaultHeight
+	}
+	rec, err := NewRecorder(f, w, h)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	t := GetTerm()
+	t.SetRecorder(rec)
+	return func() {
+		t.SetRecorder(nil)
+		if err := errors.Join(rec.Err(), f.Close()); err != nil {
+			log.Println("failed to record session, error:", err)
+		}
+	}, nil
+}
+
+// SetRecorder sets the recorder of the terminal session. Use nil to stop recording.
+func (c *Term) SetRecorder(r *Recorder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.recorder = r
+}
+
+// Recorder returns the recorder of the terminal session or nil.
+func (c *Term) Recorder() *Recorder {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.recorder
+}
@@ -0,0 +1,297 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	runtimedebug "runtime/debug"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultHookTimeout is the max run time of a ShutdownHook without a Timeout.
+var DefaultHookTimeout = 5 * time.Second
+
+// ForcedExitCode is the exit code used when a second SIGINT or SIGTERM forces the exit.
+const ForcedExitCode = 130
+
+// ErrHookTimeout is returned for hooks that did not finish in time.
+var ErrHookTimeout = errors.New("timeout waiting for shutdown hook")
+
+// ShutdownHook is a named shutdown function. Hooks run one after another
+// ordered by Priority, lowest first, and in order of registration for equal priorities.
+type ShutdownHook struct {
+	Name     string
+	Priority int
+	Timeout  time.Duration // defaults to DefaultHookTimeout
+	Fn       func(ctx context.Context) error
+}
+
+// HookPanic is returned for hooks that panicked.
+type HookPanic struct {
+	Hook  string
+	Value any
+	Stack []byte
+}
+
+func (p *HookPanic) Error() string {
+	return fmt.Sprintf("shutdown hook %s panicked: %v", p.Hook, p.Value)
+}
+
+// HookError wraps the error of a failed hook.
+type HookError struct {
+	Hook string
+	Err  error
+}
+
+func (e *HookError) Error() string { return fmt.Sprintf("shutdown hook %s failed: %v", e.Hook, e.Err) }
+func (e *HookError) Unwrap() error { return e.Err }
+
+// ShutdownManager runs the shutdown hooks and handles the OS signals of the application:
+// SIGINT and SIGTERM stop the application, a second SIGINT or SIGTERM forces the exit,
+// SIGHUP calls the reload funcs, and SIGQUIT dumps the stacks of all goroutines.
+type ShutdownManager struct {
+	mu      sync.Mutex
+	hooks   []ShutdownHook
+	reload  []func() error
+	dumpOut io.Writer
+	running bool
+	done    chan struct{} // closed after a run, replaced on the next run
+	closed  bool
+	err     error
+}
+
+var shutdownManager = &ShutdownManager{}
+
+// GetShutdownManager returns the global ShutdownManager used by StartTerm and SigWait.
+func GetShutdownManager() *ShutdownManager {
+	return shutdownManager
+}
+
+// OnShutdown adds a hook to the global ShutdownManager.
+func OnShutdown(hook ShutdownHook) {
+	shutdownManager.Add(hook)
+}
+
+// OnReload adds a reload func to the global ShutdownManager.
+func OnReload(fn func() error) {
+	shutdownManager.OnReload(fn)
+}
+
+// Add adds a hook.
+func (m *ShutdownManager) Add(hook ShutdownHook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook)
+}
+
+// OnReload adds a func that is called on SIGHUP.
+func (m *ShutdownManager) OnReload(fn func() error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reload = append(m.reload, fn)
+}
+
+// SetDumpOutput sets the output of the goroutine dumps. The default is os.Stderr.
+func (m *ShutdownManager) SetDumpOutput(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dumpOut = w
+}
+
+// Done returns a channel that is closed after the hooks of the current or next run have finished.
+func (m *ShutdownManager) Done() <-chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.doneChan()
+}
+
+// doneChan returns the done channel. It must be called with m.mu locked.
+func (m *ShutdownManager) doneChan() chan struct{} {
+	if m.done == nil {
+		m.done = make(chan struct{})
+	}
+	return m.done
+}
+
+// stopping returns true if the hooks are running.
+func (m *ShutdownManager) stopping() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.running
+}
+
+// Run runs and removes the added hooks. Failed, panicked, and timed out hooks do not stop the
+// remaining hooks. It returns the joined hook errors. Concurrent calls wait for the running
+// hooks and return the same result.
+func (m *ShutdownManager) Run(ctx context.Context) error {
+	m.mu.Lock()
+	if m.running {
+		done := m.done
+		m.mu.Unlock()
+		<-done
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.err
+	}
+	if m.closed {
+		m.done, m.closed = nil, false
+	}
+	done := m.doneChan()
+	m.running = true
+	hooks := m.hooks
+	m.hooks = nil
+	m.mu.Unlock()
+
+	sort.SliceStable(hooks, func(i, j int) bool { return hooks[i].Priority < hooks[j].Priority })
+	var errs []error
+	for _, h := range hooks {
+		debug("run shutdown hook %s", h.Name)
+		if err := runHook(ctx, h); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	err := errors.Join(errs...)
+	m.mu.Lock()
+	m.err = err
+	m.running = false
+	m.closed = true
+	m.mu.Unlock()
+	close(done)
+	return err
+}
+
+// runHook runs the hook and returns early if the hook ignores the canceled context.
+func runHook(ctx context.Context, h ShutdownHook) error {
+	timeout := h.Timeout
+	if timeout == 0 {
+		timeout = DefaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				result <- &HookPanic{Hook: h.Name, Value: r, Stack: runtimedebug.Stack()}
+			}
+		}()
+		if err := h.Fn(ctx); err != nil {
+			result <- &HookError{Hook: h.Name, Err: err}
+			return
+		}
+		result <- nil
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return &HookError{Hook: h.Name, Err: ErrHookTimeout}
+	}
+}
+
+// Reload calls the reload funcs and returns the joined errors.
+func (m *ShutdownManager) Reload() error {
+	m.mu.Lock()
+	reload := append([]func() error(nil), m.reload...)
+	m.mu.Unlock()
+	var errs []error
+	for _, fn := range reload {
+		if err := fn(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Dump writes the stacks of all goroutines to the dump output.
+func (m *ShutdownManager) Dump() error {
+	m.mu.Lock()
+	var w io.Writer = &stdErrWrapper{}
+	if m.dumpOut != nil {
+		w = m.dumpOut
+	}
+	m.mu.Unlock()
+	return pprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// Notify starts handling the OS signals and returns a channel that receives the first
+// SIGINT or SIGTERM, and a func to stop the signal handling.
+// Further SIGINT or SIGTERM signals, also during the run of the hooks, restore the
+// terminal and exit the application with the ForcedExitCode.
+func (m *ShutdownManager) Notify() (<-chan os.Signal, func()) {
+	in := make(chan os.Signal, 1)
+	signal.Notify(in, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	out := make(chan os.Signal, 1)
+	quit := make(chan struct{})
+	go func() {
+		var stopping bool
+		for {
+			select {
+			case <-quit:
+				return
+			case s := <-in:
+				switch s {
+				case syscall.SIGHUP:
+					if err := m.Reload(); err != nil {
+						log.Println("failed to reload, error:", err)
+					}
+				case syscall.SIGQUIT:
+					if err := m.Dump(); err != nil {
+						log.Println("failed to dump goroutines, error:", err)
+					}
+				default:
+					if stopping || m.stopping() {
+						forceExit(s)
+					}
+					stopping = true
+					out <- s
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return out, func() {
+		once.Do(func() {
+			signal.Stop(in)
+			close(quit)
+		})
+	}
+}
+
+// forceExit restores the terminal and exits the application.
+func forceExit(s os.Signal) {
+	if err := RestoreTerminal(); err != nil {
+		log.Println("failed to restore terminal, error:", err)
+	}
+	fmt.Fprintf(origStderr, "forced exit on repeated signal %q\n", s)
+	os.Exit(ForcedExitCode)
+}
+
+// RestoreOnPanic restores the terminal and re-panics. Use it as first deferred call in main.
+//
+// Usage:
+//
+//	func main() {
+//	    defer cli.RestoreOnPanic()
+//	    ctx, cancel := cli.StartTerm(context.Background(), config.Interactive())
+//	    // ...
+//	}
+func RestoreOnPanic() {
+	if r := recover(); r != nil {
+		if err := RestoreTerminal(); err != nil {
+			log.Println("failed to restore terminal, error:", err)
+		}
+		panic(r)
+	}
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strconv"
+
+	xterm "golang.org/x/term"
+)
+
+// detectSize returns the size of the first terminal of stderr, stdout, and stdin.
+// Without a terminal, it uses the width of the COLUMNS env var if set.
+func detectSize() (width, height int, ok bool) {
+	for _, f := range []*os.File{origStderr, origStdout, os.Stdin} {
+		if w, h, err := xterm.GetSize(int(f.Fd())); err == nil && w > 0 {
+			return w, h, true
+		}
+	}
+	if w, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && w > 0 {
+		return w, 0, true
+	}
+	return 0, 0, false
+}
+
+// termWidth returns the fixed, the tracked, or the detected terminal width.
+// It must be called with c.mu locked.
+func (c *Term) termWidth() (int, bool) {
+	switch {
+	case c.width > 0:
+		return c.width, true
+	case c.trackedWidth > 0:
+		return c.trackedWidth, true
+	}
+	w, _, ok := detectSize()
+	return w, ok
+}
+
+// Width returns the terminal width or the default width if the width is unknown.
+func (c *Term) Width() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if w, ok := c.termWidth(); ok {
+		return w
+	}
+	return defaultWidth
+}
+
+// SetWidth sets a fixed terminal width. Use 0 to detect the width.
+func (c *Term) SetWidth(w int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.width = w
+}
+
+// TrackSize updates the terminal width on resize (SIGWINCH) until the context is done.
+// After a resize, the status area is rendered again.
+func (c *Term) TrackSize(ctx context.Context) {
+	ch := make(chan os.Signal, 1)
+	notifyResize(ch)
+	defer signal.Stop(ch)
+	defer func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.trackedWidth = 0
+	}()
+	w, _, _ := detectSize()
+	c.mu.Lock()
+	c.trackedWidth = w
+	c.mu.Unlock()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			debug("terminal resized")
+			c.resize()
+		}
+	}
+}
+
+// resize stores the detected width and renders the status area.
+func (c *Term) resize() {
+	w, _, _ := detectSize()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if w == c.trackedWidth {
+		return
+	}
+	c.trackedWidth = w
+	c.lastLine = "" // force rendering the status area
+	c.write()
+}
+
+// SetColor enables or disables the color codes in the output. Disabling removes
+// the SGR escape sequences, e.g., "\x1b[31m", from the output of all loggers.
+func (c *Term) SetColor(v bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.noColor = !v
+}
+
+// SetASCII sets the ASCII clock and spinners instead of unicode.
+func (c *Term) SetASCII(v bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ascii.Store(v)
+	c.clock = Clock(clockClock)
+	if v {
+		c.clock = Clock(asciiClock)
+	}
+}
+
+// IsASCII tells if the ASCII clock and spinners are used.
+func (c *Term) IsASCII() bool {
+	return c.ascii.Load()
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package cli
+
+import "os"
+
+// notifyResize is a no-op on platforms without SIGWINCH.
+func notifyResize(chan<- os.Signal) {}
@@ -0,0 +1,283 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	xterm "golang.org/x/term"
+)
+
+// Refresh intervals of the status area.
+var (
+	// DefaultStatusInterval is the refresh interval of the status area on a TTY.
+	DefaultStatusInterval = 100 * time.Millisecond
+	// DefaultPlainInterval is the interval of the plain status lines when not on a TTY.
+	DefaultPlainInterval = 5 * time.Second
+)
+
+// defaultWidth is used if the terminal width is unknown.
+const defaultWidth = 80
+
+// defaultHeight is used if the terminal height is unknown.
+const defaultHeight = 24
+
+// Widget renders a line of the status area.
+type Widget interface {
+	Render(width int) string
+}
+
+// WidgetFunc implements a Widget using a func.
+type WidgetFunc func(width int) string
+
+// Render implements Widget.
+func (f WidgetFunc) Render(width int) string { return f(width) }
+
+// widgetEntry identifies a registered widget.
+type widgetEntry struct {
+	Widget
+}
+
+// AddWidget adds a widget to the status area and returns a func to remove it.
+func (c *Term) AddWidget(w Widget) (remove func()) {
+	e := &widgetEntry{w}
+	c.mu.Lock()
+	c.widgets = append(c.widgets, e)
+	c.mu.Unlock()
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for i, v := range c.widgets {
+			if v == e {
+				c.widgets = append(c.widgets[:i], c.widgets[i+1:]...)
+				break
+			}
+		}
+		c.write()
+	}
+}
+
+// SetStatusHeight sets a fixed number of widget lines of the status area.
+// Missing lines are left empty and additional widgets are not displayed.
+// The default height of 0 displays all widgets.
+func (c *Term) SetStatusHeight(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statusHeight = n
+}
+
+// SetTTY overrides the TTY detection of the status area.
+func (c *Term) SetTTY(v bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tty = &v
+}
+
+// IsTTY tells if the status area is rendered in the terminal or as plain lines.
+func (c *Term) IsTTY() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.isTTY()
+}
+
+func (c *Term) isTTY() bool {
+	if c.tty != nil {
+		return *c.tty
+	}
+	return xterm.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// StartStatus refreshes the status area until the context is done. When not on a TTY,
+// it writes the widgets as plain lines every DefaultPlainInterval instead.
+func (c *Term) StartStatus(ctx context.Context) {
+	tty := c.IsTTY()
+	interval := DefaultStatusInterval
+	if !tty {
+		interval = DefaultPlainInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if tty {
+				c.mu.Lock()
+				c.write()
+				c.mu.Unlock()
+				continue
+			}
+			if lines := c.renderWidgets(); len(lines) > 0 {
+				_, _ = c.WriteString(strings.Join(lines, "\n") + "\n")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// renderWidgets renders the widget lines.
+func (c *Term) renderWidgets() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.widgetLines(false)
+}
+
+// widgetLines renders the widgets, padded to the status height if fixed is true.
+// It must be called with c.mu locked.
+func (c *Term) widgetLines(fixed bool) []string {
+	width, ok := c.termWidth()
+	if !ok {
+		width = defaultWidth
+	}
+	n := len(c.widgets)
+	if c.statusHeight > 0 {
+		n = min(n, c.statusHeight)
+	}
+	lines := make([]string, 0, n)
+	for _, w := range c.widgets[:n] {
+		lines = append(lines, truncate(w.Render(width), width-1))
+	}
+	for fixed && len(lines) < c.statusHeight {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// statusArea returns the lines of the status area: the widgets on a TTY followed by the status line.
+// It must be called with c.mu locked.
+func (c *Term) statusArea() []string {
+	var lines []string
+	if len(c.widgets) > 0 && c.isTTY() {
+		lines = c.widgetLines(true)
+	}
+	if c.statusLine != "" || len(lines) == 0 {
+		lines = append(lines, c.statusLine)
+	}
+	return lines
+}
+
+// truncate cuts the string to the given number of runes.
+func truncate(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
+// ProgressBar renders the progress of a task.
+type ProgressBar struct {
+	Name    string
+	total   atomic.Int64
+	current atomic.Int64
+}
+
+// NewProgressBar returns a ProgressBar for the given total.
+func NewProgressBar(name string, total int64) *ProgressBar {
+	p := &ProgressBar{Name: name}
+	p.total.Store(total)
+	return p
+}
+
+// Add adds n to the progress.
+func (p *ProgressBar) Add(n int64) { p.current.Add(n) }
+
+// Set sets the progress.
+func (p *ProgressBar) Set(n int64) { p.current.Store(n) }
+
+// SetTotal sets the total.
+func (p *ProgressBar) SetTotal(n int64) { p.total.Store(n) }
+
+// Render implements Widget.
+func (p *ProgressBar) Render(width int) string {
+	current, total := p.current.Load(), p.total.Load()
+	ratio := 0.0
+	if total > 0 {
+		ratio = min(float64(current)/float64(total), 1)
+	}
+	info := fmt.Sprintf(" %3.0f%% %d/%d", ratio*100, current, total)
+	size := width - len(p.Name) - len(info) - 4
+	if size < 1 {
+		return p.Name + info
+	}
+	done := int(ratio * float64(size))
+	bar := strings.Repeat("=", done)
+	if done < size {
+		bar += ">" + strings.Repeat(" ", size-done-1)
+	}
+	return fmt.Sprintf("%s [%s]%s", p.Name, bar, info)
+}
+
+// Counter counts events and renders the total and the rate per second.
+type Counter struct {
+	Name  string
+	count atomic.Int64
+
+	mu        sync.Mutex
+	lastCount int64
+	lastTime  time.Time
+	rate      float64
+}
+
+// NewCounter returns a Counter.
+func NewCounter(name string) *Counter {
+	return &Counter{Name: name, lastTime: time.Now()}
+}
+
+// Add adds n events.
+func (c *Counter) Add(n int64) { c.count.Add(n) }
+
+// Count returns the number of events.
+func (c *Counter) Count() int64 { return c.count.Load() }
+
+// Rate returns the rate per second since the previous call that was at least a second ago.
+func (c *Counter) Rate() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now, count := time.Now(), c.count.Load()
+	if dt := now.Sub(c.lastTime); dt >= time.Second {
+		c.rate = float64(count-c.lastCount) / dt.Seconds()
+		c.lastCount, c.lastTime = count, now
+	}
+	return c.rate
+}
+
+// Render implements Widget.
+func (c *Counter) Render(width int) string {
+	rate := c.Rate()
+	return fmt.Sprintf("%s %d (%.1f/s)", c.Name, c.Count(), rate)
+}
+
+// Spinner renders an animated spinner followed by a name and message.
+type Spinner struct {
+	Name    string
+	clock   clock
+	message atomic.Value
+}
+
+// asciiSpinnerClock replaces the unicode spinners in ASCII mode.
+var asciiSpinnerClock = Clock(asciiSpinner)
+
+// NewSpinner returns a Spinner.
+func NewSpinner(name string) *Spinner {
+	return &Spinner{Name: name, clock: Clock(brailleSpinner)}
+}
+
+// SetMessage sets the message displayed after the spinner.
+func (s *Spinner) SetMessage(msg string) { s.message.Store(msg) }
+
+// Render implements Widget.
+func (s *Spinner) Render(width int) string {
+	msg, _ := s.message.Load().(string)
+	clk := &s.clock
+	if GetTerm().IsASCII() {
+		clk = &asciiSpinnerClock
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s %s %s", clk.Chars(DefaultStatusInterval), s.Name, msg))
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -8,9 +9,8 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	xterm "golang.org/x/term"
 )
 
 // Term stores shared terminal state for loggers, the underlying terminals, and commands.
@@ -26,6 +26,23 @@ type Term struct {
 	statusLine string // current status line text
 	message    string // message to be displayed on the status line
 	lastLine   string // last line that was printed
+	cmdLine    string // rendered command line, replaces the message while editing
+	clockParts [2]string
+	history    *History
+
+	widgets      []*widgetEntry // widgets of the status area
+	statusHeight int            // fixed number of widget lines, 0 means all widgets
+	statusLines  int            // number of lines of the last written status area
+	tty          *bool          // TTY detection override
+
+	runner      Runner
+	subscribers map[chan []byte]struct{} // receivers of the log output
+	recorder    *Recorder                // records the terminal session
+
+	noColor      bool        // remove color codes from the output
+	ascii        atomic.Bool // use the ASCII clock and spinners
+	width        int         // fixed terminal width, 0 detects the width
+	trackedWidth int         // terminal width reported on resize, see TrackSize
 }
 
 // the global term
@@ -95,6 +112,49 @@ func (c *Term) GetMessage() string {
 	return term.message
 }
 
+// SetCommandLine sets the command line that is displayed on the status line instead of the message.
+// An empty string ends the command-line display.
+func (c *Term) SetCommandLine(s string) {
+	c.mu.Lock()
+	c.cmdLine = s
+	c.mu.Unlock()
+	c.refresh()
+}
+
+// GetHistory returns the command-line history.
+func (c *Term) GetHistory() *History {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.history == nil {
+		c.history, _ = NewHistory("")
+	}
+	return c.history
+}
+
+// SetHistory sets the command-line history.
+func (c *Term) SetHistory(h *History) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.history = h
+}
+
+// refresh renders the status line with the clock and the message or command line.
+func (c *Term) refresh() {
+	c.mu.RLock()
+	digital, analog := c.clockParts[0], c.clockParts[1]
+	msg, cmdLine := c.message, c.cmdLine
+	c.mu.RUnlock()
+	if cmdLine != "" {
+		msg = cmdLine
+	}
+	if digital == "" && analog == "" {
+		// without clock only the command line is displayed
+		c.Prompt(cmdLine)
+		return
+	}
+	c.Prompt(digital, msg, analog, "")
+}
+
 // Help prints and prompts help for the configured Commands.
 func (c *Term) Help() {
 	c.SetMessage(GetCommands().String())
@@ -110,10 +170,10 @@ func (c *Term) SetOutput(w io.Writer) {
 
 // var reLineEnd = regexp.MustCompile("\n$")
 var (
-	rePendingNL   = regexp.MustCompile("[^\n]*$")
-	reStartCR     = regexp.MustCompile("^[\r]*")
-	reNLCR        = regexp.MustCompile("[\n\r]*")
-	reNLWithoutCR = regexp.MustCompile("[\n][^\r]*")
+	rePendingNL = regexp.MustCompile("[^\n]*$")
+	reStartCR   = regexp.MustCompile("^[\r]*")
+	reNLCR      = regexp.MustCompile("[\n\r]*")
+	reColor     = regexp.MustCompile("\x1b\\[[0-9;]*m")
 )
 
 // TODO: what is safer/faster regex check or last bytes check?
@@ -200,11 +260,23 @@ const (
 //	has data        any              clear + print input and status
 func (c *Term) write() {
 	output, pending := c.printableOutput()
-	if len(output) == 0 && c.statusLine == c.lastLine {
+	area := c.statusArea()
+	status := strings.Join(area, "\r\n")
+	if len(output) == 0 && status == c.lastLine {
 		return
 	}
+	if c.noColor {
+		output = reColor.ReplaceAllLiteral(output, nil)
+	}
+	if len(output) > 0 {
+		c.publish(output)
+	}
 	var buf []byte
 	buf = append(buf, []byte(c.clearString())...)
+	for i := 1; i < c.statusLines; i++ {
+		// move up and clear the remaining lines of the previous status area
+		buf = append(buf, []byte("\x1b[1A"+c.clearString())...)
+	}
 	buf = append(buf, output...) // output is nil or ends with NL/CR
 
 	// CR check and handling in raw mode
@@ -213,36 +285,69 @@ func (c *Term) write() {
 		panic(fmt.Errorf("invalid buffer data: %q", buf))
 	}
 	if c.raw {
-		// TODO: do we need to add CR for each inline NL?
-		// Yes! ;)
-		locs := reNLWithoutCR.FindAllIndex(buf, -1)
-		if len(locs) > 0 {
-			b := make([]byte, len(buf)+len(locs))
-			loc1 := 0
-			for _, loc := range locs {
-				b = append(b, buf[loc1:loc[0]]...)
-				b = append(b, []byte("\n\r")...)
-				loc1 = loc[0] + 1
-				// Since loc[0] is always a valid byte, loc[0] + 1 is at most == len(buf),
-				// which is a valid range index for a slice
-			}
-			buf = append(b, buf[loc1:]...)
-			if len(locs) > 1 && c.debug && c.verbose {
-				// Note: len(locs) == 1 is the default case, no need to log it.
-				buf = append(buf, []byte(fmt.Sprintf("injected %d CR\n\r", len(locs)))...)
+		// add CR to each NL that is not followed by a CR
+		b := make([]byte, 0, len(buf)+bytes.Count(buf, []byte{NL}))
+		injected := 0
+		for i, v := range buf {
+			b = append(b, v)
+			if v == NL && (i+1 == len(buf) || buf[i+1] != CR) {
+				b = append(b, CR)
+				injected++
 			}
 		}
+		buf = b
+		if injected > 1 && c.debug && c.verbose {
+			// Note: injected == 1 is the default case, no need to log it.
+			buf = append(buf, []byte(fmt.Sprintf("injected %d CR\n\r", injected))...)
+		}
 	}
 
-	buf = append(buf, []byte(c.statusLine)...)
+	buf = append(buf, []byte(status)...)
 	_, _ = c.out.Write(buf)
+	if c.recorder != nil {
+		_, _ = c.recorder.Write(buf)
+	}
 	c.buf = pending
-	c.lastLine = c.statusLine
+	c.lastLine = status
+	c.statusLines = len(area)
+}
+
+// SubscribeOutput returns a channel that receives the completed output lines written to the
+// terminal, excluding the status area, and a func to stop the subscription.
+// Output is dropped if the channel is full.
+func (c *Term) SubscribeOutput(size int) (<-chan []byte, func()) {
+	ch := make(chan []byte, size)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.subscribers == nil {
+		c.subscribers = make(map[chan []byte]struct{})
+	}
+	c.subscribers[ch] = struct{}{}
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			delete(c.subscribers, ch)
+			close(ch)
+		})
+	}
+}
+
+// publish sends the output to all subscribers. It must be called with c.mu locked.
+func (c *Term) publish(output []byte) {
+	for ch := range c.subscribers {
+		select {
+		case ch <- append([]byte(nil), output...):
+		default:
+		}
+	}
 }
 
 // clearString returns a string to clear the complete line.
+// It must be called with c.mu locked.
 func (c *Term) clearString() string {
-	if w, _, err := xterm.GetSize(0); err == nil && w > 0 {
+	if w, ok := c.termWidth(); ok {
 		return fmt.Sprintf("\r%s\r", strings.Repeat(" ", w))
 	}
 	return ClearAll
@@ -270,18 +375,29 @@ func (c *Term) StartClock(ctx context.Context) {
 	for {
 		select {
 		case <-ticker.C:
-			dt := c.clock.DisplayTime(interval)
+			c.mu.RLock()
+			clk := c.clock
+			c.mu.RUnlock()
+			dt := clk.DisplayTime(interval)
 			if dt != nil {
-				c.Prompt(dt.digital, c.GetMessage(), dt.analog, "")
+				c.setClockParts(dt.digital, dt.analog)
+				c.refresh()
 			}
 		case <-ctx.Done():
 			debug("clock stopped")
+			c.setClockParts("", "")
 			c.Prompt("")
 			return
 		}
 	}
 }
 
+func (c *Term) setClockParts(digital, analog string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clockParts = [2]string{digital, analog}
+}
+
 // GetClock returns the global clock.
 func (c *Term) GetClock() *clock {
 	return &c.clock
@@ -1,9 +1,13 @@
 package cli
 
 import (
+	"bytes"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/cli/vt"
+	xterm "golang.org/x/term"
 )
 
 func TestNewlineExpr(t *testing.T) {
@@ -19,3 +23,51 @@ func TestNewlineExpr(t *testing.T) {
 	cr := reStartCR.FindString(rest)
 	assert.Equal(t, "\r\r", cr)
 }
+
+func TestRawModeScreen(t *testing.T) {
+	screen := vt.NewScreen(100, 5).SetRaw(true)
+	c := &Term{out: screen, raw: true, tty: new(bool)}
+	c.statusLine = "status"
+
+	_, _ = c.Write([]byte("first\nsecond\nthird\n"))
+	assert.Equal(t, "first\nsecond\nthird\nstatus", screen.String(), "each NL requires a CR in raw mode")
+
+	_, _ = c.Write([]byte("fourth\r\nfif"))
+	_, _ = c.Write([]byte("th\n"))
+	assert.Equal(t, []string{"second", "third", "fourth", "fifth", "status"}, screen.Lines())
+	assert.Equal(t, []string{"first"}, screen.Scrollback())
+	row, col := screen.Cursor()
+	assert.Equal(t, []int{4, 6}, []int{row, col})
+}
+
+func TestColorASCIIAndWidth(t *testing.T) {
+	var out bytes.Buffer
+	c := &Term{out: &out, tty: new(bool), clock: Clock(clockClock)}
+	c.SetWidth(10)
+	c.SetColor(false)
+	c.SetASCII(true)
+
+	_, _ = c.Write([]byte("\x1b[31mred\x1b[0m \x1b[1;90mgray\x1b[0m\n"))
+	assert.Equal(t, "\r          \rred gray\n", out.String(), "colors are removed and the line is cleared with the fixed width")
+	assert.Equal(t, 10, c.Width())
+	assert.Equal(t, []string{"/", "-", "\\", "|"}, c.clock.clockRunes)
+	assert.True(t, c.IsASCII())
+
+	out.Reset()
+	c.SetColor(true)
+	_, _ = c.Write([]byte("\x1b[31mred\x1b[0m\n"))
+	assert.Contains(t, out.String(), "\x1b[31mred")
+}
+
+func TestColumnsHint(t *testing.T) {
+	for _, f := range []*os.File{origStderr, origStdout, os.Stdin} {
+		if xterm.IsTerminal(int(f.Fd())) {
+			t.Skip("COLUMNS is only used without a terminal")
+		}
+	}
+	t.Setenv("COLUMNS", "33")
+	c := &Term{tty: new(bool)}
+	assert.Equal(t, 33, c.Width())
+	t.Setenv("COLUMNS", "")
+	assert.Equal(t, defaultWidth, c.Width())
+}
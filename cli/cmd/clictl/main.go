@@ -0,0 +1,137 @@
+// Command clictl attaches to the control endpoint of a running process
+// to list and run its commands and to stream its log output.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ubntc/go/cli/cli"
+	xterm "golang.org/x/term"
+)
+
+// detachKey (CTRL-]) ends an attach session.
+const detachKey = 0x1d
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: clictl [-addr ADDR] COMMAND
+
+Commands:
+  list          list the commands of the process
+  jobs          list the running commands of the process
+  logs          stream the log output of the process
+  run KEY       run the command bound to KEY
+  exec LINE...  run a command line, e.g., "flush table x"
+  attach        stream the log output and run the commands of pressed keys (detach with CTRL-])
+
+Flags:`)
+	flag.PrintDefaults()
+}
+
+func main() {
+	addr := flag.String("addr", "unix:/tmp/cli.sock", "control address of the process")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	c := cli.NewControlClient(*addr)
+	args := flag.Args()[1:]
+
+	var err error
+	switch flag.Arg(0) {
+	case "list":
+		var cmds []cli.CommandInfo
+		if cmds, err = c.Commands(ctx); err == nil {
+			for _, cmd := range cmds {
+				fmt.Printf("%-4q %s\n", cmd.Key, cmd.Usage)
+			}
+		}
+	case "jobs":
+		var jobs []cli.ExecutionInfo
+		if jobs, err = c.Jobs(ctx); err == nil {
+			for _, j := range jobs {
+				fmt.Printf("[%d] %s (since %s)\n", j.ID, j.Name, j.Started.Format(cli.TimeFormatHuman))
+			}
+		}
+	case "logs":
+		err = c.Logs(ctx, os.Stdout)
+	case "run":
+		if len(args) != 1 || len([]rune(args[0])) != 1 {
+			usage()
+			os.Exit(2)
+		}
+		err = printResult(c.RunKey(ctx, []rune(args[0])[0]))
+	case "exec":
+		err = printResult(c.RunLine(ctx, strings.Join(args, " ")))
+	case "attach":
+		err = attach(ctx, c)
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func printResult(res cli.RunResult, err error) error {
+	if err != nil {
+		return err
+	}
+	if res.Error != "" {
+		return fmt.Errorf("%s: %s", res.Command, res.Error)
+	}
+	fmt.Println("ran command:", res.Command)
+	return nil
+}
+
+func attach(ctx context.Context, c *cli.ControlClient) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if xterm.IsTerminal(int(os.Stdin.Fd())) {
+		restore, err := cli.ClaimTerminal()
+		if restore != nil {
+			defer restore() // nolint
+		}
+		if err != nil {
+			return err
+		}
+	}
+	out := os.Stdout // the raw terminal replaces stdout with a CR-prepending pipe
+
+	logs := make(chan error, 1)
+	go func() { logs <- c.Logs(ctx, out) }()
+
+	fmt.Fprintln(out, "attached, press CTRL-] to detach")
+	input := cli.KeyEvents(cli.InputChan(os.Stdin), cli.DefaultEscTimeout)
+	for {
+		select {
+		case err := <-logs:
+			return err
+		case ev, more := <-input:
+			if !more || ev.Key() == detachKey {
+				return nil
+			}
+			if ev.Key() == 0 {
+				// escape sequences cannot be sent as key
+				continue
+			}
+			res, err := c.RunKey(ctx, ev.Key())
+			switch {
+			case err != nil:
+				fmt.Fprintln(out, "error:", err)
+			case res.Error != "":
+				fmt.Fprintln(out, "error:", res.Error)
+			}
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"sync"
 	"time"
 
@@ -12,8 +13,10 @@ import (
 	zlog "github.com/rs/zerolog/log"
 	"github.com/ubntc/go/cli/cli"
 	"github.com/ubntc/go/cli/cli/config"
+	"github.com/ubntc/go/cli/loggers/slogger"
 	"github.com/ubntc/go/cli/loggers/stdlogger"
 	"github.com/ubntc/go/cli/loggers/zerologger"
+	"go.uber.org/zap"
 )
 
 // Server is an dummy server.
@@ -67,6 +70,11 @@ func logPrint(context.Context) {
 	log.Println("log.Println\nmulti-\nline")
 }
 
+func slogPrint(context.Context) {
+	slog.Info("slog.Info single-line", "key", "value")
+	zap.L().Info("zap.Info single-line", zap.String("key", "value"))
+}
+
 func zeroPrint(context.Context) {
 	zlog.Print("log.Println single-line")
 	zlog.Print("log.Println\nmulti-\nline")
@@ -81,15 +89,23 @@ func main() {
 		interactive = flag.Bool("i", false, "interactive mode (also required for clock, raw, quit, and CR flags)")
 		useZeroLog  = flag.Bool("z", false, "setup zerolog in interactive mode")
 		stdLog      = flag.Bool("s", false, "setup stdlog in interactive mode")
+		useSlog     = flag.Bool("slog", false, "setup slog in interactive mode, routing log, zerolog, and zap to slog")
 
 		showClock = flag.Bool("c", false, "don't display the clock")
 		rawTerm   = flag.Bool("raw", false, "set term to raw mode")
 		crFix     = flag.Bool("cr", false, "prepend CR to NL")
 		useQuit   = flag.Bool("q", false, "use Quit keys")
+		control   = flag.String("control", "", "serve the commands on a control endpoint, e.g., unix:/tmp/mixed.sock")
+		record    = flag.String("record", "", "record the session as asciicast file, e.g., /tmp/mixed.cast")
 
 		verbose = flag.Bool("v", false, "more logs")
 		debug   = flag.Bool("x", false, "debug mode")
+
+		display config.Overrides
 	)
+	flag.Var(&display.Color, "color", "colored output: auto, on, or off")
+	flag.Var(&display.Unicode, "unicode", "unicode clock: auto, on, or off")
+	flag.IntVar(&display.Width, "width", 0, "fixed terminal width, 0 detects the width")
 	flag.Parse()
 
 	cli.GetTerm().SetVerbose(*verbose)
@@ -100,18 +116,35 @@ func main() {
 
 	var cmds cli.Commands
 	cfg := config.Server()
+	auto := config.AutoWith(display)
+	cfg.NoColor, cfg.ASCII, cfg.Width = auto.NoColor, auto.ASCII, auto.Width
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	if *interactive {
+	if *interactive || *control != "" {
 		cmds = []cli.Command{
 			{Name: "fmt.Print", Key: 'f', Fn: fmtPrint},
 			{Name: "log.Print", Key: 'l', Fn: logPrint},
 			{Name: "zerolog.Print", Key: 'z', Fn: zeroPrint},
+			{Name: "slog+zap.Print", Key: 'S', Fn: slogPrint},
 			{Name: "help", Key: 'h', Fn: help},
+			{Name: "F1 help", Event: cli.KeyEvent{Code: cli.KeyF1}, Fn: help},
 			{Name: "status", Key: 's', Fn: srv.Status},
 			{Name: "print status", Key: 'p', Fn: srv.PrintStatus},
+			{Name: "set log interval", Args: []cli.Arg{{Name: "interval", Type: cli.DurationArg}},
+				Exec: func(ctx context.Context, args cli.Args) error {
+					srv.logInterval = args.Duration("interval")
+					return nil
+				}},
+			{Name: "sleep", Key: 'w', Policy: cli.SingleFlight, Fn: func(ctx context.Context) {
+				select {
+				case <-ctx.Done():
+				case <-time.After(10 * time.Second):
+				}
+			}},
+			cli.JobsCommand(),
+			cli.KillCommand(),
 		}
 		srv.logInterval = time.Second * 10
 
@@ -135,6 +168,9 @@ func main() {
 		} else if *stdLog {
 			cli.SetupLogging(stdlogger.Setup)
 			log.Println("setup stdlog")
+		} else if *useSlog {
+			cli.SetupLogging(slogger.Setup)
+			slog.Info("setup slog")
 		}
 
 	} else {
@@ -143,6 +179,9 @@ func main() {
 		log.Println("setting standard logger to UTC")
 	}
 
+	cfg.ControlAddr = *control
+	cfg.RecordFile = *record
+
 	tctx, tcancel := cli.StartTerm(ctx, cfg, cmds...)
 	defer tcancel()
 
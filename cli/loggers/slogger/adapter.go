@@ -0,0 +1,209 @@
+package slogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// errorKey is the key of error fields of zerolog and zap, which are logged as errors.
+const errorKey = "error"
+
+// lineWriter is an io.Writer that logs each written line as message.
+type lineWriter struct {
+	h     slog.Handler
+	level slog.Level
+}
+
+// LineWriter returns an io.Writer that logs each line written to it as message with
+// the given level, e.g., to route the output of the standard logger to the handler.
+func LineWriter(h slog.Handler, level slog.Level) io.Writer {
+	return &lineWriter{h, level}
+}
+
+func (w *lineWriter) Write(b []byte) (int, error) {
+	ctx := context.Background()
+	if !w.h.Enabled(ctx, w.level) {
+		return len(b), nil
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(b), "\r\n"), "\n") {
+		r := slog.NewRecord(time.Now(), w.level, strings.TrimRight(line, "\r"), 0)
+		if err := w.h.Handle(ctx, r); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// RedirectStdLog routes the output of the standard logger to the handler.
+func RedirectStdLog(h slog.Handler) {
+	log.SetFlags(0)
+	log.SetOutput(LineWriter(h, slog.LevelInfo))
+}
+
+// zerologWriter is an io.Writer that parses zerolog's JSON output.
+type zerologWriter struct {
+	h slog.Handler
+}
+
+// ZerologWriter returns an io.Writer that logs the JSON lines written by a zerolog.Logger.
+func ZerologWriter(h slog.Handler) io.Writer {
+	return &zerologWriter{h}
+}
+
+// Zerolog returns a zerolog.Logger that writes to the handler.
+func Zerolog(h slog.Handler) zerolog.Logger {
+	return zerolog.New(ZerologWriter(h)).With().Timestamp().Logger()
+}
+
+func (w *zerologWriter) Write(b []byte) (int, error) {
+	ctx := context.Background()
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "", 0)
+	var attrs []slog.Attr
+	err := parseJSONObject(b, func(key string, value any) {
+		s, isString := value.(string)
+		switch {
+		case key == zerolog.LevelFieldName && isString:
+			r.Level = zerologLevel(s)
+		case key == zerolog.MessageFieldName && isString:
+			r.Message = s
+		case key == zerolog.TimestampFieldName && isString:
+			if t, err := time.Parse(zerolog.TimeFieldFormat, s); err == nil {
+				r.Time = t
+			}
+		case key == zerolog.ErrorFieldName && isString:
+			attrs = append(attrs, slog.Any(key, errors.New(s)))
+		default:
+			attrs = append(attrs, slog.Any(key, value))
+		}
+	})
+	if err != nil {
+		// not a JSON object, log the raw output
+		r.Message = strings.TrimSpace(string(b))
+	}
+	if !w.h.Enabled(ctx, r.Level) {
+		return len(b), nil
+	}
+	r.AddAttrs(attrs...)
+	return len(b), w.h.Handle(ctx, r)
+}
+
+// parseJSONObject calls fn with the keys and values of a JSON object in their original order.
+func parseJSONObject(b []byte, fn func(key string, value any)) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return errors.New("invalid JSON object")
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := t.(string)
+		var value any
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		fn(key, value)
+	}
+	return nil
+}
+
+func zerologLevel(s string) slog.Level {
+	l, err := zerolog.ParseLevel(s)
+	if err != nil {
+		return slog.LevelInfo
+	}
+	switch l {
+	case zerolog.TraceLevel:
+		return LevelTrace
+	case zerolog.DebugLevel:
+		return slog.LevelDebug
+	case zerolog.WarnLevel:
+		return slog.LevelWarn
+	case zerolog.ErrorLevel:
+		return slog.LevelError
+	case zerolog.FatalLevel, zerolog.PanicLevel:
+		return LevelFatal
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// zapCore is a zapcore.Core that writes to a slog.Handler.
+type zapCore struct {
+	h slog.Handler
+}
+
+// Zap returns a zap.Logger that writes to the handler.
+func Zap(h slog.Handler) *zap.Logger {
+	return zap.New(&zapCore{h})
+}
+
+func (c *zapCore) Enabled(l zapcore.Level) bool {
+	return c.h.Enabled(context.Background(), zapLevel(l))
+}
+
+func (c *zapCore) With(fields []zapcore.Field) zapcore.Core {
+	return &zapCore{c.h.WithAttrs(zapAttrs(fields))}
+}
+
+func (c *zapCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(e.Level) {
+		return ce.AddCore(e, c)
+	}
+	return ce
+}
+
+func (c *zapCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	r := slog.NewRecord(e.Time, zapLevel(e.Level), e.Message, 0)
+	if e.LoggerName != "" {
+		r.AddAttrs(slog.String("logger", e.LoggerName))
+	}
+	r.AddAttrs(zapAttrs(fields)...)
+	return c.h.Handle(context.Background(), r)
+}
+
+func (c *zapCore) Sync() error { return nil }
+
+// zapAttrs converts zap fields to attrs in the order of the fields.
+func zapAttrs(fields []zapcore.Field) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		for key, value := range enc.Fields {
+			if s, ok := value.(string); ok && key == errorKey {
+				value = errors.New(s)
+			}
+			attrs = append(attrs, slog.Any(key, value))
+		}
+	}
+	return attrs
+}
+
+func zapLevel(l zapcore.Level) slog.Level {
+	switch {
+	case l <= zapcore.DebugLevel:
+		return slog.LevelDebug
+	case l == zapcore.InfoLevel:
+		return slog.LevelInfo
+	case l == zapcore.WarnLevel:
+		return slog.LevelWarn
+	case l == zapcore.ErrorLevel:
+		return slog.LevelError
+	default:
+		return LevelFatal
+	}
+}
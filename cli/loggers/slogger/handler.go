@@ -0,0 +1,180 @@
+package slogger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimeFormatHuman is the default time format of the Handler.
+const TimeFormatHuman = time.DateTime
+
+// Additional levels used for the levels of other loggers.
+const (
+	LevelTrace = slog.Level(-8)
+	LevelFatal = slog.Level(12)
+)
+
+const (
+	colorRed      = 31
+	colorGreen    = 32
+	colorYellow   = 33
+	colorMagenta  = 35
+	colorCyan     = 36
+	colorBold     = 1
+	colorDarkGray = 90
+)
+
+// HandlerOptions configures the Handler.
+type HandlerOptions struct {
+	Level      slog.Leveler // minimum level, defaults to slog.LevelInfo
+	TimeFormat string       // defaults to TimeFormatHuman
+	NoColor    bool
+}
+
+// Handler is a slog.Handler that writes colored, human-readable lines like the
+// zerolog.ConsoleWriter. Each record is written using a single Write call, so that
+// the cli.Term can clear the status line before the log line is printed.
+type Handler struct {
+	opts   HandlerOptions
+	mu     *sync.Mutex
+	out    io.Writer
+	attrs  string // preformatted attrs of WithAttrs
+	prefix string // key prefix of WithGroup
+}
+
+var _ slog.Handler = (*Handler)(nil)
+
+// NewHandler returns a Handler that writes to out. Use the global cli.Term as out to
+// avoid log lines mixing with the clock and status line.
+func NewHandler(out io.Writer, opts *HandlerOptions) *Handler {
+	h := &Handler{out: out, mu: &sync.Mutex{}}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.Level == nil {
+		h.opts.Level = slog.LevelInfo
+	}
+	if h.opts.TimeFormat == "" {
+		h.opts.TimeFormat = TimeFormatHuman
+	}
+	return h
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.opts.Level.Level()
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	var sb strings.Builder
+	if !r.Time.IsZero() {
+		sb.WriteString(h.colored(r.Time.Format(h.opts.TimeFormat), colorDarkGray))
+		sb.WriteByte(' ')
+	}
+	sb.WriteString(h.level(r.Level))
+	sb.WriteByte(' ')
+	sb.WriteString(r.Message)
+	sb.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		h.appendAttr(&sb, h.prefix, a)
+		return true
+	})
+	sb.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.out, sb.String())
+	return err
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var sb strings.Builder
+	for _, a := range attrs {
+		h.appendAttr(&sb, h.prefix, a)
+	}
+	c := *h
+	c.attrs += sb.String()
+	return &c
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.prefix += name + "."
+	return &c
+}
+
+// level returns the colored 3-letter level.
+func (h *Handler) level(l slog.Level) string {
+	switch {
+	case l < slog.LevelDebug:
+		return h.colored("TRC", colorMagenta)
+	case l < slog.LevelInfo:
+		return h.colored("DBG", colorYellow)
+	case l < slog.LevelWarn:
+		return h.colored("INF", colorGreen)
+	case l < slog.LevelError:
+		return h.colored("WRN", colorRed)
+	case l < LevelFatal:
+		return h.colored(h.colored("ERR", colorRed), colorBold)
+	default:
+		return h.colored(h.colored("FTL", colorRed), colorBold)
+	}
+}
+
+func (h *Handler) appendAttr(sb *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			h.appendAttr(sb, prefix, ga)
+		}
+		return
+	}
+	sb.WriteByte(' ')
+	sb.WriteString(h.colored(prefix+a.Key+"=", colorCyan))
+	if err, ok := a.Value.Any().(error); ok {
+		sb.WriteString(h.colored(quote(err.Error()), colorRed))
+		return
+	}
+	sb.WriteString(quote(h.value(a.Value)))
+}
+
+func (h *Handler) value(v slog.Value) string {
+	if v.Kind() == slog.KindTime {
+		return v.Time().Format(h.opts.TimeFormat)
+	}
+	return v.String()
+}
+
+// quote quotes strings with spaces or special characters.
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, " =\"\n\r\t") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// colored returns a colored string.
+func (h *Handler) colored(s string, c int) string {
+	if h.opts.NoColor {
+		return s
+	}
+	return fmt.Sprintf("\x1b[%dm%v\x1b[0m", c, s)
+}
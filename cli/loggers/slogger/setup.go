@@ -0,0 +1,22 @@
+package slogger
+
+import (
+	"io"
+	"log/slog"
+
+	"github.com/rs/zerolog/log"
+	"go.uber.org/zap"
+)
+
+// Setup sets up a Handler as default slog handler and routes the output of the standard
+// logger, the global zerolog logger, and the global zap logger to the same handler.
+func Setup(out io.Writer, timeFormat string) error {
+	h := NewHandler(out, &HandlerOptions{TimeFormat: timeFormat, Level: slog.LevelDebug})
+	slog.SetDefault(slog.New(h))
+	RedirectStdLog(h)
+	log.Logger = Zerolog(h)
+	zap.ReplaceGlobals(Zap(h))
+
+	slog.Debug("using slogger.Handler")
+	return nil
+}
@@ -1,12 +1,17 @@
 package zaplogger
 
 import (
-	"fmt"
 	"io"
+	"log/slog"
+
+	"github.com/ubntc/go/cli/loggers/slogger"
+	"go.uber.org/zap"
 )
 
-// Setup sets up a zap CLI logger.
+// Setup sets up a zap CLI logger that writes colored lines using a slogger.Handler.
 func Setup(out io.Writer, timeFormat string) error {
-	// TODO: implement zap CLI logger setup
-	return fmt.Errorf("Setup not implemented")
+	h := slogger.NewHandler(out, &slogger.HandlerOptions{TimeFormat: timeFormat, Level: slog.LevelDebug})
+	zap.ReplaceGlobals(slogger.Zap(h))
+	zap.L().Debug("using zaplogger")
+	return nil
 }
@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/cli/cli"
+)
+
+func TestCommandLineMode(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*500)
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "history")
+	assert.NoError(t, os.WriteFile(path, []byte("flush table old\n"), 0o600))
+	h, err := cli.NewHistory(path)
+	assert.NoError(t, err)
+	cli.GetTerm().SetHistory(h)
+
+	var (
+		mu     sync.Mutex
+		tables []string
+	)
+	cmds := []cli.Command{
+		{Name: "flush table", Key: 'f', Args: []cli.Arg{{Name: "table"}}, Exec: func(ctx context.Context, args cli.Args) error {
+			mu.Lock()
+			defer mu.Unlock()
+			tables = append(tables, args.String("table"))
+			if len(tables) == 3 {
+				cancel()
+			}
+			return nil
+		}},
+	}
+
+	// run a command line, complete a command line, and run a command with args via its key
+	f, remove := TempFile(t, ":flush table x\r:fl\ty\rfz\r")
+	defer remove()
+
+	go cli.ProcessInput(ctx, f, cmds, false)
+
+	<-ctx.Done()
+	assert.Equal(t, context.Canceled, ctx.Err())
+	mu.Lock()
+	assert.ElementsMatch(t, []string{"x", "y", "z"}, tables)
+	mu.Unlock()
+
+	h, err = cli.NewHistory(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"flush table old", "flush table x", "flush table y", "flush table z"}, h.Lines())
+	assert.Contains(t, cli.Commands(cmds).Help(), "Key: 'f', Command: flush table <table>")
+}
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/cli/cli/config"
+)
+
+func TestAuto(t *testing.T) {
+	t.Setenv("TERM", "xterm")
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_CTYPE", "")
+	t.Setenv("LANG", "C")
+	t.Setenv("COLUMNS", "120")
+	t.Setenv(config.EnvWidth, "")
+	t.Setenv(config.EnvColor, "")
+	t.Setenv(config.EnvUnicode, "")
+
+	t.Setenv(config.EnvTTY, "off")
+	cfg := config.Auto()
+	assert.False(t, cfg.ShowClock)
+	assert.True(t, cfg.NoColor)
+	assert.True(t, cfg.ASCII, "the C locale uses ASCII")
+	assert.Equal(t, 0, cfg.Width, "COLUMNS is only a detection hint")
+
+	// env vars override the detection
+	t.Setenv(config.EnvTTY, "on")
+	t.Setenv(config.EnvColor, "true")
+	t.Setenv("LANG", "en_US.UTF-8")
+	t.Setenv(config.EnvWidth, "100")
+	t.Setenv("NO_COLOR", "")
+	cfg = config.Auto()
+	assert.True(t, cfg.ShowClock)
+	assert.True(t, cfg.MakeTermRaw)
+	assert.False(t, cfg.NoColor)
+	assert.False(t, cfg.ASCII)
+	assert.Equal(t, 100, cfg.Width)
+
+	// NO_COLOR and TERM=dumb are ignored if the color is forced
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("TERM", "dumb")
+	cfg = config.Auto()
+	assert.False(t, cfg.NoColor)
+	assert.False(t, cfg.ShowClock, "dumb terminals have no clock")
+	t.Setenv(config.EnvColor, "auto")
+	assert.True(t, config.Auto().NoColor)
+
+	// flags override the env vars
+	var o config.Overrides
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	o.RegisterFlags(fs)
+	assert.NoError(t, fs.Parse([]string{"-tty=off", "-color", "on", "-unicode=0", "-width=40"}))
+	cfg = config.AutoWith(o)
+	assert.Equal(t, config.Server().WithQuit, cfg.WithQuit)
+	assert.False(t, cfg.MakeTermRaw)
+	assert.False(t, cfg.NoColor)
+	assert.True(t, cfg.ASCII)
+	assert.Equal(t, 40, cfg.Width)
+
+	assert.Error(t, fs.Parse([]string{"-color=maybe"}))
+	assert.Equal(t, "auto", config.Mode("").String())
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/cli/cli"
+)
+
+// syncBuffer is a concurrency-safe bytes.Buffer.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestControl(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var flushed []string
+	cli.SetCommands(cli.Commands{
+		{Name: "status", Key: 's', Fn: func(context.Context) { cli.GetTerm().Println("status ok") }},
+		{Name: "flush table", Args: []cli.Arg{{Name: "table"}}, Exec: func(ctx context.Context, args cli.Args) error {
+			flushed = append(flushed, args.String("table"))
+			return nil
+		}},
+	})
+	defer cli.SetCommands(nil)
+
+	addr := "unix:" + filepath.Join(t.TempDir(), "cli.sock")
+	done := make(chan error)
+	go func() { done <- cli.ServeControl(ctx, addr) }()
+
+	c := cli.NewControlClient(addr)
+	var cmds []cli.CommandInfo
+	assert.Eventually(t, func() bool {
+		var err error
+		cmds, err = c.Commands(ctx)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []cli.CommandInfo{
+		{Name: "status", Key: "s", Usage: "status"},
+		{Name: "flush table", Usage: "flush table <table>"},
+	}, cmds)
+
+	logs := &syncBuffer{}
+	logCtx, stopLogs := context.WithCancel(ctx)
+	logsDone := make(chan error)
+	go func() { logsDone <- c.Logs(logCtx, logs) }()
+	time.Sleep(50 * time.Millisecond) // wait for the log subscription
+
+	res, err := c.RunKey(ctx, 's')
+	assert.NoError(t, err)
+	assert.Equal(t, cli.RunResult{Command: "status"}, res)
+	assert.Eventually(t, func() bool { return logs.String() == "status ok\n" }, time.Second, 10*time.Millisecond)
+	stopLogs()
+	assert.NoError(t, <-logsDone)
+
+	res, err = c.RunLine(ctx, "flush table x")
+	assert.NoError(t, err)
+	assert.Equal(t, cli.RunResult{Command: "flush table"}, res)
+	assert.Equal(t, []string{"x"}, flushed)
+
+	res, err = c.RunKey(ctx, 'x')
+	assert.NoError(t, err)
+	assert.Contains(t, res.Error, "Command not found")
+	res, err = c.RunLine(ctx, "flush table")
+	assert.NoError(t, err)
+	assert.Contains(t, res.Error, "missing argument: table")
+
+	jobs, err := c.Jobs(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, jobs)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestControlGuard(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var runs atomic.Int32
+	cli.SetCommands(cli.Commands{{Name: "status", Key: 's', Fn: func(context.Context) { runs.Add(1) }}})
+	defer cli.SetCommands(nil)
+
+	srv := httptest.NewServer(cli.ControlHandler(ctx))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	post := func(host, body string, header map[string]string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/run", strings.NewReader(body))
+		assert.NoError(t, err)
+		req.Host = host
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	header := map[string]string{cli.ControlHeader: "1"}
+
+	assert.Equal(t, http.StatusForbidden, post("localhost:"+port, "key=s", nil), "missing header")
+	assert.Equal(t, http.StatusForbidden, post("evil.example:"+port, "key=s", header), "DNS rebinding")
+	assert.Equal(t, http.StatusForbidden, post("localhost:"+port, "key=s", map[string]string{
+		cli.ControlHeader: "1", "Origin": "http://evil.example",
+	}), "cross-origin")
+	assert.Equal(t, http.StatusBadRequest, post("localhost:"+port, "key=s", header), "form-encoded trigger")
+	assert.Equal(t, int32(0), runs.Load())
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/run?key=s", nil)
+	assert.NoError(t, err)
+	req.Header.Set(cli.ControlHeader, "1")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NoError(t, cli.GetRunner().Wait(time.Second))
+	assert.Equal(t, int32(1), runs.Load())
+}
+
+func TestControlListen(t *testing.T) {
+	_, err := cli.ControlListen("0.0.0.0:0")
+	assert.ErrorIs(t, err, cli.ErrNonLocalAddr)
+	l, err := cli.ControlListen("127.0.0.1:0")
+	assert.NoError(t, err)
+	l.Close()
+}
@@ -2,6 +2,7 @@ package tests
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -40,3 +41,78 @@ func TestProcessInput(t *testing.T) {
 	<-ctx.Done()
 	assert.Equal(t, context.Canceled, ctx.Err())
 }
+
+func TestKeyEvents(t *testing.T) {
+	input := make(chan rune)
+	ch := cli.KeyEvents(input, 20*time.Millisecond)
+
+	for _, r := range "\x1b[A[A" {
+		input <- r
+	}
+	assert.Equal(t, "Up", (<-ch).String())
+	assert.Equal(t, "[", (<-ch).String())
+	assert.Equal(t, "A", (<-ch).String())
+
+	// a single ESC is sent after the timeout
+	input <- 27
+	select {
+	case ev := <-ch:
+		t.Fatalf("unexpected event %s before timeout", ev)
+	case <-time.After(5 * time.Millisecond):
+	}
+	assert.Equal(t, cli.KeyEvent{Code: cli.KeyEscape}.String(), (<-ch).String())
+
+	input <- 27
+	close(input)
+	assert.Equal(t, cli.KeyEscape, (<-ch).Code, "pending ESC is flushed on close")
+	_, more := <-ch
+	assert.False(t, more)
+}
+
+func TestProcessInputEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var (
+		mu  sync.Mutex
+		got []string
+	)
+	record := func(s string) func(context.Context) {
+		return func(context.Context) {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, s)
+		}
+	}
+	done := make(chan struct{})
+	cmds := []cli.Command{
+		{Name: "up", Event: cli.KeyEvent{Code: cli.KeyUp, Mod: cli.ModShift}, Fn: record("up")},
+		{Name: "A", Key: 'A', Fn: record("A")},
+		{Name: "bracket", Key: '[', Fn: record("[")},
+		{Name: "alt x", Event: cli.KeyEvent{Code: cli.KeyRune, Rune: 'x', Mod: cli.ModAlt}, Fn: record("alt x")},
+		{Name: "done", Key: 'd', Fn: func(context.Context) { close(done) }},
+	}
+
+	// Shift+Up, Up, F5, Alt+x, and 'd' must not trigger '[' or 'A'
+	f, remove := TempFile(t, "\x1b[1;2A\x1b[A\x1b[15~\x1bxd")
+	defer remove()
+
+	cli.GetRunner().SetContext(ctx)
+	defer cli.GetRunner().SetContext(nil)
+	go cli.ProcessInput(ctx, f, cmds, false)
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatal("timeout")
+	}
+	assert.NoError(t, cli.GetRunner().Wait(time.Second))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(got) == 2
+	}, time.Second, time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.ElementsMatch(t, []string{"up", "alt x"}, got)
+}
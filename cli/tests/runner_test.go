@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/cli/cli"
+)
+
+func TestRunnerPolicies(t *testing.T) {
+	r := &cli.Runner{}
+	ctx := context.Background()
+	release := make(chan struct{})
+	var calls atomic.Int32
+	block := func(context.Context) {
+		calls.Add(1)
+		<-release
+	}
+
+	single := &cli.Command{Name: "single", Policy: cli.SingleFlight}
+	queue := &cli.Command{Name: "queue", Policy: cli.Queue}
+	parallel := &cli.Command{Name: "parallel"}
+
+	var wg sync.WaitGroup
+	run := func(cmd *cli.Command) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = r.Do(ctx, cmd, block)
+		}()
+	}
+	run(single)
+	run(queue)
+	run(queue)
+	run(parallel)
+	run(parallel)
+
+	assert.Eventually(t, func() bool { return calls.Load() == 4 }, time.Second, time.Millisecond)
+	assert.ErrorIs(t, r.Do(ctx, single, block), cli.ErrAlreadyRunning)
+
+	var names []string
+	for _, e := range r.Running() {
+		names = append(names, e.Name)
+	}
+	assert.ElementsMatch(t, []string{"single", "queue", "parallel", "parallel"}, names, "queued commands are not running")
+
+	assert.ErrorIs(t, r.Wait(10*time.Millisecond), cli.ErrCommandTimeout)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(5), calls.Load())
+	assert.Empty(t, r.Running())
+	assert.NoError(t, r.Wait(time.Second))
+}
+
+func TestRunnerCancelAndPanic(t *testing.T) {
+	r := &cli.Runner{}
+	ctx := context.Background()
+	cmd := &cli.Command{Name: "wait"}
+
+	done := make(chan error)
+	go func() {
+		done <- r.Do(ctx, cmd, func(ctx context.Context) { <-ctx.Done() })
+	}()
+	assert.Eventually(t, func() bool { return len(r.Running()) == 1 }, time.Second, time.Millisecond)
+	assert.NoError(t, r.Shutdown(time.Second), "shutdown cancels running commands")
+	assert.NoError(t, <-done)
+	assert.False(t, r.Cancel(1))
+
+	err := r.Do(ctx, &cli.Command{Name: "bad"}, func(context.Context) { panic("boom") })
+	var p *cli.CommandPanic
+	assert.ErrorAs(t, err, &p)
+	assert.Equal(t, "boom", p.Value)
+	assert.Contains(t, string(p.Stack), "runner_test.go")
+	assert.Contains(t, cli.GetTerm().GetMessage(), "command bad panicked: boom")
+}
+
+func TestCommandsUseRunnerContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runner := cli.GetRunner()
+	runner.SetContext(ctx)
+	defer runner.SetContext(context.Background())
+
+	var err error
+	cmds := cli.Commands{{Name: "ctx", Key: 'x', Fn: func(ctx context.Context) { err = ctx.Err() }}}
+	cancel()
+	assert.NoError(t, cmds.Run('x'))
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/cli/cli"
+	"github.com/ubntc/go/cli/cli/config"
+	"github.com/ubntc/go/cli/vt"
+)
+
+const screenChildEnv = "CLI_SCREEN_CHILD"
+
+func TestScreenChild(t *testing.T) {
+	if os.Getenv(screenChildEnv) == "" {
+		t.Skip("run by TestScreenPTY")
+	}
+	ctx, cancel := cli.StartTerm(context.Background(), config.Interactive(), cli.Command{Name: "print", Key: 'p', Fn: func(context.Context) {
+		cli.GetTerm().Println("out 1\nout 2")
+	}})
+	defer cancel()
+	<-ctx.Done()
+	<-cli.GetShutdownManager().Done()
+}
+
+// TestScreenPTY runs a terminal session in a child process and checks the rendered screen.
+func TestScreenPTY(t *testing.T) {
+	master, slave := openPTY(t)
+	assert.NoError(t, vt.SetSize(master, 80, 10))
+	screen := vt.NewScreen(80, 10).SetRaw(true) // the pty translates NL if needed
+	go func() { _, _ = io.Copy(screen, master) }()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestScreenChild$")
+	cmd.Env = append(os.Environ(), "GORACE=atexit_sleep_ms=0", screenChildEnv+"=1")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	assert.NoError(t, cmd.Start())
+
+	lastLine := func() string {
+		lines := strings.Split(screen.String(), "\n")
+		return lines[len(lines)-1]
+	}
+	waitFor := func(s string) {
+		t.Helper()
+		if !assert.Eventually(t, func() bool { return strings.Contains(lastLine(), s) }, 5*time.Second, 5*time.Millisecond) {
+			t.Fatalf("missing %q in last line of the screen:\n%q", s, screen.Text())
+		}
+	}
+	typeKeys := func(keys string) {
+		t.Helper()
+		_, err := master.WriteString(keys)
+		assert.NoError(t, err)
+	}
+
+	waitFor("Commands:")
+	assert.True(t, isRaw(t, slave))
+
+	typeKeys("p")
+	waitFor("Last command: print ('p')")
+	assert.Contains(t, screen.Lines(), "out 1", "output lines must start in the first column")
+	assert.Contains(t, screen.Lines(), "out 2")
+
+	typeKeys(":pri")
+	waitFor(":pri")
+	typeKeys("\t")
+	waitFor(":print")
+	typeKeys("\r")
+	// the prompt still shows the first command, wait for the output of the second one
+	assert.Eventually(t, func() bool { return strings.Count(screen.Text(), "out 2\n") == 2 }, 5*time.Second, 5*time.Millisecond)
+
+	typeKeys("q")
+	if !assert.NoError(t, cmd.Wait()) {
+		t.Log(screen.Text())
+	}
+	assert.False(t, isRaw(t, slave), "terminal must be restored")
+	for _, line := range strings.Split(screen.Text(), "\n") {
+		assert.False(t, strings.HasPrefix(line, " "), "unexpected indentation of line %q", line)
+	}
+}
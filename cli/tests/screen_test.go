@@ -0,0 +1,35 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/cli/cli"
+	"github.com/ubntc/go/cli/vt"
+)
+
+func TestStatusAreaScreen(t *testing.T) {
+	term, release := cli.AcquireTerm()
+	defer release()
+	screen := vt.NewScreen(100, 6)
+	term.SetOutput(screen)
+	term.SetTTY(true)
+	defer term.SetTTY(false)
+
+	removeA := term.AddWidget(cli.WidgetFunc(func(int) string { return "widget A" }))
+	removeB := term.AddWidget(cli.WidgetFunc(func(int) string { return "widget B" }))
+
+	term.WriteString("log 1\nlog 2\n")
+	assert.Equal(t, "log 1\nlog 2\nwidget A\nwidget B", screen.String())
+
+	removeB()
+	term.WriteString("log 3\n")
+	assert.Equal(t, "log 1\nlog 2\nlog 3\nwidget A", screen.String(), "the status area is replaced")
+
+	term.WriteString("log 4\nlog 5\nlog 6\n")
+	assert.Equal(t, []string{"log 1"}, screen.Scrollback())
+	assert.Equal(t, []string{"log 2", "log 3", "log 4", "log 5", "log 6", "widget A"}, screen.Lines())
+
+	removeA()
+	term.Sync()
+}
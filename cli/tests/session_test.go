@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/cli/cli"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var count atomic.Int32
+	countCmd := cli.Command{Name: "count", Key: 'a', Fn: func(context.Context) {
+		cli.GetTerm().Println(fmt.Sprintf("count %d", count.Add(1)))
+	}}
+
+	buf := &syncBuffer{}
+	rec, err := cli.NewRecorder(buf, 80, 24)
+	assert.NoError(t, err)
+	cli.GetTerm().SetRecorder(rec)
+	defer cli.GetTerm().SetRecorder(nil)
+
+	done := make(chan struct{})
+	f, remove := TempFile(t, "aad")
+	defer remove()
+	go cli.ProcessInput(ctx, f, cli.Commands{countCmd, {Name: "done", Key: 'd', Fn: func(context.Context) { close(done) }}}, false)
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatal("timeout")
+	}
+	assert.NoError(t, cli.GetRunner().Wait(time.Second))
+	cli.GetTerm().SetRecorder(nil)
+	assert.NoError(t, rec.Err())
+
+	cast, err := cli.ReadCast(strings.NewReader(buf.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cast.Header.Version)
+	assert.Equal(t, 80, cast.Header.Width)
+	assert.Equal(t, "aad", cast.Input())
+	var output string
+	for _, e := range cast.Events {
+		if e.Type == cli.CastOutput {
+			output += e.Data
+		}
+	}
+	assert.Contains(t, output, "count 1\n", "the output of all commands is recorded before the session ends")
+	assert.Contains(t, output, "count 2\n")
+
+	// replay the keys with and without timing
+	count.Store(0)
+	assert.NoError(t, cast.Replay(ctx, cli.Commands{countCmd}, 0))
+	assert.Equal(t, int32(2), count.Load())
+	assert.NoError(t, cast.Replay(ctx, cli.Commands{countCmd}, 100))
+	assert.Equal(t, int32(4), count.Load())
+
+	_, err = cli.ReadCast(strings.NewReader(`{"version": 1}`))
+	assert.Error(t, err, "only asciicast v2 is supported")
+}
+
+func TestScript(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var count atomic.Int32
+	cmds := cli.Commands{
+		{Name: "count", Key: 'a', Fn: func(context.Context) {
+			cli.GetTerm().Println(fmt.Sprintf("count %d", count.Add(1)))
+		}},
+		{Name: "up", Event: cli.KeyEvent{Code: cli.KeyUp}, Fn: func(context.Context) {
+			cli.GetTerm().Println("pressed up")
+		}},
+	}
+
+	script, err := cli.ParseScript(`
+		# count to three and press Up
+		repeat 3
+			keys a
+		end
+		expect count 3
+		wait 10ms
+		keys "\x1b[A"
+		expect "pressed up"
+	`)
+	assert.NoError(t, err)
+	assert.NoError(t, script.Run(ctx, cmds))
+	assert.Equal(t, int32(3), count.Load())
+
+	script, err = cli.ParseScript("timeout 50ms\nexpect never")
+	assert.NoError(t, err)
+	err = script.Run(ctx, cmds)
+	assert.ErrorContains(t, err, `line 2: expect "never"`)
+
+	for _, s := range []string{"repeat 2\nkeys a", "end", "unknown", "wait x", "keys", `keys "x`} {
+		_, err := cli.ParseScript(s)
+		assert.Error(t, err, s)
+	}
+}
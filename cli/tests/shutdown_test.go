@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/cli/cli"
+)
+
+func TestShutdownHooks(t *testing.T) {
+	m := &cli.ShutdownManager{}
+	var order []string
+	hook := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			order = append(order, name)
+			return nil
+		}
+	}
+	errFailed := errors.New("failed")
+	m.Add(cli.ShutdownHook{Name: "db", Priority: 2, Fn: hook("db")})
+	m.Add(cli.ShutdownHook{Name: "http", Priority: 1, Fn: hook("http")})
+	m.Add(cli.ShutdownHook{Name: "cache", Priority: 2, Fn: hook("cache")})
+	m.Add(cli.ShutdownHook{Name: "slow", Priority: 3, Timeout: 10 * time.Millisecond, Fn: func(context.Context) error {
+		time.Sleep(time.Second) // ignores the context
+		return nil
+	}})
+	m.Add(cli.ShutdownHook{Name: "panic", Priority: 3, Fn: func(context.Context) error { panic("boom") }})
+	m.Add(cli.ShutdownHook{Name: "fail", Priority: 4, Fn: func(context.Context) error { return errFailed }})
+	m.Add(cli.ShutdownHook{Name: "flush", Priority: 5, Fn: hook("flush")})
+
+	select {
+	case <-m.Done():
+		t.Fatal("done before run")
+	default:
+	}
+
+	start := time.Now()
+	err := m.Run(context.Background())
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, []string{"http", "db", "cache", "flush"}, order)
+	assert.ErrorIs(t, err, cli.ErrHookTimeout)
+	assert.ErrorIs(t, err, errFailed)
+	var p *cli.HookPanic
+	assert.ErrorAs(t, err, &p)
+	assert.Equal(t, "panic", p.Hook)
+	assert.Equal(t, "boom", p.Value)
+	assert.Contains(t, err.Error(), "shutdown hook slow failed")
+	<-m.Done()
+
+	// hooks run only once
+	assert.NoError(t, m.Run(context.Background()))
+	assert.Len(t, order, 4)
+}
+
+func TestShutdownReloadAndDump(t *testing.T) {
+	m := &cli.ShutdownManager{}
+	var reloads int
+	m.OnReload(func() error { reloads++; return nil })
+	m.OnReload(func() error { return errors.New("bad config") })
+	assert.EqualError(t, m.Reload(), "bad config")
+	assert.Equal(t, 1, reloads)
+
+	var buf bytes.Buffer
+	m.SetDumpOutput(&buf)
+	assert.NoError(t, m.Dump())
+	assert.Contains(t, buf.String(), "TestShutdownReloadAndDump")
+}
@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/cli/cli"
+	"github.com/ubntc/go/cli/cli/config"
+	"github.com/ubntc/go/cli/vt"
+	"golang.org/x/sys/unix"
+)
+
+const signalScenarioEnv = "CLI_SIGNAL_SCENARIO"
+
+// openPTY opens a pseudo terminal and returns the master and the slave file.
+func openPTY(t *testing.T) (*os.File, *os.File) {
+	t.Helper()
+	master, slave, err := vt.OpenPTY()
+	if err != nil {
+		t.Skip("pseudo terminals not available:", err)
+	}
+	t.Cleanup(func() {
+		master.Close()
+		slave.Close()
+	})
+	return master, slave
+}
+
+// isRaw returns true if echo and canonical mode of the terminal are disabled.
+func isRaw(t *testing.T, tty *os.File) bool {
+	t.Helper()
+	state, err := unix.IoctlGetTermios(int(tty.Fd()), unix.TCGETS)
+	assert.NoError(t, err)
+	return state.Lflag&(unix.ECHO|unix.ICANON) == 0
+}
+
+// child runs a signal scenario in a child process with the pseudo terminal as stdin.
+type child struct {
+	t   *testing.T
+	cmd *exec.Cmd
+	out *syncBuffer
+	tty *os.File
+}
+
+func startChild(t *testing.T, scenario string) *child {
+	master, slave := openPTY(t)
+	go func() {
+		// drain the terminal echo
+		buf := make([]byte, 1024)
+		for {
+			if _, err := master.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+	out := &syncBuffer{}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSignalChild$", "-test.v")
+	cmd.Env = append(os.Environ(), "GORACE=atexit_sleep_ms=0", signalScenarioEnv+"="+scenario)
+	cmd.Stdin = slave
+	cmd.Stdout = out
+	cmd.Stderr = out
+	assert.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		if t.Failed() {
+			t.Logf("child output:\n%s", out)
+		}
+	})
+	return &child{t, cmd, out, slave}
+}
+
+// waitForRaw waits until the child claimed the terminal.
+func (c *child) waitForRaw() {
+	c.t.Helper()
+	c.waitFor("ready")
+	assert.True(c.t, isRaw(c.t, c.tty), "terminal must be claimed by the child")
+}
+
+func (c *child) waitFor(s string) {
+	c.t.Helper()
+	if !assert.Eventually(c.t, func() bool { return strings.Contains(c.out.String(), s) }, 5*time.Second, 10*time.Millisecond) {
+		c.t.FailNow()
+	}
+}
+
+func (c *child) signal(s os.Signal) {
+	c.t.Helper()
+	assert.NoError(c.t, c.cmd.Process.Signal(s))
+}
+
+// wait waits for the child to exit and returns the exit code.
+func (c *child) wait() int {
+	c.t.Helper()
+	err := c.cmd.Wait()
+	assert.False(c.t, isRaw(c.t, c.tty), "terminal must be restored by the child")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	assert.NoError(c.t, err)
+	return 0
+}
+
+func TestSignalChild(t *testing.T) {
+	scenario := os.Getenv(signalScenarioEnv)
+	if scenario == "" {
+		t.Skip("run by the signal tests")
+	}
+	defer cli.RestoreOnPanic()
+	stdout := os.Stdout // os.Stdout is replaced in raw mode
+
+	m := cli.GetShutdownManager()
+	cli.OnReload(func() error { fmt.Fprintln(stdout, "reloaded"); return nil })
+	for _, name := range []string{"second", "first", "third"} {
+		name := name
+		prio := map[string]int{"first": 1, "second": 2, "third": 3}[name]
+		cli.OnShutdown(cli.ShutdownHook{Name: name, Priority: prio, Fn: func(context.Context) error {
+			fmt.Fprintln(stdout, "hook", name)
+			return nil
+		}})
+	}
+	cli.OnShutdown(cli.ShutdownHook{Name: "panic", Priority: 2, Fn: func(context.Context) error { panic("boom") }})
+	if scenario == "force" {
+		cli.OnShutdown(cli.ShutdownHook{Name: "blocking", Timeout: time.Minute, Fn: func(ctx context.Context) error {
+			fmt.Fprintln(stdout, "hook blocking")
+			<-ctx.Done()
+			return nil
+		}})
+	}
+
+	cfg := config.Server()
+	cfg.MakeTermRaw = true
+	ctx, cancel := cli.StartTerm(context.Background(), cfg, cli.Command{Name: "noop", Key: 'n', Fn: func(context.Context) {}})
+	defer cancel()
+	for !cli.GetTerm().IsRaw() {
+		time.Sleep(time.Millisecond)
+	}
+	fmt.Fprintln(stdout, "ready")
+
+	if scenario == "panic" {
+		panic("main panicked")
+	}
+
+	<-ctx.Done()
+	<-m.Done()
+	fmt.Fprintln(stdout, "stopped")
+}
+
+func TestSignalShutdown(t *testing.T) {
+	c := startChild(t, "hooks")
+	c.waitForRaw()
+	c.signal(syscall.SIGHUP)
+	c.waitFor("reloaded")
+	c.signal(syscall.SIGQUIT)
+	c.waitFor("goroutine ")
+	c.signal(syscall.SIGTERM)
+	assert.Equal(t, 0, c.wait())
+
+	out := c.out.String()
+	assert.Contains(t, out, "stopped")
+	assert.Contains(t, out, "shutdown hook panic panicked: boom")
+	first, second, third := strings.Index(out, "hook first"), strings.Index(out, "hook second"), strings.Index(out, "hook third")
+	assert.True(t, first >= 0 && first < second && second < third, "hooks must run in order:\n%s", out)
+}
+
+func TestSignalForcedExit(t *testing.T) {
+	c := startChild(t, "force")
+	c.waitForRaw()
+	c.signal(os.Interrupt)
+	c.waitFor("hook blocking")
+	c.signal(os.Interrupt)
+	assert.Equal(t, cli.ForcedExitCode, c.wait())
+	assert.Contains(t, c.out.String(), "forced exit")
+	assert.NotContains(t, c.out.String(), "stopped")
+}
+
+func TestSignalPanic(t *testing.T) {
+	c := startChild(t, "panic")
+	c.waitFor("claimed terminal")
+	assert.NotEqual(t, 0, c.wait())
+	assert.Contains(t, c.out.String(), "panic: main panicked")
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	zlog "github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/cli/cli"
+	"github.com/ubntc/go/cli/loggers/slogger"
+	"github.com/ubntc/go/cli/loggers/zaplogger"
+	"go.uber.org/zap"
+)
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := slogger.NewHandler(&buf, &slogger.HandlerOptions{NoColor: true, TimeFormat: "15:04"})
+	logger := slog.New(h).With("app", "test").WithGroup("req")
+
+	logger.Info("handled request", "path", "/a b", slog.Group("user", "id", 1), "err", errors.New("failed"))
+	logger.Debug("skipped")
+	slog.New(h).Warn("")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Regexp(t, `^\d\d:\d\d INF handled request app=test req.path="/a b" req.user.id=1 req.err=failed$`, lines[0])
+	assert.Regexp(t, `^\d\d:\d\d WRN\s*$`, lines[1])
+}
+
+func TestSlogAdapters(t *testing.T) {
+	var buf bytes.Buffer
+	h := slogger.NewHandler(&buf, &slogger.HandlerOptions{NoColor: true, Level: slog.LevelDebug})
+
+	zl := slogger.Zerolog(h)
+	zl.Debug().Str("name", "abc").Int("n", 2).Err(errors.New("bad")).Msg("zerolog message")
+	slogger.Zap(h).Named("svc").Warn("zap message", zap.String("name", "abc"))
+	w := slogger.LineWriter(h, slog.LevelError)
+	_, _ = w.Write([]byte("line 1\nline 2\n"))
+
+	s := buf.String()
+	assert.Contains(t, s, "DBG zerolog message name=abc n=2 error=bad\n")
+	assert.Contains(t, s, "WRN zap message logger=svc name=abc\n")
+	assert.Contains(t, s, "ERR line 1\n")
+	assert.Contains(t, s, "ERR line 2\n")
+}
+
+func TestSlogSetup(t *testing.T) {
+	cli.SetupLogging(slogger.Setup)
+	s := Capture(os.Stderr, func() {
+		slog.Info("slog test", "name", "abc")
+		log.Println("log test")
+		zlog.Info().Msg("zerolog test")
+		zap.L().Info("zap test")
+	})
+	for _, msg := range []string{"slog test", "log test", "zerolog test", "zap test"} {
+		assert.Contains(t, s, msg)
+	}
+	assert.Contains(t, s, "INF")
+	assert.Contains(t, s, "name=")
+}
+
+func TestZapLog(t *testing.T) {
+	cli.SetupLogging(zaplogger.Setup)
+	s := Capture(os.Stderr, func() {
+		zap.L().Info("test", zap.String("name", "abc"))
+	})
+	assert.Contains(t, s, "test", "test message must be logged")
+	assert.Contains(t, s, "abc", "test message must be logged")
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/cli/cli"
+)
+
+func TestWidgets(t *testing.T) {
+	bar := cli.NewProgressBar("load", 200)
+	bar.Add(50)
+	assert.Equal(t, "load [=====>              ]  25% 50/200", bar.Render(40))
+	bar.Set(300)
+	assert.Equal(t, "load [===================] 100% 300/200", bar.Render(40))
+	assert.Equal(t, "load 100% 300/200", bar.Render(10), "bars are omitted if too narrow")
+
+	cnt := cli.NewCounter("rows")
+	cnt.Add(3)
+	assert.Equal(t, "rows 3 (0.0/s)", cnt.Render(80))
+
+	sp := cli.NewSpinner("sync")
+	sp.SetMessage("running")
+	assert.Regexp(t, `^.+ sync running$`, sp.Render(80))
+}
+
+func TestStatusArea(t *testing.T) {
+	term, release := cli.AcquireTerm()
+	defer release()
+	w := &LogWriter{}
+	term.SetOutput(w)
+	term.SetTTY(true)
+	defer term.SetTTY(false)
+
+	removeA := term.AddWidget(cli.WidgetFunc(func(int) string { return "widget A" }))
+	removeB := term.AddWidget(cli.WidgetFunc(func(int) string { return "widget B" }))
+	term.SetStatusHeight(3)
+	defer term.SetStatusHeight(0)
+
+	term.WriteString("log line\n")
+	assert.Contains(t, w.String(), "log line\nwidget A\r\nwidget B\r\n")
+
+	// the next write clears the previous 3 lines of the status area before writing the log line
+	removeB()
+	w.Flush()
+	term.WriteString("next line\n")
+	assert.Equal(t, 2, strings.Count(w.String(), "\x1b[1A"))
+	assert.NotContains(t, w.String(), "widget B")
+
+	removeA()
+	term.Sync()
+}
+
+func TestStatusAreaPlain(t *testing.T) {
+	term, release := cli.AcquireTerm()
+	defer release()
+	w := &LogWriter{}
+	term.SetOutput(w)
+	term.SetTTY(false)
+
+	interval := cli.DefaultPlainInterval
+	cli.DefaultPlainInterval = 10 * time.Millisecond
+	defer func() { cli.DefaultPlainInterval = interval }()
+
+	bar := cli.NewProgressBar("load", 10)
+	remove := term.AddWidget(bar)
+	defer remove()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	term.WriteString("log line\n")
+	assert.NotContains(t, w.String(), "load", "widgets are not rendered as status area without TTY")
+
+	term.StartStatus(ctx)
+	assert.Contains(t, w.String(), "load [")
+	assert.NotContains(t, w.String(), "\x1b[1A")
+}
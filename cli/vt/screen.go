@@ -0,0 +1,283 @@
+// Package vt provides an in-memory VT100 screen for testing terminal output.
+//
+// The Screen interprets the byte stream written to a terminal, so that tests can check
+// what a user actually sees after CRs, clears, and cursor movements.
+package vt
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// DefaultScrollback is the max number of lines kept in the scrollback of a Screen.
+var DefaultScrollback = 1000
+
+// Screen is an in-memory VT100 screen. It implements io.Writer and is safe for concurrent use.
+//
+// Supported are printable characters with auto-wrap, CR, LF, BS, TAB, cursor movement (CUU, CUD,
+// CUF, CUB, CUP, HVP, CHA), erase in display and line (ED, EL), and cursor save and restore.
+// Other escape sequences, such as colors, are ignored.
+type Screen struct {
+	mu         sync.Mutex
+	width      int
+	height     int
+	grid       [][]rune
+	row, col   int
+	wrap       bool // the next character wraps to the next line
+	saved      [2]int
+	raw        bool
+	scrollback []string
+	maxScroll  int
+	seq        []byte // incomplete escape sequence or UTF-8 character
+}
+
+// NewScreen returns a Screen with the given size. Like a terminal in cooked mode,
+// the Screen translates LF to CRLF until SetRaw(true) is called.
+func NewScreen(width, height int) *Screen {
+	s := &Screen{width: width, height: height, maxScroll: DefaultScrollback}
+	s.grid = make([][]rune, height)
+	for i := range s.grid {
+		s.grid[i] = s.blankLine()
+	}
+	return s
+}
+
+// SetRaw enables or disables raw output mode. In raw mode, LF only moves the cursor down.
+func (s *Screen) SetRaw(raw bool) *Screen {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.raw = raw
+	return s
+}
+
+// Size returns the width and height of the Screen.
+func (s *Screen) Size() (width, height int) {
+	return s.width, s.height
+}
+
+// Cursor returns the zero-based cursor position.
+func (s *Screen) Cursor() (row, col int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.row, s.col
+}
+
+// Line returns the visible row without trailing spaces.
+func (s *Screen) Line(row int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return trim(s.grid[row])
+}
+
+// Lines returns the visible rows without trailing spaces.
+func (s *Screen) Lines() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]string, len(s.grid))
+	for i, line := range s.grid {
+		res[i] = trim(line)
+	}
+	return res
+}
+
+// String returns the visible rows without trailing spaces and trailing empty rows.
+func (s *Screen) String() string {
+	lines := s.Lines()
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Scrollback returns the lines that were scrolled out of the Screen, oldest first.
+func (s *Screen) Scrollback() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.scrollback...)
+}
+
+// Text returns the scrollback and the visible rows without trailing empty rows.
+func (s *Screen) Text() string {
+	lines := s.Scrollback()
+	if screen := s.String(); screen != "" {
+		lines = append(lines, screen)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Write interprets the bytes written to the terminal.
+func (s *Screen) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range p {
+		s.writeByte(b)
+	}
+	return len(p), nil
+}
+
+func (s *Screen) writeByte(b byte) {
+	if len(s.seq) > 0 {
+		s.seq = append(s.seq, b)
+		switch {
+		case s.seq[0] == 0x1b:
+			s.escape()
+		case utf8.FullRune(s.seq):
+			r, _ := utf8.DecodeRune(s.seq)
+			s.seq = nil
+			s.put(r)
+		}
+		return
+	}
+	switch {
+	case b == 0x1b || b >= utf8.RuneSelf:
+		s.seq = append(s.seq, b)
+	case b == '\r':
+		s.col, s.wrap = 0, false
+	case b == '\n':
+		if !s.raw {
+			s.col = 0
+		}
+		s.lineFeed()
+	case b == '\b':
+		s.col, s.wrap = max(s.col-1, 0), false
+	case b == '\t':
+		s.col = min((s.col/8+1)*8, s.width-1)
+	case b < 0x20 || b == 0x7f:
+		// ignore other control characters
+	default:
+		s.put(rune(b))
+	}
+}
+
+// put writes a character at the cursor.
+func (s *Screen) put(r rune) {
+	if s.wrap {
+		s.col, s.wrap = 0, false
+		s.lineFeed()
+	}
+	s.grid[s.row][s.col] = r
+	if s.col == s.width-1 {
+		s.wrap = true
+		return
+	}
+	s.col++
+}
+
+// lineFeed moves the cursor down and scrolls at the bottom of the Screen.
+func (s *Screen) lineFeed() {
+	s.wrap = false
+	if s.row < s.height-1 {
+		s.row++
+		return
+	}
+	s.scrollback = append(s.scrollback, trim(s.grid[0]))
+	if n := len(s.scrollback) - s.maxScroll; n > 0 {
+		s.scrollback = s.scrollback[n:]
+	}
+	copy(s.grid, s.grid[1:])
+	s.grid[s.height-1] = s.blankLine()
+}
+
+// escape processes the escape sequence after it is complete.
+func (s *Screen) escape() {
+	seq := s.seq
+	if len(seq) < 2 {
+		return
+	}
+	if (seq[1] == '(' || seq[1] == ')') && len(seq) < 3 {
+		return // charset designation with one more byte
+	}
+	if seq[1] != '[' {
+		s.seq = nil
+		switch seq[1] {
+		case '7':
+			s.saved = [2]int{s.row, s.col}
+		case '8':
+			s.row, s.col, s.wrap = s.saved[0], s.saved[1], false
+		}
+		return
+	}
+	final := seq[len(seq)-1]
+	if len(seq) == 2 || final < 0x40 || final > 0x7e {
+		if len(seq) > 32 {
+			s.seq = nil // drop invalid sequences
+		}
+		return
+	}
+	s.seq = nil
+	params := string(seq[2 : len(seq)-1])
+	if strings.HasPrefix(params, "?") {
+		return // private modes, e.g., cursor visibility
+	}
+	args := strings.Split(params, ";")
+	arg := func(i, def int) int {
+		if i < len(args) {
+			if n, err := strconv.Atoi(args[i]); err == nil && n > 0 {
+				return n
+			}
+		}
+		return def
+	}
+	s.wrap = false
+	switch final {
+	case 'A':
+		s.row = max(s.row-arg(0, 1), 0)
+	case 'B':
+		s.row = min(s.row+arg(0, 1), s.height-1)
+	case 'C':
+		s.col = min(s.col+arg(0, 1), s.width-1)
+	case 'D':
+		s.col = max(s.col-arg(0, 1), 0)
+	case 'G':
+		s.col = min(arg(0, 1), s.width) - 1
+	case 'H', 'f':
+		s.row = min(arg(0, 1), s.height) - 1
+		s.col = min(arg(1, 1), s.width) - 1
+	case 'J':
+		s.eraseDisplay(arg(0, 0))
+	case 'K':
+		s.eraseLine(s.row, arg(0, 0))
+	case 's':
+		s.saved = [2]int{s.row, s.col}
+	case 'u':
+		s.row, s.col = s.saved[0], s.saved[1]
+	}
+}
+
+// eraseLine clears the line from the cursor (0), up to the cursor (1), or completely (2).
+func (s *Screen) eraseLine(row, mode int) {
+	from, to := 0, s.width
+	switch mode {
+	case 0:
+		from = s.col
+	case 1:
+		to = s.col + 1
+	}
+	for i := from; i < to; i++ {
+		s.grid[row][i] = ' '
+	}
+}
+
+// eraseDisplay clears the display from the cursor (0), up to the cursor (1), or completely (2).
+func (s *Screen) eraseDisplay(mode int) {
+	s.eraseLine(s.row, mode)
+	for i := range s.grid {
+		if mode == 2 || (mode == 0 && i > s.row) || (mode == 1 && i < s.row) {
+			s.grid[i] = s.blankLine()
+		}
+	}
+}
+
+func (s *Screen) blankLine() []rune {
+	line := make([]rune, s.width)
+	for i := range line {
+		line[i] = ' '
+	}
+	return line
+}
+
+func trim(line []rune) string {
+	return strings.TrimRight(string(line), " ")
+}
@@ -0,0 +1,71 @@
+package vt_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/cli/vt"
+)
+
+func TestScreenNewlines(t *testing.T) {
+	s := vt.NewScreen(10, 3)
+	fmt.Fprint(s, "a\nb\r\nc")
+	assert.Equal(t, "a\nb\nc", s.String(), "cooked mode translates LF to CRLF")
+
+	s = vt.NewScreen(10, 3).SetRaw(true)
+	fmt.Fprint(s, "a\nb\r\nc")
+	assert.Equal(t, "a\n b\nc", s.String(), "raw mode requires CR")
+	row, col := s.Cursor()
+	assert.Equal(t, []int{2, 1}, []int{row, col})
+}
+
+func TestScreenOverwrite(t *testing.T) {
+	s := vt.NewScreen(10, 2)
+	fmt.Fprint(s, "status 1\rstatus 2\r        \rok")
+	assert.Equal(t, "ok", s.Line(0))
+	fmt.Fprint(s, "\b\bOK\tx")
+	assert.Equal(t, "OK      x", s.Line(0))
+}
+
+func TestScreenWrapAndScroll(t *testing.T) {
+	s := vt.NewScreen(4, 2)
+	fmt.Fprint(s, "abcd")
+	row, col := s.Cursor()
+	assert.Equal(t, []int{0, 3}, []int{row, col}, "wrap is pending at the last column")
+	fmt.Fprint(s, "\r1234efg\nh\ni")
+	assert.Equal(t, "h\ni", s.String())
+	assert.Equal(t, []string{"1234", "efg"}, s.Scrollback())
+	assert.Equal(t, "1234\nefg\nh\ni", s.Text())
+}
+
+func TestScreenEscapes(t *testing.T) {
+	s := vt.NewScreen(10, 4)
+	fmt.Fprint(s, "line 1\nline 2\nline 3")
+	fmt.Fprint(s, "\x1b[1A\r\x1b[K\x1b[31mred\x1b[0m")
+	assert.Equal(t, "line 1\nred\nline 3", s.String())
+
+	fmt.Fprint(s, "\x1b[1;3HX\x1b[2C\x1b[1DY")
+	assert.Equal(t, "liXeY1", s.Line(0))
+	fmt.Fprint(s, "\x1b7\x1b[4;1Hend\x1b8Z")
+	assert.Equal(t, "liXeYZ", s.Line(0))
+	assert.Equal(t, "end", s.Line(3))
+
+	fmt.Fprint(s, "\x1b[2;1H\x1b[J")
+	assert.Equal(t, "liXeYZ", s.String())
+	fmt.Fprint(s, "\x1b[2J\x1b[?25l")
+	assert.Equal(t, "", s.String())
+}
+
+func TestScreenUnicode(t *testing.T) {
+	s := vt.NewScreen(10, 1)
+	b := []byte("⌚ 12:00")
+	for i := range b {
+		_, _ = s.Write(b[i : i+1]) // split UTF-8 characters
+	}
+	assert.Equal(t, "⌚ 12:00", s.Line(0))
+	row, col := s.Cursor()
+	assert.Equal(t, []int{0, 7}, []int{row, col})
+	assert.Equal(t, 10, len([]rune(s.Line(0)+strings.Repeat(" ", 3))))
+}
@@ -0,0 +1,153 @@
+package errchan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AggregateOption configures an Aggregator.
+type AggregateOption interface {
+	apply(*Aggregator)
+}
+
+// MaxGroups caps the number of stored error groups. Errors that would create
+// more groups are only counted as overflow.
+type MaxGroups int
+
+func (opt MaxGroups) apply(a *Aggregator) { a.maxGroups = int(opt) }
+
+type targetOption []error
+
+func (opt targetOption) apply(a *Aggregator) { a.targets = append(a.targets, opt...) }
+
+// ByTarget groups errors by the first target that matches using `errors.Is`.
+// Errors that match no target are grouped by message.
+func ByTarget(targets ...error) AggregateOption {
+	return targetOption(targets)
+}
+
+// ErrorGroup stores the occurrences of similar errors.
+type ErrorGroup struct {
+	Key     string    `json:"key"`     // message or target message
+	Message string    `json:"message"` // message of the first error
+	Type    string    `json:"type"`    // type name of the first error
+	Count   int       `json:"count"`
+	First   time.Time `json:"first"`
+	Last    time.Time `json:"last"`
+}
+
+// Report is a summary of aggregated errors. The groups are sorted by count, most frequent first.
+type Report struct {
+	Total    int          `json:"total"`
+	Overflow int          `json:"overflow"` // errors that were not stored due to MaxGroups
+	Groups   []ErrorGroup `json:"groups"`
+}
+
+// JSON returns the report as JSON object.
+func (r Report) JSON() []byte {
+	res, err := json.Marshal(r)
+	if err != nil {
+		panic("failed to Marshal error report: " + err.Error())
+	}
+	return res
+}
+
+// String returns a line for each group and the overflow if any.
+func (r Report) String() string {
+	lines := make([]string, 0, len(r.Groups)+1)
+	for _, g := range r.Groups {
+		lines = append(lines, fmt.Sprintf("%dx %s (%s)", g.Count, g.Message, g.Type))
+	}
+	if r.Overflow > 0 {
+		lines = append(lines, fmt.Sprintf("%dx other errors", r.Overflow))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Aggregator groups concurrent errors by message or by `errors.Is` target.
+type Aggregator struct {
+	targets   []error
+	maxGroups int
+
+	mu       sync.Mutex
+	groups   map[string]*ErrorGroup
+	order    []*ErrorGroup
+	firsts   []error // first error of each group
+	total    int
+	overflow int
+}
+
+// NewAggregator returns a new Aggregator that groups errors by message by default.
+func NewAggregator(opts ...AggregateOption) *Aggregator {
+	a := &Aggregator{groups: make(map[string]*ErrorGroup)}
+	for _, o := range opts {
+		o.apply(a)
+	}
+	return a
+}
+
+// Add adds an error that occurred now. Nil errors are ignored.
+func (a *Aggregator) Add(err error) { a.AddAt(err, time.Now()) }
+
+// AddAt adds an error that occurred at the given time.
+func (a *Aggregator) AddAt(err error, t time.Time) {
+	if err == nil {
+		return
+	}
+	key := a.key(err)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.total++
+	g, ok := a.groups[key]
+	if !ok {
+		if a.maxGroups > 0 && len(a.order) >= a.maxGroups {
+			a.overflow++
+			return
+		}
+		g = &ErrorGroup{Key: key, Message: err.Error(), Type: fmt.Sprintf("%T", err), First: t, Last: t}
+		a.groups[key] = g
+		a.order = append(a.order, g)
+		a.firsts = append(a.firsts, err)
+	}
+	g.Count++
+	if t.Before(g.First) {
+		g.First = t
+	}
+	if t.After(g.Last) {
+		g.Last = t
+	}
+}
+
+func (a *Aggregator) key(err error) string {
+	for _, target := range a.targets {
+		if errors.Is(err, target) {
+			return target.Error()
+		}
+	}
+	return err.Error()
+}
+
+// Errors returns the first error of each group in the order of their first occurrence.
+func (a *Aggregator) Errors() []error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]error(nil), a.firsts...)
+}
+
+// Report returns a copy of the current aggregation.
+func (a *Aggregator) Report() Report {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	r := Report{Total: a.total, Overflow: a.overflow, Groups: make([]ErrorGroup, len(a.order))}
+	for i, g := range a.order {
+		r.Groups[i] = *g
+	}
+	sort.SliceStable(r.Groups, func(i, j int) bool { return r.Groups[i].Count > r.Groups[j].Count })
+	return r
+}
@@ -0,0 +1,121 @@
+package errchan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestAggregator(t *testing.T) {
+	a := NewAggregator()
+	t0 := time.Now()
+	for i := 0; i < 1000; i++ {
+		a.AddAt(errors.New("timeout"), t0.Add(time.Duration(i)*time.Second))
+	}
+	a.AddAt(io.EOF, t0)
+	a.Add(nil)
+
+	r := a.Report()
+	assert.Equal(t, 1001, r.Total)
+	assert.Len(t, r.Groups, 2)
+	assert.Equal(t, ErrorGroup{
+		Key: "timeout", Message: "timeout", Type: "*errors.errorString",
+		Count: 1000, First: t0, Last: t0.Add(999 * time.Second),
+	}, r.Groups[0])
+	assert.Equal(t, "1000x timeout (*errors.errorString)\n1x EOF (*errors.errorString)", r.String())
+}
+
+func TestAggregatorByTarget(t *testing.T) {
+	a := NewAggregator(ByTarget(os.ErrNotExist, io.EOF), MaxGroups(2))
+	a.Add(&os.PathError{Op: "open", Path: "a", Err: os.ErrNotExist})
+	a.Add(&os.PathError{Op: "open", Path: "b", Err: os.ErrNotExist})
+	a.Add(fmt.Errorf("read: %w", io.EOF))
+	a.Add(errors.New("other 1"))
+	a.Add(errors.New("other 2"))
+
+	r := a.Report()
+	assert.Equal(t, 5, r.Total)
+	assert.Equal(t, 2, r.Overflow)
+	assert.Len(t, r.Groups, 2)
+	assert.Equal(t, os.ErrNotExist.Error(), r.Groups[0].Key)
+	assert.Equal(t, "open a: file does not exist", r.Groups[0].Message)
+	assert.Equal(t, "*fs.PathError", r.Groups[0].Type)
+	assert.Equal(t, 2, r.Groups[0].Count)
+	assert.Equal(t, "*fmt.wrapError", r.Groups[1].Type)
+	assert.Contains(t, r.String(), "2x other errors")
+
+	var decoded Report
+	assert.NoError(t, json.Unmarshal(r.JSON(), &decoded))
+	assert.Equal(t, 2, decoded.Overflow)
+	assert.Equal(t, "*fs.PathError", decoded.Groups[0].Type)
+}
+
+func TestCollectorReport(t *testing.T) {
+	ec, ch := NewGroup()
+	ec.Add(100)
+	for i := 0; i < 100; i++ {
+		go func(i int) {
+			defer ec.Done()
+			ch <- fmt.Errorf("error %d", i%3)
+		}(i)
+	}
+	ec.Wait()
+
+	r := ec.Report()
+	assert.Equal(t, 100, r.Total)
+	assert.Len(t, r.Groups, 3)
+	assert.Equal(t, 34, r.Groups[0].Count)
+	assert.Equal(t, "error 0", r.Groups[0].Key)
+	assert.False(t, r.Groups[0].First.After(r.Groups[0].Last))
+	assert.Len(t, ec.Errors(), 100)
+}
+
+func TestAggregatingCollector(t *testing.T) {
+	ec, ch := NewGroup(MaxGroups(2))
+	ec.Add(100)
+	for i := 0; i < 100; i++ {
+		go func(i int) {
+			defer ec.Done()
+			ch <- fmt.Errorf("error %d", i%10)
+		}(i)
+	}
+	ec.Wait()
+
+	r := ec.Report()
+	assert.Equal(t, 100, r.Total)
+	assert.Equal(t, 80, r.Overflow)
+	assert.Len(t, r.Groups, 2)
+	assert.Len(t, ec.Errors(), 2, "only the first error of each group is stored")
+	assert.Len(t, ec.Strings(), 2)
+}
+
+func TestGroupReport(t *testing.T) {
+	g, _ := WithContext(context.Background(), Aggregate(ByTarget(io.ErrUnexpectedEOF)))
+	for i := 0; i < 10; i++ {
+		g.Go(func(ctx context.Context) error { return fmt.Errorf("read %d: %w", i, io.ErrUnexpectedEOF) })
+	}
+	err := g.Wait()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Len(t, g.Errors(), 1)
+	r := g.Report()
+	assert.Equal(t, 10, r.Groups[0].Count)
+
+	plain, _ := WithContext(context.Background())
+	plain.Go(func(ctx context.Context) error { return io.EOF })
+	plain.Go(func(ctx context.Context) error { return io.EOF })
+	assert.Error(t, plain.Wait())
+	assert.Len(t, plain.Errors(), 2)
+	assert.Equal(t, 2, plain.Report().Groups[0].Count)
+
+	errch, errs := NewChan(2)
+	errs <- io.EOF
+	errs <- io.EOF
+	assert.Equal(t, "2x EOF (*errors.errorString)", errch.Report().String())
+}
@@ -38,11 +38,17 @@ type chanGroup struct {
 type ChanGroup struct{ *chanGroup }
 
 // NewChan returns a new Chan and a write error channel.
+// Use NewGroup to aggregate errors as they arrive.
 func NewChan(size int) (*Chan, chan<- error) {
+	return newChan(size)
+}
+
+// newChan returns a new Chan whose store aggregates the errors if opts are given.
+func newChan(size int, opts ...AggregateOption) (*Chan, chan<- error) {
 	ch := make(chan error, size)
 	return &Chan{
 		errChan:  ch,
-		errStore: newStore(errChan(ch)),
+		errStore: newStore(errChan(ch), opts...),
 	}, ch
 }
 
@@ -53,8 +59,8 @@ func NewChanGroup(size int) (*ChanGroup, chan<- error) {
 }
 
 // newChanGroup returns a new synchronized chanGroup.
-func newChanGroup(size int) *chanGroup {
-	c, _ := NewChan(size)
+func newChanGroup(size int, opts ...AggregateOption) *chanGroup {
+	c, _ := newChan(size, opts...)
 	return &chanGroup{c, sync.WaitGroup{}}
 }
 
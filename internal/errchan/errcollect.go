@@ -1,5 +1,7 @@
 package errchan
 
+import "time"
+
 // Collector collects errors.
 type Collector struct {
 	*chanGroup
@@ -7,23 +9,25 @@ type Collector struct {
 }
 
 // NewGroup creates a new ChanGroup and starts a collector goroutine to collect errors from ChanGroup.C.
-func NewGroup() (*Collector, chan<- error) {
-	g := newChanGroup(10)
+// Given AggregateOptions, the collector aggregates the errors instead of storing them.
+func NewGroup(opts ...AggregateOption) (*Collector, chan<- error) {
+	g := newChanGroup(10, opts...)
 	c := &Collector{chanGroup: g, done: make(chan struct{})}
 	go c.collect()
 	return c, g.errChan
 }
 
-// collect reads errors from the channel into a slice.
+// collect reads errors from the channel into the store.
 func (c *Collector) collect() {
 	defer close(c.done)
-	var errs []error
 	for v := range c.errChan {
-		errs = append(errs, v)
+		c.mu.Lock()
+		c.add(v, time.Now())
+		c.mu.Unlock()
 	}
 	c.mu.Lock()
-	c.errList = errs
-	defer c.mu.Unlock()
+	c.collected = 1 // the channel is already drained
+	c.mu.Unlock()
 }
 
 // Wait returns the error channel.
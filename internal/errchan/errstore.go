@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"strings"
 	"sync"
+	"time"
 )
 
 type errList []error
@@ -15,22 +16,45 @@ type errCollector interface {
 type errStore struct {
 	collector errCollector
 	errList   errList
+	times     []time.Time // optional receive times of the errors
+	agg       *Aggregator // aggregates the errors instead of storing them
 	mu        sync.Mutex
 	collected uint32
 }
 
-func newStore(ec errCollector) *errStore {
-	return &errStore{ec, nil, sync.Mutex{}, 0}
+func newStore(ec errCollector, opts ...AggregateOption) *errStore {
+	s := &errStore{collector: ec}
+	if len(opts) > 0 {
+		s.agg = NewAggregator(opts...)
+	}
+	return s
+}
+
+// add stores an error or adds it to the aggregation. It must be called with s.mu held.
+func (s *errStore) add(err error, t time.Time) {
+	if s.agg != nil {
+		s.agg.AddAt(err, t)
+		return
+	}
+	s.errList = append(s.errList, err)
+	s.times = append(s.times, t)
 }
 
 // Errors processes all errors in the collector once and returns the result as slice.
+// Aggregating stores return the first error of each group.
 func (s *errStore) Errors() []error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if s.collected == 0 {
-		s.errList = s.collector.errors()
+		now := time.Now()
+		for _, err := range s.collector.errors() {
+			s.add(err, now)
+		}
 		s.collected = 1
 	}
+	if s.agg != nil {
+		return s.agg.Errors()
+	}
 	return s.errList
 }
 
@@ -58,3 +82,24 @@ func (s *errStore) JSON() []byte {
 	}
 	return res
 }
+
+// Report returns the aggregation of an aggregating store.
+// Other stores group the stored errors by message.
+func (s *errStore) Report() Report {
+	errs := s.Errors()
+	if s.agg != nil {
+		return s.agg.Report()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return aggregate(errs, s.times)
+}
+
+// aggregate groups the errors received at the given times by message.
+func aggregate(errs []error, times []time.Time) Report {
+	a := NewAggregator()
+	for i, err := range errs {
+		a.AddAt(err, times[i])
+	}
+	return a.Report()
+}
@@ -0,0 +1,164 @@
+package errchan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// GroupOption configures a Group.
+type GroupOption interface {
+	apply(*Group)
+}
+
+// Limit sets the maximum number of concurrently running functions of a Group.
+type Limit int
+
+func (opt Limit) apply(g *Group) {
+	if opt > 0 {
+		g.sem = make(chan struct{}, int(opt))
+	}
+}
+
+// FailFast cancels the context of the Group on the first error.
+type FailFast bool
+
+func (opt FailFast) apply(g *Group) { g.failFast = bool(opt) }
+
+type aggregateOption []AggregateOption
+
+func (opt aggregateOption) apply(g *Group) { g.agg = NewAggregator(opt...) }
+
+// Aggregate aggregates the errors of the Group instead of storing them.
+// Wait and Errors then return the first error of each group.
+func Aggregate(opts ...AggregateOption) GroupOption {
+	return aggregateOption(opts)
+}
+
+// PanicError is returned for functions of a Group that panicked.
+type PanicError struct {
+	Value any    // value passed to panic
+	Stack []byte // stack trace of the panicking goroutine
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Group runs functions concurrently and collects all errors. Unlike `errgroup.Group`, it keeps
+// all errors and converts panics to errors. The zero Group runs all functions without limit
+// using the background context.
+//
+//	g, ctx := errchan.WithContext(ctx, errchan.Limit(4), errchan.FailFast(true))
+//	for _, job := range jobs {
+//	    job := job
+//	    g.Go(func(ctx context.Context) error { return job.Run(ctx) })
+//	}
+//	err := g.Wait() // returns all errors joined, supports errors.Is and errors.As
+type Group struct {
+	ctx      context.Context
+	cancel   context.CancelCauseFunc
+	sem      chan struct{}
+	failFast bool
+	agg      *Aggregator
+
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+	errs  []error
+	times []time.Time
+}
+
+// WithContext returns a new Group and a derived context that is canceled when Wait returns or,
+// using FailFast, when the first function fails.
+func WithContext(ctx context.Context, opts ...GroupOption) (*Group, context.Context) {
+	g := &Group{}
+	for _, o := range opts {
+		o.apply(g)
+	}
+	g.ctx, g.cancel = context.WithCancelCause(ctx)
+	return g, g.ctx
+}
+
+// Go calls f in a new goroutine. It blocks until the number of running functions is below the limit.
+func (g *Group) Go(f func(ctx context.Context) error) {
+	ctx := g.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if g.sem != nil {
+			defer func() { <-g.sem }()
+		}
+		if err := run(ctx, f); err != nil {
+			g.add(err)
+		}
+	}()
+}
+
+// run calls f and converts panics to a PanicError.
+func run(ctx context.Context, f func(ctx context.Context) error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return f(ctx)
+}
+
+func (g *Group) add(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.agg != nil {
+		g.agg.Add(err)
+	} else {
+		g.errs = append(g.errs, err)
+		g.times = append(g.times, time.Now())
+	}
+	if g.failFast && g.cancel != nil {
+		g.cancel(err)
+	}
+}
+
+// Wait blocks until all functions returned and returns all errors joined using `errors.Join`.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel(nil)
+	}
+	return errors.Join(g.Errors()...)
+}
+
+// Errors returns the errors collected so far.
+func (g *Group) Errors() []error {
+	if g.agg != nil {
+		return g.agg.Errors()
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]error(nil), g.errs...)
+}
+
+// Report returns the aggregation of an aggregating Group.
+// Other Groups group the errors collected so far by message.
+func (g *Group) Report() Report {
+	if g.agg != nil {
+		return g.agg.Report()
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return aggregate(g.errs, g.times)
+}
@@ -0,0 +1,78 @@
+package errchan
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestGroup(t *testing.T) {
+	g, ctx := WithContext(context.Background())
+	g.Go(func(ctx context.Context) error { return io.EOF })
+	g.Go(func(ctx context.Context) error { return &os.PathError{Op: "open", Path: "x", Err: os.ErrNotExist} })
+	g.Go(func(ctx context.Context) error { return nil })
+
+	err := g.Wait()
+	assert.ErrorIs(t, err, io.EOF)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	var pathErr *os.PathError
+	assert.ErrorAs(t, err, &pathErr)
+	assert.Len(t, g.Errors(), 2)
+	assert.ErrorIs(t, ctx.Err(), context.Canceled, "Wait cancels the context")
+}
+
+func TestGroupFailFast(t *testing.T) {
+	g, ctx := WithContext(context.Background(), FailFast(true))
+	g.Go(func(ctx context.Context) error { return io.EOF })
+	g.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	err := g.Wait()
+	assert.ErrorIs(t, err, io.EOF)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, context.Cause(ctx), io.EOF)
+}
+
+func TestGroupLimit(t *testing.T) {
+	var running, max int32
+	g, _ := WithContext(context.Background(), Limit(3))
+	for i := 0; i < 20; i++ {
+		g.Go(func(ctx context.Context) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			return nil
+		})
+	}
+	assert.NoError(t, g.Wait())
+	assert.Equal(t, int32(3), max)
+}
+
+func TestGroupPanic(t *testing.T) {
+	var g Group // zero value
+	g.Go(func(ctx context.Context) error { panic("boom") })
+	g.Go(func(ctx context.Context) error { panic(io.ErrUnexpectedEOF) })
+
+	err := g.Wait()
+	var p *PanicError
+	assert.ErrorAs(t, err, &p)
+	assert.Contains(t, string(p.Stack), "group_test.go")
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF, "error values of panics are unwrapped")
+	assert.Contains(t, err.Error(), "panic: boom")
+
+	assert.False(t, errors.Is(new(Group).Wait(), io.EOF))
+}
@@ -1,6 +1,7 @@
 package errchan
 
 import (
+	"context"
 	"errors"
 	"math/rand"
 	"sync"
@@ -88,6 +89,25 @@ func benchmarkErrorGroup(b *testing.B, factor int) {
 	}
 }
 
+func benchmarkErrorGroupLimit(b *testing.B, factor, limit int) {
+	for n := 0; n < b.N; n++ {
+		size := numWorkers(factor)
+		errRate := errorRate()
+		var grp = errgroup.Group{}
+		grp.SetLimit(limit)
+		for i := 0; i < size; i++ {
+			i := i
+			grp.Go(func() error {
+				if i%errRate == 0 {
+					return errors.New("test")
+				}
+				return nil
+			})
+		}
+		grp.Wait()
+	}
+}
+
 func benchmarkErrChan(b *testing.B, factor int) {
 	for n := 0; n < b.N; n++ {
 		size := numWorkers(factor)
@@ -161,6 +181,24 @@ func benchmarkErrPool(b *testing.B, factor int) {
 	}
 }
 
+func benchmarkGroup(b *testing.B, factor int, opts ...GroupOption) {
+	for n := 0; n < b.N; n++ {
+		size := numWorkers(factor)
+		errRate := errorRate()
+		grp, _ := WithContext(context.Background(), opts...)
+		for i := 0; i < size; i++ {
+			i := i
+			grp.Go(func(ctx context.Context) error {
+				if i%errRate == 0 {
+					return errors.New("test")
+				}
+				return nil
+			})
+		}
+		grp.Wait()
+	}
+}
+
 func BenchmarkMultiErrorS(b *testing.B) { benchmarkMultiError(b, small) }
 func BenchmarkMultiErrorM(b *testing.B) { benchmarkMultiError(b, big) }
 
@@ -170,6 +208,15 @@ func BenchmarkMultiErrB(b *testing.B) { benchmarkMultiErr(b, big) }
 func BenchmarkErrorGroupA(b *testing.B) { benchmarkErrorGroup(b, small) }
 func BenchmarkErrorGroupB(b *testing.B) { benchmarkErrorGroup(b, big) }
 
+func BenchmarkGroupA(b *testing.B) { benchmarkGroup(b, small) }
+func BenchmarkGroupB(b *testing.B) { benchmarkGroup(b, big) }
+
+func BenchmarkGroupLimitA(b *testing.B) { benchmarkGroup(b, small, Limit(4)) }
+func BenchmarkGroupLimitB(b *testing.B) { benchmarkGroup(b, big, Limit(4)) }
+
+func BenchmarkErrorGroupLimitA(b *testing.B) { benchmarkErrorGroupLimit(b, small, 4) }
+func BenchmarkErrorGroupLimitB(b *testing.B) { benchmarkErrorGroupLimit(b, big, 4) }
+
 func BenchmarkErrChanA(b *testing.B) { benchmarkErrChan(b, small) }
 func BenchmarkErrChanB(b *testing.B) { benchmarkErrChan(b, big) }
 
@@ -0,0 +1,371 @@
+package buffertest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// BenchConfig configures the benchmark mode.
+type BenchConfig struct {
+	Enabled     bool
+	Rate        int           // target rate of all producers in msgs/sec, 0 means unlimited
+	PayloadSize int           // size of the event payload in bytes
+	Producers   int           // number of parallel producers
+	Consumers   int           // number of parallel consumers
+	Warmup      time.Duration // events produced during the warmup are not measured
+	Duration    time.Duration // duration of the measure phase
+	Drain       time.Duration // max wait time for pending events after producing
+	Interval    time.Duration // interval of the throughput timeline
+}
+
+// WithDefaults copies the config by value, sets missing defaults values returns the copy.
+func (cfg BenchConfig) WithDefaults() BenchConfig {
+	if cfg.Producers <= 0 {
+		cfg.Producers = 1
+	}
+	if cfg.Consumers <= 0 {
+		cfg.Consumers = 1
+	}
+	if cfg.Duration <= 0 {
+		cfg.Duration = 10 * time.Second
+	}
+	if cfg.Drain <= 0 {
+		cfg.Drain = 5 * time.Second
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	return cfg
+}
+
+// LatencyStats contains end-to-end latencies in milliseconds.
+type LatencyStats struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean_ms"`
+	P50   float64 `json:"p50_ms"`
+	P90   float64 `json:"p90_ms"`
+	P95   float64 `json:"p95_ms"`
+	P99   float64 `json:"p99_ms"`
+	Max   float64 `json:"max_ms"`
+}
+
+// ThroughputSample contains the rates of an interval of the benchmark.
+type ThroughputSample struct {
+	Elapsed  float64 `json:"elapsed_s"`
+	Produced float64 `json:"produced_per_s"`
+	Consumed float64 `json:"consumed_per_s"`
+}
+
+// BenchResult is the result of a benchmark run.
+type BenchResult struct {
+	Provider   string             `json:"provider"`
+	Settings   Map                `json:"settings"`
+	Produced   int                `json:"produced"` // events produced in the measure phase
+	Consumed   int                `json:"consumed"` // events of the measure phase that were consumed
+	Throughput float64            `json:"throughput_per_s"`
+	Latency    LatencyStats       `json:"latency"`
+	Timeline   []ThroughputSample `json:"timeline"`
+	Report     Report             `json:"report"`
+}
+
+// JSON returns the result as indented JSON.
+func (r BenchResult) JSON() []byte {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		panic("failed to Marshal bench result: " + err.Error())
+	}
+	return b
+}
+
+// Bench produces events at the target rate and measures the end-to-end latency of the consumed
+// events using their embedded timestamps.
+func Bench(cfg Config) (BenchResult, error) {
+	bc := cfg.Bench.WithDefaults()
+	broker, err := NewBroker(cfg)
+	if err != nil {
+		return BenchResult{}, err
+	}
+	defer broker.Close()
+
+	if broker.Name() == ProviderPebble && bc.Consumers > 1 {
+		// kstore readers have no consumer groups and would read all events
+		log.Warn().Int("consumers", bc.Consumers).Msg("pebble supports a single consumer, using 1")
+		bc.Consumers = 1
+	}
+
+	b := &bench{
+		cfg:      cfg,
+		bc:       bc,
+		broker:   broker,
+		verifier: NewVerifier(uuid.NewString()),
+		payload:  strings.Repeat("x", bc.PayloadSize),
+	}
+	err = b.run()
+
+	res := b.result()
+	res.Provider = broker.Name()
+	res.Settings = Map{
+		"rate":           bc.Rate,
+		"payload_size":   bc.PayloadSize,
+		"producers":      bc.Producers,
+		"consumers":      bc.Consumers,
+		"warmup":         bc.Warmup.String(),
+		"duration":       bc.Duration.String(),
+		"batch_timeout":  cfg.Writer.BatchTimeout.String(),
+		"batch_size":     cfg.Writer.BatchSize,
+		"queue_capacity": cfg.Reader.QueueCapacity,
+		"partitions":     cfg.Topic.NumPartitions,
+	}
+	return res, err
+}
+
+type bench struct {
+	cfg      Config
+	bc       BenchConfig
+	broker   Broker
+	verifier *Verifier
+	payload  string
+
+	start        time.Time
+	measureStart time.Time
+	measureEnd   time.Time
+
+	produced atomic.Int64 // all produced events
+	consumed atomic.Int64 // all consumed events
+
+	mu        sync.Mutex
+	measured  int             // produced events of the measure phase
+	latencies []time.Duration // latencies of consumed events of the measure phase
+	timeline  []ThroughputSample
+}
+
+func (b *bench) inMeasurePhase(t time.Time) bool {
+	return !t.Before(b.measureStart) && t.Before(b.measureEnd)
+}
+
+func (b *bench) run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := b.broker.CreateTopic(ctx); err != nil {
+		return errors.Wrap(err, "failed to create topics")
+	}
+
+	// stop and wait for all goroutines before closing their consumers and producers,
+	// also if the benchmark fails early
+	var (
+		workers   sync.WaitGroup // consumers and sampler
+		producers sync.WaitGroup
+		closers   []func() error
+	)
+	defer func() {
+		cancel()
+		producers.Wait()
+		workers.Wait()
+		for _, closeFn := range closers {
+			_ = closeFn()
+		}
+	}()
+
+	for i := 0; i < b.bc.Consumers; i++ {
+		r, err := b.broker.NewConsumer()
+		if err != nil {
+			return errors.Wrap(err, "failed to create consumer")
+		}
+		closers = append(closers, r.Close)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			b.consume(ctx, r)
+		}()
+	}
+
+	b.start = time.Now()
+	b.measureStart = b.start.Add(b.bc.Warmup)
+	b.measureEnd = b.measureStart.Add(b.bc.Duration)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		b.sample(ctx)
+	}()
+
+	log.Info().Interface("bench", Map{
+		"warmup":   b.bc.Warmup.String(),
+		"duration": b.bc.Duration.String(),
+		"rate":     b.bc.Rate,
+	}).Msg("start benchmark")
+
+	errs := make(chan error, b.bc.Producers)
+	for i := 0; i < b.bc.Producers; i++ {
+		w, err := b.broker.NewProducer()
+		if err != nil {
+			return errors.Wrap(err, "failed to create producer")
+		}
+		closers = append(closers, w.Close)
+		producers.Add(1)
+		go func(id int) {
+			defer producers.Done()
+			errs <- b.produce(ctx, w, id)
+		}(i)
+	}
+	producers.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return errors.Wrap(err, "failed to produce events")
+		}
+	}
+
+	// wait for pending events
+	drain, stop := context.WithTimeout(ctx, b.bc.Drain)
+	defer stop()
+	err := waitForEvents(drain, b.verifier, int(b.produced.Load()), 10*time.Millisecond)
+	cancel()
+	workers.Wait()
+	return err
+}
+
+// produce sends events at the producer's share of the target rate until the measure phase ends.
+func (b *bench) produce(ctx context.Context, w Producer, id int) error {
+	var interval time.Duration
+	if b.bc.Rate > 0 {
+		interval = time.Duration(float64(time.Second) * float64(b.bc.Producers) / float64(b.bc.Rate))
+	}
+	numKeys := b.cfg.NumKeys
+	if numKeys <= 0 {
+		numKeys = 1
+	}
+	for i := 0; ; i++ {
+		if interval > 0 {
+			next := b.start.Add(time.Duration(i) * interval)
+			time.Sleep(time.Until(next))
+		}
+		now := time.Now()
+		if !now.Before(b.measureEnd) {
+			return nil
+		}
+
+		// keys are not shared between producers to keep the per-key order
+		key := fmt.Sprintf("p%d-key-%d", id, i%numKeys)
+		e := b.verifier.Next(key, b.cfg.Topic.Topic, uuid.NewString())
+		e.Payload = b.payload
+		value, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if err := w.Produce(ctx, Message{Key: []byte(key), Value: value}); err != nil {
+			return err
+		}
+		b.produced.Add(1)
+		if b.inMeasurePhase(e.Timestamp) {
+			b.mu.Lock()
+			b.measured++
+			b.mu.Unlock()
+		}
+	}
+}
+
+func (b *bench) consume(ctx context.Context, r Consumer) {
+	for {
+		msg, err := r.Fetch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("failed to read message")
+			return
+		}
+		now := time.Now()
+		if e, ok := b.verifier.Consume(msg); ok {
+			b.consumed.Add(1)
+			if b.inMeasurePhase(e.Timestamp) {
+				b.mu.Lock()
+				b.latencies = append(b.latencies, now.Sub(e.Timestamp))
+				b.mu.Unlock()
+			}
+		}
+		if err := r.Commit(ctx, msg); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("failed to commit message")
+			return
+		}
+	}
+}
+
+// sample records the throughput timeline.
+func (b *bench) sample(ctx context.Context) {
+	tick := time.NewTicker(b.bc.Interval)
+	defer tick.Stop()
+	var lastProduced, lastConsumed int64
+	last := b.start
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-tick.C:
+			p, c := b.produced.Load(), b.consumed.Load()
+			dt := t.Sub(last).Seconds()
+			b.mu.Lock()
+			b.timeline = append(b.timeline, ThroughputSample{
+				Elapsed:  math.Round(t.Sub(b.start).Seconds()*1000) / 1000,
+				Produced: math.Round(float64(p-lastProduced)/dt*100) / 100,
+				Consumed: math.Round(float64(c-lastConsumed)/dt*100) / 100,
+			})
+			b.mu.Unlock()
+			log.Info().Int64("produced", p).Int64("consumed", c).Msg("bench progress")
+			lastProduced, lastConsumed, last = p, c, t
+		}
+	}
+}
+
+func (b *bench) result() BenchResult {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	res := BenchResult{
+		Produced: b.measured,
+		Consumed: len(b.latencies),
+		Latency:  latencyStats(b.latencies),
+		Timeline: b.timeline,
+		Report:   b.verifier.Report(),
+	}
+	if d := b.bc.Duration.Seconds(); d > 0 {
+		res.Throughput = float64(res.Consumed) / d
+	}
+	return res
+}
+
+func latencyStats(latencies []time.Duration) LatencyStats {
+	n := len(latencies)
+	if n == 0 {
+		return LatencyStats{}
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	quantile := func(q float64) float64 {
+		return ms(sorted[int(math.Ceil(q*float64(n)))-1])
+	}
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	return LatencyStats{
+		Count: n,
+		Mean:  ms(sum / time.Duration(n)),
+		P50:   quantile(0.5),
+		P90:   quantile(0.9),
+		P95:   quantile(0.95),
+		P99:   quantile(0.99),
+		Max:   ms(sorted[n-1]),
+	}
+}
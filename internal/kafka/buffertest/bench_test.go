@@ -0,0 +1,104 @@
+package buffertest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyStats(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i > 0; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	s := latencyStats(latencies)
+	assert.Equal(t, LatencyStats{Count: 100, Mean: 50.5, P50: 50, P90: 90, P95: 95, P99: 99, Max: 100}, s)
+	assert.Equal(t, LatencyStats{}, latencyStats(nil))
+}
+
+func TestBenchPebble(t *testing.T) {
+	cfg := Config{
+		Provider: ProviderPebble,
+		Dir:      t.TempDir(),
+		Topic:    kafka.TopicConfig{Topic: "buffertest"},
+		NumKeys:  3,
+		Bench: BenchConfig{
+			Rate:        500,
+			PayloadSize: 10,
+			Producers:   2,
+			Consumers:   2, // reduced to 1 for pebble
+			Warmup:      100 * time.Millisecond,
+			Duration:    300 * time.Millisecond,
+			Interval:    100 * time.Millisecond,
+		},
+	}
+	res, err := Bench(cfg)
+	assert.NoError(t, err)
+	assert.NoError(t, res.Report.Err())
+	assert.Equal(t, 1, res.Settings["consumers"])
+	assert.Greater(t, res.Produced, 0)
+	assert.LessOrEqual(t, res.Produced, 160, "the rate limits the produced events")
+	assert.Equal(t, res.Produced, res.Consumed)
+	assert.Equal(t, res.Consumed, res.Latency.Count)
+	assert.Greater(t, res.Latency.P99, 0.0)
+	assert.NotEmpty(t, res.Timeline)
+
+	var decoded map[string]any
+	assert.NoError(t, json.Unmarshal(res.JSON(), &decoded))
+	assert.Contains(t, decoded, "latency")
+}
+
+// failingProducers is a broker that cannot create producers and tracks running fetches.
+type failingProducers struct {
+	Broker
+	fetching, closedWhileFetching *atomic.Int32
+}
+
+// NewProducer fails after both consumers are fetching.
+func (b failingProducers) NewProducer() (Producer, error) {
+	for b.fetching.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	return nil, errors.New("no producer")
+}
+
+func (b failingProducers) NewConsumer() (Consumer, error) {
+	c, err := b.Broker.NewConsumer()
+	return trackedConsumer{c, b.fetching, b.closedWhileFetching}, err
+}
+
+type trackedConsumer struct {
+	Consumer
+	fetching, closedWhileFetching *atomic.Int32
+}
+
+func (c trackedConsumer) Close() error {
+	c.closedWhileFetching.Add(c.fetching.Load())
+	return c.Consumer.Close()
+}
+
+// Fetch blocks until the context is canceled.
+func (c trackedConsumer) Fetch(ctx context.Context) (Message, error) {
+	c.fetching.Add(1)
+	defer c.fetching.Add(-1)
+	<-ctx.Done()
+	return Message{}, ctx.Err()
+}
+
+func TestBenchStopsOnError(t *testing.T) {
+	var fetching, closedWhileFetching atomic.Int32
+	b := &bench{
+		bc:       BenchConfig{Consumers: 2}.WithDefaults(),
+		broker:   failingProducers{newMemoryBroker(2), &fetching, &closedWhileFetching},
+		verifier: NewVerifier("run"),
+	}
+	assert.ErrorContains(t, b.run(), "failed to create producer")
+	assert.Equal(t, int32(0), closedWhileFetching.Load(), "consumers must be stopped before they are closed")
+	assert.Equal(t, int32(0), fetching.Load(), "consumers must be stopped before run returns")
+}
@@ -0,0 +1,61 @@
+package buffertest
+
+import (
+	"context"
+	"fmt"
+)
+
+// Message is a raw message with its delivery metadata.
+type Message struct {
+	Key       []byte
+	Value     []byte
+	Partition int
+	Offset    int64
+
+	raw any // provider message used for committing
+}
+
+// Producer writes messages to the test topic.
+type Producer interface {
+	Produce(ctx context.Context, msgs ...Message) error
+	Close() error
+}
+
+// Consumer reads messages from the test topic.
+type Consumer interface {
+	// Fetch blocks until the next message is available or the context is done.
+	Fetch(ctx context.Context) (Message, error)
+	// Commit marks the message as consumed.
+	Commit(ctx context.Context, msg Message) error
+	Close() error
+}
+
+// Broker creates the test topic, producers, and consumers.
+type Broker interface {
+	Name() string
+	CreateTopic(ctx context.Context) error
+	NewProducer() (Producer, error)
+	NewConsumer() (Consumer, error)
+	Close() error
+}
+
+// Providers supported by NewBroker.
+const (
+	ProviderKafka  = "kafka"
+	ProviderPebble = "pebble"
+	ProviderMemory = "memory"
+)
+
+// NewBroker returns the Broker of the configured provider.
+func NewBroker(cfg Config) (Broker, error) {
+	switch cfg.Provider {
+	case ProviderKafka, "":
+		return newKafkaBroker(cfg), nil
+	case ProviderPebble:
+		return newPebbleBroker(cfg), nil
+	case ProviderMemory:
+		return newMemoryBroker(cfg.Topic.NumPartitions), nil
+	default:
+		return nil, fmt.Errorf("unknown provider: %s", cfg.Provider)
+	}
+}
@@ -0,0 +1,499 @@
+package buffertest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/ubntc/go/kstore/kstore"
+)
+
+// Scenario actions.
+const (
+	ActionKill         = "kill"          // abruptly stops a consumer, dropping its uncommitted events
+	ActionRestart      = "restart"       // kills a consumer and starts a new one in its slot
+	ActionAdd          = "add"           // adds a consumer, which forces a rebalance
+	ActionCancelCommit = "cancel-commit" // cancels the context of the next commit of a consumer
+)
+
+// Duration is a time.Duration that is read from and written to JSON as string, e.g., "500ms".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	*d = Duration(v)
+	return err
+}
+
+// Step is a chaos action that is executed at the given offset after the start of a scenario.
+type Step struct {
+	At       Duration `json:"at"`
+	Action   string   `json:"action"`
+	Consumer int      `json:"consumer"` // consumer slot, ignored by "add"
+}
+
+// Scenario defines a scripted chaos test. The events are produced at the given rate while the
+// steps are executed. Consumers commit each event after handling it, so events must never go
+// missing, but duplicates are expected.
+type Scenario struct {
+	Name          string   `json:"name"`
+	Events        int      `json:"events"`
+	Keys          int      `json:"keys"`
+	Rate          int      `json:"rate"` // events per second, 0 means unlimited
+	Partitions    int      `json:"partitions"`
+	Consumers     int      `json:"consumers"`                // initial number of consumers
+	ProcessTime   Duration `json:"process_time"`             // simulated work per event before the commit
+	Drain         Duration `json:"drain"`                    // max wait for pending events
+	Settle        Duration `json:"settle"`                   // idle time of the consumers required to finish
+	Check         Duration `json:"check"`                    // read time of the final offset check
+	MaxDuplicates *int     `json:"max_duplicates,omitempty"` // fails the scenario if exceeded
+	Steps         []Step   `json:"steps"`
+}
+
+// WithDefaults returns a copy of the scenario with defaults for all unset values.
+func (sc Scenario) WithDefaults() Scenario {
+	if sc.Events <= 0 {
+		sc.Events = 100
+	}
+	if sc.Keys <= 0 {
+		sc.Keys = 3
+	}
+	if sc.Partitions <= 0 {
+		sc.Partitions = 4
+	}
+	if sc.Consumers <= 0 {
+		sc.Consumers = 1
+	}
+	if sc.Drain <= 0 {
+		sc.Drain = Duration(10 * time.Second)
+	}
+	if sc.Settle <= 0 {
+		sc.Settle = Duration(200 * time.Millisecond)
+	}
+	if sc.Check <= 0 {
+		sc.Check = Duration(time.Second)
+	}
+	return sc
+}
+
+func (sc Scenario) validate() error {
+	if sc.Name == "" {
+		return errors.New("scenario without name")
+	}
+	for i, s := range sc.Steps {
+		switch s.Action {
+		case ActionKill, ActionRestart, ActionAdd, ActionCancelCommit:
+		default:
+			return fmt.Errorf("scenario %s: step %d: unknown action %q", sc.Name, i, s.Action)
+		}
+	}
+	return nil
+}
+
+// LoadScenarios reads a JSON array of scenarios from a file.
+func LoadScenarios(path string) ([]Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var scenarios []Scenario
+	if err := json.Unmarshal(data, &scenarios); err != nil {
+		return nil, fmt.Errorf("failed to parse scenarios file %s: %w", path, err)
+	}
+	for _, sc := range scenarios {
+		if err := sc.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return scenarios, nil
+}
+
+// ScenarioResult is the result of a scenario.
+type ScenarioResult struct {
+	Name         string   `json:"name"`
+	Passed       bool     `json:"passed"`
+	Report       Report   `json:"report"`
+	Uncommitted  int      `json:"uncommitted"` // events redelivered to a new consumer after the scenario
+	CommitErrors int      `json:"commit_errors"`
+	MaxDupes     *int     `json:"max_duplicates,omitempty"`
+	Consumers    int      `json:"consumers"` // number of started consumers
+	Errors       []string `json:"errors,omitempty"`
+}
+
+// Err returns an error if the scenario failed.
+func (r ScenarioResult) Err() error {
+	var errs []error
+	for _, e := range r.Errors {
+		errs = append(errs, errors.New(e))
+	}
+	if r.Report.Missing > 0 {
+		errs = append(errs, fmt.Errorf("%w: %d %v", ErrMissing, r.Report.Missing, r.Report.Samples))
+	}
+	if r.Report.OutOfOrder > 0 {
+		errs = append(errs, fmt.Errorf("%w: %d", ErrOutOfOrder, r.Report.OutOfOrder))
+	}
+	if r.Uncommitted > 0 {
+		errs = append(errs, fmt.Errorf("%w: %d", ErrUncommitted, r.Uncommitted))
+	}
+	if r.MaxDupes != nil && r.Report.Duplicates > *r.MaxDupes {
+		// duplicates are expected and only considered as failure if limited by the scenario
+		errs = append(errs, fmt.Errorf("%w: %d > %d", ErrDuplicate, r.Report.Duplicates, *r.MaxDupes))
+	}
+	return errors.Join(errs...)
+}
+
+// ErrUncommitted is reported for handled events that were not committed after the scenario.
+var ErrUncommitted = errors.New("uncommitted events")
+
+// RunScenarios runs all scenarios one after another and returns their results.
+func RunScenarios(cfg Config, scenarios []Scenario) []ScenarioResult {
+	results := make([]ScenarioResult, len(scenarios))
+	for i, sc := range scenarios {
+		results[i] = RunScenario(cfg, sc)
+		log.Info().Interface("result", results[i]).Msg("scenario finished")
+	}
+	return results
+}
+
+// RunScenario runs a scenario against a new topic and consumer group of the configured broker.
+func RunScenario(cfg Config, sc Scenario) ScenarioResult {
+	sc = sc.WithDefaults()
+	res := ScenarioResult{Name: sc.Name, MaxDupes: sc.MaxDuplicates}
+	fail := func(err error) ScenarioResult {
+		res.Errors = append(res.Errors, err.Error())
+		return res
+	}
+	if err := sc.validate(); err != nil {
+		return fail(err)
+	}
+	if cfg.Provider == ProviderPebble {
+		return fail(fmt.Errorf("provider %s does not support consumer groups", cfg.Provider))
+	}
+
+	topic := cfg.Topic.Topic + "." + sc.Name
+	cfg.Topic.Topic = topic
+	cfg.Topic.NumPartitions = sc.Partitions
+	cfg.Writer.Topic = topic
+	cfg.Reader.Topic = topic
+	cfg.Reader.GroupID += "." + sc.Name
+
+	broker, err := NewBroker(cfg)
+	if err != nil {
+		return fail(err)
+	}
+	defer broker.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(sc.Drain)*2+cfg.PipelineTimeout)
+	defer cancel()
+	if err := broker.CreateTopic(ctx); err != nil {
+		return fail(fmt.Errorf("failed to create topic: %w", err))
+	}
+
+	r := &chaosRun{sc: sc, broker: broker, v: NewVerifier(uuid.NewString()), topic: topic}
+	r.run(ctx)
+
+	res.Report = r.v.Report()
+	res.Uncommitted = r.uncommitted
+	res.CommitErrors = int(r.commitErrors.Load())
+	res.Consumers = r.started
+	res.Errors = append(res.Errors, r.errs...)
+	res.Passed = res.Err() == nil
+	return res
+}
+
+// chaosRun is the state of a running scenario.
+type chaosRun struct {
+	sc     Scenario
+	broker Broker
+	v      *Verifier
+	topic  string
+
+	wg           sync.WaitGroup
+	mu           sync.Mutex
+	slots        []*chaosConsumer
+	started      int
+	errs         []string
+	lastActive   atomic.Int64 // unix nanos of the last fetch or commit
+	commitErrors atomic.Int64
+	uncommitted  int
+}
+
+// chaosConsumer is a consumer goroutine that can be stopped gracefully or killed.
+type chaosConsumer struct {
+	c            Consumer
+	stop         context.CancelFunc // stops fetching, pending events are still committed
+	kill         context.CancelFunc // cancels fetching and committing
+	cancelCommit atomic.Bool
+	done         chan struct{}
+}
+
+func (r *chaosRun) errorf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func (r *chaosRun) touch() { r.lastActive.Store(time.Now().UnixNano()) }
+
+func (r *chaosRun) run(ctx context.Context) {
+	for i := 0; i < r.sc.Consumers; i++ {
+		r.add(ctx, -1)
+	}
+
+	start := time.Now()
+	stepsDone := make(chan struct{})
+	go func() {
+		defer close(stepsDone)
+		for i, s := range r.sc.Steps {
+			select {
+			case <-time.After(time.Until(start.Add(time.Duration(s.At)))):
+			case <-ctx.Done():
+				return
+			}
+			log.Info().Int("step", i).Str("action", s.Action).Int("consumer", s.Consumer).Msg("chaos step")
+			r.step(ctx, s)
+		}
+	}()
+
+	if err := r.produce(ctx); err != nil {
+		r.errorf("failed to produce events: %v", err)
+	}
+	<-stepsDone
+	r.drain(ctx)
+
+	r.mu.Lock()
+	slots := r.slots
+	r.mu.Unlock()
+	for _, c := range slots {
+		if c != nil {
+			c.stop()
+			<-c.done
+		}
+	}
+	r.wg.Wait()
+	r.check(ctx)
+}
+
+func (r *chaosRun) step(ctx context.Context, s Step) {
+	if s.Action == ActionAdd {
+		r.add(ctx, -1)
+		return
+	}
+	r.mu.Lock()
+	var c *chaosConsumer
+	if s.Consumer >= 0 && s.Consumer < len(r.slots) {
+		c = r.slots[s.Consumer]
+	}
+	r.mu.Unlock()
+	if c == nil {
+		r.errorf("%s: consumer %d is not running", s.Action, s.Consumer)
+		return
+	}
+
+	switch s.Action {
+	case ActionKill:
+		c.kill()
+		<-c.done
+	case ActionRestart:
+		c.kill()
+		<-c.done
+		r.add(ctx, s.Consumer)
+	case ActionCancelCommit:
+		c.cancelCommit.Store(true)
+	}
+}
+
+// add starts a consumer in the given slot or in a new slot if the slot is negative.
+func (r *chaosRun) add(ctx context.Context, slot int) {
+	consumer, err := r.broker.NewConsumer()
+	if err != nil {
+		r.errorf("failed to create consumer: %v", err)
+		return
+	}
+	killCtx, kill := context.WithCancel(ctx)
+	fetchCtx, stop := context.WithCancel(killCtx)
+	c := &chaosConsumer{c: consumer, stop: stop, kill: kill, done: make(chan struct{})}
+
+	r.mu.Lock()
+	if slot < 0 {
+		slot = len(r.slots)
+		r.slots = append(r.slots, nil)
+	}
+	r.slots[slot] = c
+	r.started++
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer close(c.done)
+		defer kill()
+		defer func() {
+			if err := consumer.Close(); err != nil {
+				r.errorf("consumer %d: failed to close: %v", slot, err)
+			}
+			r.mu.Lock()
+			if r.slots[slot] == c {
+				r.slots[slot] = nil
+			}
+			r.mu.Unlock()
+		}()
+		// Exit errors of killed consumers are checked like in kstore to find ungraceful exits.
+		if err := kstore.FilterGraceful(r.consume(fetchCtx, killCtx, c)); err != nil {
+			r.errorf("consumer %d: %v", slot, err)
+		}
+	}()
+}
+
+// consume handles and commits events until the consumer is stopped or killed.
+func (r *chaosRun) consume(fetchCtx, killCtx context.Context, c *chaosConsumer) error {
+	for {
+		msg, err := c.c.Fetch(fetchCtx)
+		if err != nil {
+			if fetchCtx.Err() != nil {
+				return context.Canceled
+			}
+			return err
+		}
+		if killCtx.Err() != nil {
+			return context.Canceled
+		}
+		r.touch()
+		if _, ok := r.v.Consume(msg); !ok {
+			log.Debug().Str("key", string(msg.Key)).Msg("skipped foreign event")
+		}
+
+		if d := time.Duration(r.sc.ProcessTime); d > 0 {
+			select {
+			case <-time.After(d):
+			case <-killCtx.Done():
+				return context.Canceled
+			}
+		}
+
+		commitCtx := killCtx
+		if c.cancelCommit.Load() {
+			// simulates a shutdown signal arriving during the commit
+			commitCtx = &inflightCtx{Context: killCtx, hook: c.kill}
+		}
+		err = c.c.Commit(commitCtx, msg)
+		r.touch()
+		switch {
+		case killCtx.Err() != nil:
+			return context.Canceled
+		case err != nil:
+			// commits of revoked partitions fail, the event is redelivered to the new owner
+			r.commitErrors.Add(1)
+			log.Debug().Err(err).Int("partition", msg.Partition).Msg("failed to commit")
+		}
+	}
+}
+
+// inflightCtx calls the hook when a commit first uses the context, i.e., while the commit is in flight.
+type inflightCtx struct {
+	context.Context
+	once sync.Once
+	hook func()
+}
+
+func (c *inflightCtx) Done() <-chan struct{} {
+	c.once.Do(c.hook)
+	return c.Context.Done()
+}
+
+func (c *inflightCtx) Err() error {
+	c.once.Do(c.hook)
+	return c.Context.Err()
+}
+
+func (r *chaosRun) produce(ctx context.Context) error {
+	w, err := r.broker.NewProducer()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	var tick <-chan time.Time
+	if r.sc.Rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(r.sc.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for i := 0; i < r.sc.Events; i++ {
+		msg, err := newEvent(r.v, r.topic, fmt.Sprintf("key-%d", i%r.sc.Keys))
+		if err != nil {
+			return err
+		}
+		if err := w.Produce(ctx, msg); err != nil {
+			return err
+		}
+		if tick != nil {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
+
+// drain waits until all events are handled and the consumers are idle for the settle time.
+func (r *chaosRun) drain(ctx context.Context) {
+	settle := time.Duration(r.sc.Settle)
+	deadline := time.After(time.Duration(r.sc.Drain))
+	ticker := time.NewTicker(settle / 4)
+	defer ticker.Stop()
+	for {
+		idle := time.Since(time.Unix(0, r.lastActive.Load()))
+		if r.v.Unique() >= r.sc.Events && idle >= settle {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			r.errorf("drain timeout after %s", time.Duration(r.sc.Drain))
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check reads the topic with a new consumer of the group. All events it receives were not
+// committed by the consumers of the scenario. They are not counted as consumed or duplicates.
+func (r *chaosRun) check(ctx context.Context) {
+	consumer, err := r.broker.NewConsumer()
+	if err != nil {
+		r.errorf("failed to create check consumer: %v", err)
+		return
+	}
+	defer consumer.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(r.sc.Check))
+	defer cancel()
+	for {
+		msg, err := consumer.Fetch(ctx)
+		if err != nil {
+			return
+		}
+		if _, ok := r.v.decode(msg); ok {
+			r.uncommitted++
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package buffertest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBrokerRebalance(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBroker(2)
+	p, _ := b.NewProducer()
+	for _, key := range []string{"a", "b", "c", "d", "e", "f"} {
+		assert.NoError(t, p.Produce(ctx, Message{Key: []byte(key)}))
+	}
+
+	c1, _ := b.NewConsumer()
+	m1, err := c1.Fetch(ctx)
+	assert.NoError(t, err)
+	_, err = c1.Fetch(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, c1.Commit(ctx, m1))
+
+	// a new consumer revokes partition 1 from c1
+	c2, _ := b.NewConsumer()
+	assert.ErrorIs(t, c1.Commit(ctx, Message{Partition: 1}), ErrRebalanced)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, c2.Commit(canceled, m1), context.Canceled)
+
+	// uncommitted messages are redelivered after the rebalance
+	assert.NoError(t, c1.Close())
+	_, err = c1.Fetch(ctx)
+	assert.ErrorIs(t, err, ErrConsumerClosed)
+	committed := b.CommittedOffsets()
+	var fetched int
+	for {
+		fetchCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		msg, err := c2.Fetch(fetchCtx)
+		cancel()
+		if err != nil {
+			break
+		}
+		assert.GreaterOrEqual(t, msg.Offset, committed[msg.Partition])
+		assert.NoError(t, c2.Commit(ctx, msg))
+		fetched++
+	}
+	assert.Equal(t, 5, fetched)
+	assert.Equal(t, b.EndOffsets(), b.CommittedOffsets())
+}
+
+func TestCheckUncommitted(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBroker(1)
+	r := &chaosRun{sc: Scenario{Check: Duration(50 * time.Millisecond)}, broker: b, v: NewVerifier("run"), topic: "t"}
+	p, _ := b.NewProducer()
+	for i := 0; i < 3; i++ {
+		msg, err := newEvent(r.v, r.topic, "key")
+		assert.NoError(t, err)
+		assert.NoError(t, p.Produce(ctx, msg))
+	}
+
+	// handle the events without committing them
+	c, _ := b.NewConsumer()
+	for i := 0; i < 3; i++ {
+		msg, err := c.Fetch(ctx)
+		assert.NoError(t, err)
+		_, ok := r.v.Consume(msg)
+		assert.True(t, ok)
+	}
+	assert.NoError(t, c.Close())
+
+	r.check(ctx)
+	assert.Equal(t, 3, r.uncommitted)
+	assert.Equal(t, 0, r.v.Report().Duplicates, "redeliveries of the check are not duplicates")
+}
+
+func TestLoadScenarios(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenarios.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`[{"name":"x","steps":[{"at":"1s","action":"explode"}]}]`), 0o644))
+	_, err := LoadScenarios(path)
+	assert.ErrorContains(t, err, `unknown action "explode"`)
+
+	res := RunScenario(Config{Provider: ProviderPebble}, Scenario{Name: "x"})
+	assert.False(t, res.Passed)
+	assert.Error(t, res.Err())
+}
+
+func TestScenariosMemory(t *testing.T) {
+	scenarios, err := LoadScenarios("../scenarios.json")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, scenarios)
+
+	cfg := Config{
+		Provider:        ProviderMemory,
+		Topic:           kafka.TopicConfig{Topic: "buffertest"},
+		PipelineTimeout: 10 * time.Second,
+	}
+	for _, sc := range scenarios {
+		sc.Check = Duration(100 * time.Millisecond)
+		res := RunScenario(cfg, sc)
+		assert.NoError(t, res.Err(), sc.Name)
+		assert.True(t, res.Passed, sc.Name)
+		assert.Equal(t, sc.Events, res.Report.Unique, sc.Name)
+		assert.Equal(t, 0, res.Uncommitted, sc.Name)
+		if sc.Name == "cancel-during-commit" {
+			assert.GreaterOrEqual(t, res.Report.Duplicates, 1, "the canceled commit is redelivered")
+		}
+	}
+
+	limit := 0
+	res := RunScenario(cfg, Scenario{
+		Name: "limited", Events: 50, Rate: 500, Consumers: 2, MaxDuplicates: &limit, Check: Duration(100 * time.Millisecond),
+		Steps: []Step{{At: Duration(20 * time.Millisecond), Action: ActionCancelCommit, Consumer: 0}},
+	})
+	assert.False(t, res.Passed)
+	assert.ErrorIs(t, res.Err(), ErrDuplicate)
+}
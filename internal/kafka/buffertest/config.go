@@ -7,11 +7,16 @@ import (
 )
 
 type Config struct {
+	Provider        string // kafka, pebble, or memory
+	Dir             string // pebble directory
 	Writer          kafka.WriterConfig
 	Reader          kafka.ReaderConfig
 	Topic           kafka.TopicConfig
 	NumEvents       int
+	NumKeys         int
 	PipelineTimeout time.Duration
 	WriterTick      time.Duration
 	WaiterTick      time.Duration
+	Bench           BenchConfig
+	Scenarios       string // path of a chaos scenarios file
 }
@@ -15,24 +15,47 @@ func LoadConfig() Config {
 	var timeout = flag.Duration("d", time.Second*20, "send and receive timeout")
 	var numEvents = flag.Int("c", 10, "number of events to send and receive")
 	var numPartitions = flag.Int("p", 10, "number of partitions used when creating topics")
+	var numKeys = flag.Int("k", 3, "number of event keys with separate sequence numbers")
+	var provider = flag.String("provider", ProviderKafka, "broker provider: kafka, pebble, or memory")
+	var dir = flag.String("dir", "", "pebble directory, defaults to a temporary directory")
+	var batchTimeout = flag.Duration("batch-timeout", time.Millisecond, "kafka writer batch timeout")
+	var batchSize = flag.Int("batch-size", 0, "kafka writer batch size, 0 uses the kafka-go default")
+	var queueCapacity = flag.Int("queue-capacity", 1, "kafka reader queue capacity")
+	var scenarios = flag.String("scenarios", "", "run the chaos scenarios of the given JSON file")
+
+	var bench = BenchConfig{}
+	flag.BoolVar(&bench.Enabled, "bench", false, "run the benchmark mode and print the result as JSON")
+	flag.IntVar(&bench.Rate, "rate", 1000, "bench: target rate of all producers in msgs/sec, 0 means unlimited")
+	flag.IntVar(&bench.PayloadSize, "size", 100, "bench: payload size in bytes")
+	flag.IntVar(&bench.Producers, "producers", 1, "bench: number of parallel producers")
+	flag.IntVar(&bench.Consumers, "consumers", 1, "bench: number of parallel consumers")
+	flag.DurationVar(&bench.Warmup, "warmup", 2*time.Second, "bench: warmup phase")
+	flag.DurationVar(&bench.Duration, "duration", 10*time.Second, "bench: measure phase")
+	flag.DurationVar(&bench.Drain, "drain", 5*time.Second, "bench: max wait for pending events")
 
 	flag.Parse()
 	brokers := strings.Split(*broker, ",")
 	return Config{
+		Provider:        *provider,
+		Dir:             *dir,
 		NumEvents:       *numEvents,
+		NumKeys:         *numKeys,
 		PipelineTimeout: *timeout,
 		WriterTick:      *timeout / time.Duration(*numEvents) / 10,
 		WaiterTick:      *timeout / 10,
+		Bench:           bench,
+		Scenarios:       *scenarios,
 		Writer: kafka.WriterConfig{
 			Brokers:      brokers,
 			Topic:        *topic,
-			BatchTimeout: time.Millisecond,
+			BatchTimeout: *batchTimeout,
+			BatchSize:    *batchSize,
 		},
 		Reader: kafka.ReaderConfig{
 			Brokers:       brokers,
 			Topic:         *topic,
 			GroupID:       *group,
-			QueueCapacity: 1,
+			QueueCapacity: *queueCapacity,
 		},
 		Topic: kafka.TopicConfig{
 			Topic:             *topic,
@@ -2,115 +2,70 @@ package buffertest
 
 import (
 	"context"
-	"encoding/json"
-	"io"
-	"time"
 
-	"github.com/google/uuid"
-	"github.com/rs/zerolog/log"
 	"github.com/segmentio/kafka-go"
-	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-func newConn(ctx context.Context, address, topic string) (*kafka.Conn, error) {
-	return kafka.DialLeader(ctx, "tcp", address, topic, 0)
+// kafkaBroker implements the Broker using kafka-go.
+type kafkaBroker struct {
+	cfg Config
 }
 
-func newEvent(topic string) (*kafka.Message, error) {
-	id := uuid.NewString()
-	data := Map{
-		"timestamp": timestamppb.Now(),
-		"id":        id,
-		"topic":     topic,
-	}
-	value, err := json.Marshal(data)
+func newKafkaBroker(cfg Config) *kafkaBroker {
+	return &kafkaBroker{cfg: cfg}
+}
+
+func (b *kafkaBroker) Name() string { return ProviderKafka }
+
+func (b *kafkaBroker) CreateTopic(ctx context.Context) error {
+	conn, err := kafka.DialLeader(ctx, "tcp", b.cfg.Writer.Brokers[0], b.cfg.Topic.Topic, 0)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return &kafka.Message{
-		Key:   []byte(id),
-		Value: value,
-	}, nil
+	defer conn.Close()
+	return conn.CreateTopics(b.cfg.Topic)
 }
 
-func handleEvent(msg kafka.Message) error {
-	n := mxNumHandled.Inc()
-	if n%10 == 1 || true {
-		log.Info().Interface("handleEvent", Map{
-			"key":         string(msg.Key),
-			"topic":       msg.Topic,
-			"num_handled": n,
-		}).Msg("handled event")
-	}
-	return nil
+func (b *kafkaBroker) NewProducer() (Producer, error) {
+	return &kafkaProducer{kafka.NewWriter(b.cfg.Writer)}, nil
 }
 
-func consumeEvents(ctx context.Context, r *kafka.Reader) {
-	log.Info().Msg("start consuming events")
-	defer log.Info().Msg("finished consuming events")
-	for {
-		msg, err := r.ReadMessage(ctx)
-		if err == io.EOF || err == context.Canceled {
-			log.Info().Msg("reader drained")
-			return
-		}
-		if err != nil {
-			log.Error().Err(err).Msg("failed to read message")
-			return
-		}
-		handleEvent(msg)
-	}
+func (b *kafkaBroker) NewConsumer() (Consumer, error) {
+	return &kafkaConsumer{kafka.NewReader(b.cfg.Reader)}, nil
 }
 
-func produceEvents(ctx context.Context, w *kafka.Writer, numEvents int, tick time.Duration) error {
-	log.Info().Msg("start producing events")
-	var kv *kafka.Message
-	var err error
-	ticker := time.NewTicker(tick)
-	defer ticker.Stop()
-	for i := 0; i < numEvents; i++ {
-		kv, err = newEvent(w.Topic)
-		if err != nil {
-			return err
-		}
-		err = w.WriteMessages(ctx, *kv)
-		if err != nil {
-			return err
-		}
-		<-ticker.C
+func (b *kafkaBroker) Close() error { return nil }
+
+type kafkaProducer struct{ w *kafka.Writer }
+
+func (p *kafkaProducer) Produce(ctx context.Context, msgs ...Message) error {
+	kms := make([]kafka.Message, len(msgs))
+	for i, m := range msgs {
+		kms[i] = kafka.Message{Key: m.Key, Value: m.Value}
 	}
-	log.Info().Interface("produceEvents", Map{
-		"num_events": numEvents,
-		"topic":      w.Topic,
-	}).Msg("produced all events")
-	return nil
+	return p.w.WriteMessages(ctx, kms...)
 }
 
-func waitForEvents(ctx context.Context, numEvents int, tick time.Duration) error {
-	finished := false
-	handled := 0
-	report := func() {
-		log.Info().Interface("waitForEvents", Map{
-			"expected": numEvents,
-			"handled":  handled,
-			"finished": finished,
-		}).Msg("waiting for events")
-	}
-	defer report()
-
-	ticker := time.NewTicker(tick)
-	defer ticker.Stop()
-
-	for {
-		handled = mxNumHandled.Get()
-		finished = handled >= numEvents
-		if finished {
-			return nil
-		}
-		select {
-		case <-ticker.C:
-		case <-ctx.Done():
-			return ctx.Err()
-		}
+func (p *kafkaProducer) Close() error { return p.w.Close() }
+
+type kafkaConsumer struct{ r *kafka.Reader }
+
+func (c *kafkaConsumer) Fetch(ctx context.Context) (Message, error) {
+	km, err := c.r.FetchMessage(ctx)
+	if err != nil {
+		return Message{}, err
 	}
+	return Message{
+		Key:       km.Key,
+		Value:     km.Value,
+		Partition: km.Partition,
+		Offset:    km.Offset,
+		raw:       km,
+	}, nil
+}
+
+func (c *kafkaConsumer) Commit(ctx context.Context, msg Message) error {
+	return c.r.CommitMessages(ctx, msg.raw.(kafka.Message))
 }
+
+func (c *kafkaConsumer) Close() error { return c.r.Close() }
@@ -0,0 +1,102 @@
+package buffertest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ubntc/go/kstore/kschema"
+	"github.com/ubntc/go/kstore/provider/api"
+	"github.com/ubntc/go/kstore/provider/pebble"
+)
+
+// markerKey is the key of the message written when creating the topic.
+const markerKey = "buffertest.marker"
+
+// kstoreBroker implements the Broker using a kstore api.Client, which allows running the
+// pipeline locally without a Kafka broker.
+type kstoreBroker struct {
+	name   string
+	client api.Client
+	topic  string
+
+	// Messages get their offset when they are created. Writes are serialized to store the
+	// offsets in order so that readers do not skip messages of concurrent writers.
+	mu sync.Mutex
+}
+
+// NewKstoreBroker returns a Broker for the topic of a kstore client.
+func NewKstoreBroker(name string, client api.Client, topic string) Broker {
+	return &kstoreBroker{name: name, client: client, topic: topic}
+}
+
+func newPebbleBroker(cfg Config) Broker {
+	return NewKstoreBroker(ProviderPebble, pebble.NewClient(cfg.Dir), cfg.Topic.Topic)
+}
+
+func (b *kstoreBroker) Name() string { return b.name }
+
+// CreateTopic creates the topic and writes a marker message. Readers that start at the first
+// message of a topic continue after that message, so the marker ensures that no test event
+// is the first message.
+func (b *kstoreBroker) CreateTopic(ctx context.Context) error {
+	errs, err := b.client.CreateTopics(ctx, b.topic)
+	if err != nil && !b.client.IsExistsError(errs[b.topic]) {
+		return err
+	}
+	p, err := b.NewProducer()
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	return p.Produce(ctx, Message{Key: []byte(markerKey)})
+}
+
+func (b *kstoreBroker) NewProducer() (Producer, error) {
+	return &kstoreProducer{b, b.client.NewWriter()}, nil
+}
+
+func (b *kstoreBroker) NewConsumer() (Consumer, error) {
+	return &kstoreConsumer{b.client.NewReader(b.topic)}, nil
+}
+
+func (b *kstoreBroker) Close() error { return b.client.Close() }
+
+type kstoreProducer struct {
+	b *kstoreBroker
+	w api.Writer
+}
+
+func (p *kstoreProducer) Produce(ctx context.Context, msgs ...Message) error {
+	p.b.mu.Lock()
+	defer p.b.mu.Unlock()
+	kms := make([]api.Message, len(msgs))
+	for i, m := range msgs {
+		kms[i] = kschema.NewMessage(p.b.topic, m.Key, m.Value)
+	}
+	return p.w.Write(ctx, p.b.topic, kms...)
+}
+
+func (p *kstoreProducer) Close() error { return p.w.Close() }
+
+type kstoreConsumer struct{ r api.Reader }
+
+func (c *kstoreConsumer) Fetch(ctx context.Context) (Message, error) {
+	km, err := c.r.Read(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+	msg := Message{Key: km.Key(), Value: km.Value(), Offset: int64(km.Offset()), raw: km}
+
+	// Some providers return the stored message encoded as value.
+	var stored kschema.Message
+	if err := stored.Decode(km.Value()); err == nil && stored.Key() != nil {
+		msg.Key, msg.Value = stored.Key(), stored.Value()
+	}
+	return msg, nil
+}
+
+func (c *kstoreConsumer) Commit(ctx context.Context, msg Message) error {
+	return c.r.Commit(ctx, msg.raw.(api.Message))
+}
+
+func (c *kstoreConsumer) Close() error { return c.r.Close() }
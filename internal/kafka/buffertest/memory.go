@@ -0,0 +1,189 @@
+package buffertest
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+// Errors of the memory broker.
+var (
+	ErrConsumerClosed = errors.New("consumer closed")
+	ErrRebalanced     = errors.New("partition not assigned to consumer, rebalance in progress")
+)
+
+// memoryBroker is a local stand-in for a Kafka broker with a single topic and consumer group.
+// Messages are assigned to partitions by key. Each join or leave of a consumer triggers an eager
+// rebalance: all partitions are reassigned and consumers continue at the committed offsets, so
+// uncommitted messages are redelivered.
+type memoryBroker struct {
+	mu         sync.Mutex
+	partitions [][]Message
+	committed  []int64
+	members    []*memoryConsumer
+	changed    chan struct{} // closed and replaced on new messages and rebalances
+}
+
+func newMemoryBroker(numPartitions int) *memoryBroker {
+	if numPartitions <= 0 {
+		numPartitions = 1
+	}
+	return &memoryBroker{
+		partitions: make([][]Message, numPartitions),
+		committed:  make([]int64, numPartitions),
+		changed:    make(chan struct{}),
+	}
+}
+
+func (b *memoryBroker) Name() string                          { return ProviderMemory }
+func (b *memoryBroker) CreateTopic(ctx context.Context) error { return nil }
+func (b *memoryBroker) Close() error                          { return nil }
+
+func (b *memoryBroker) NewProducer() (Producer, error) { return &memoryProducer{b}, nil }
+
+func (b *memoryBroker) NewConsumer() (Consumer, error) {
+	c := &memoryConsumer{b: b}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.members = append(b.members, c)
+	b.rebalance()
+	return c, nil
+}
+
+// notify wakes up waiting consumers. It must be called with b.mu locked.
+func (b *memoryBroker) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// rebalance assigns the partitions round-robin to all members. It must be called with b.mu locked.
+func (b *memoryBroker) rebalance() {
+	for _, c := range b.members {
+		c.positions = make(map[int]int64)
+	}
+	if len(b.members) > 0 {
+		for p := range b.partitions {
+			c := b.members[p%len(b.members)]
+			c.positions[p] = b.committed[p]
+		}
+	}
+	b.notify()
+}
+
+// CommittedOffsets returns the committed offsets by partition.
+func (b *memoryBroker) CommittedOffsets() []int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]int64(nil), b.committed...)
+}
+
+// EndOffsets returns the offsets of the next messages by partition.
+func (b *memoryBroker) EndOffsets() []int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	res := make([]int64, len(b.partitions))
+	for p, log := range b.partitions {
+		res[p] = int64(len(log))
+	}
+	return res
+}
+
+type memoryProducer struct{ b *memoryBroker }
+
+func (p *memoryProducer) Produce(ctx context.Context, msgs ...Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b := p.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, m := range msgs {
+		h := fnv.New32a()
+		h.Write(m.Key)
+		m.Partition = int(h.Sum32() % uint32(len(b.partitions)))
+		m.Offset = int64(len(b.partitions[m.Partition]))
+		b.partitions[m.Partition] = append(b.partitions[m.Partition], m)
+	}
+	b.notify()
+	return nil
+}
+
+func (p *memoryProducer) Close() error { return nil }
+
+type memoryConsumer struct {
+	b         *memoryBroker
+	positions map[int]int64 // fetch positions of the assigned partitions, guarded by b.mu
+	next      int           // partition to check first for fair fetching
+	closed    bool
+}
+
+func (c *memoryConsumer) Fetch(ctx context.Context) (Message, error) {
+	b := c.b
+	for {
+		b.mu.Lock()
+		if c.closed {
+			b.mu.Unlock()
+			return Message{}, ErrConsumerClosed
+		}
+		assigned := make([]int, 0, len(c.positions))
+		for p := range c.positions {
+			assigned = append(assigned, p)
+		}
+		sort.Ints(assigned)
+		for i := range assigned {
+			p := assigned[(c.next+i)%len(assigned)]
+			if pos := c.positions[p]; pos < int64(len(b.partitions[p])) {
+				c.positions[p] = pos + 1
+				c.next = (c.next + i + 1) % len(assigned)
+				msg := b.partitions[p][pos]
+				b.mu.Unlock()
+				return msg, nil
+			}
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (c *memoryConsumer) Commit(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b := c.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := c.positions[msg.Partition]; !ok || c.closed {
+		return ErrRebalanced
+	}
+	if next := msg.Offset + 1; next > b.committed[msg.Partition] {
+		b.committed[msg.Partition] = next
+	}
+	return nil
+}
+
+// Close leaves the consumer group, which triggers a rebalance.
+func (c *memoryConsumer) Close() error {
+	b := c.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	for i, m := range b.members {
+		if m == c {
+			b.members = append(b.members[:i], b.members[i+1:]...)
+			break
+		}
+	}
+	b.rebalance()
+	return nil
+}
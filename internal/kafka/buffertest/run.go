@@ -2,56 +2,169 @@ package buffertest
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
-	"github.com/segmentio/kafka-go"
 )
 
-func Run(cfg Config) (int, error) {
-	err := runPipeline(cfg)
-	numHandled := mxNumHandled.Get()
+// Run runs the pipeline and returns the delivery report. The returned error includes
+// all delivery violations of the report.
+func Run(cfg Config) (Report, error) {
+	broker, err := NewBroker(cfg)
+	if err != nil {
+		return Report{}, err
+	}
+	defer broker.Close()
+
+	v := NewVerifier(uuid.NewString())
+	err = runPipeline(cfg, broker, v)
+	report := v.Report()
 
 	log.Info().Interface("results", Map{
+		"provider": broker.Name(),
 		"topic":    cfg.Topic.Topic,
-		"handled":  numHandled,
-		"expected": cfg.NumEvents,
+		"report":   report,
 		"graceful": err == nil,
 	}).Msg("pipeline finished")
 
-	return numHandled, err
+	if err == nil {
+		err = report.Err()
+	}
+	return report, err
 }
 
-func runPipeline(cfg Config) error {
+func runPipeline(cfg Config, broker Broker, v *Verifier) error {
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.PipelineTimeout)
 	defer cancel()
 
-	client, err := newConn(ctx, cfg.Writer.Brokers[0], cfg.Topic.Topic)
-	if err != nil {
-		return errors.Wrap(err, "failed to create connection Kafka")
+	log.Info().Msg("create topics")
+	if err := broker.CreateTopic(ctx); err != nil {
+		return errors.Wrap(err, "failed to create topics")
 	}
-	defer client.Close()
 
 	log.Info().Msg("create writer")
-	w := kafka.NewWriter(cfg.Writer)
-	defer w.Close()
-
-	log.Info().Msg("create topics")
-	if err := client.CreateTopics(cfg.Topic); err != nil {
-		return errors.Wrap(err, "failed to create topics")
+	w, err := broker.NewProducer()
+	if err != nil {
+		return errors.Wrap(err, "failed to create producer")
 	}
+	defer w.Close()
 
 	log.Info().Msg("create reader")
-	r := kafka.NewReader(cfg.Reader)
+	r, err := broker.NewConsumer()
+	if err != nil {
+		return errors.Wrap(err, "failed to create consumer")
+	}
 	defer r.Close()
-	go consumeEvents(ctx, r)
+	go consumeEvents(ctx, r, v)
 
-	if err := produceEvents(ctx, w, cfg.NumEvents, cfg.WriterTick); err != nil {
+	if err := produceEvents(ctx, w, v, cfg); err != nil {
 		return errors.Wrap(err, "failed to produce events")
 	}
 
 	// wait for termination condition
-	err = waitForEvents(ctx, cfg.NumEvents, cfg.WaiterTick)
-	log.Info().Msg("closing reader, writer, and connection")
+	err = waitForEvents(ctx, v, cfg.NumEvents, cfg.WaiterTick)
+	log.Info().Msg("closing reader and writer")
 	return err
 }
+
+func newEvent(v *Verifier, topic, key string) (Message, error) {
+	e := v.Next(key, topic, uuid.NewString())
+	value, err := json.Marshal(e)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Key: []byte(key), Value: value}, nil
+}
+
+func handleEvent(msg Message, v *Verifier) {
+	e, ok := v.Consume(msg)
+	if !ok {
+		log.Debug().Str("key", string(msg.Key)).Msg("skipped foreign event")
+		return
+	}
+	log.Info().Interface("handleEvent", Map{
+		"key":       e.Key,
+		"seq":       e.Seq,
+		"partition": msg.Partition,
+		"offset":    msg.Offset,
+	}).Msg("handled event")
+}
+
+func consumeEvents(ctx context.Context, r Consumer, v *Verifier) {
+	log.Info().Msg("start consuming events")
+	defer log.Info().Msg("finished consuming events")
+	for {
+		msg, err := r.Fetch(ctx)
+		if ctx.Err() != nil {
+			log.Info().Msg("reader drained")
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("failed to read message")
+			return
+		}
+		handleEvent(msg, v)
+		if err := r.Commit(ctx, msg); err != nil {
+			log.Error().Err(err).Msg("failed to commit message")
+			return
+		}
+	}
+}
+
+func produceEvents(ctx context.Context, w Producer, v *Verifier, cfg Config) error {
+	log.Info().Msg("start producing events")
+	numKeys := cfg.NumKeys
+	if numKeys <= 0 {
+		numKeys = 1
+	}
+	ticker := time.NewTicker(cfg.WriterTick)
+	defer ticker.Stop()
+	for i := 0; i < cfg.NumEvents; i++ {
+		msg, err := newEvent(v, cfg.Topic.Topic, fmt.Sprintf("key-%d", i%numKeys))
+		if err != nil {
+			return err
+		}
+		if err := w.Produce(ctx, msg); err != nil {
+			return err
+		}
+		<-ticker.C
+	}
+	log.Info().Interface("produceEvents", Map{
+		"num_events": cfg.NumEvents,
+		"topic":      cfg.Topic.Topic,
+	}).Msg("produced all events")
+	return nil
+}
+
+func waitForEvents(ctx context.Context, v *Verifier, numEvents int, tick time.Duration) error {
+	finished := false
+	handled := 0
+	report := func() {
+		log.Info().Interface("waitForEvents", Map{
+			"expected": numEvents,
+			"handled":  handled,
+			"finished": finished,
+		}).Msg("waiting for events")
+	}
+	defer report()
+
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		handled = v.Unique()
+		finished = handled >= numEvents
+		if finished {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package buffertest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Event is the payload of the test messages.
+type Event struct {
+	Run       string    `json:"run"`
+	ID        string    `json:"id"`
+	Topic     string    `json:"topic"`
+	Key       string    `json:"key"`
+	Seq       int       `json:"seq"` // sequence number per key, starting at 1
+	Timestamp time.Time `json:"timestamp"`
+	Payload   string    `json:"payload,omitempty"` // padding to simulate larger events
+}
+
+// Errors reported for delivery violations.
+var (
+	ErrMissing    = errors.New("missing events")
+	ErrDuplicate  = errors.New("duplicate events")
+	ErrOutOfOrder = errors.New("out-of-order events")
+)
+
+// maxSamples limits the number of listed missing events in a Report.
+const maxSamples = 10
+
+// Report summarizes the delivery of the events of a run.
+type Report struct {
+	Produced   int      `json:"produced"`
+	Consumed   int      `json:"consumed"`   // consumed events of this run, incl. duplicates
+	Unique     int      `json:"unique"`     // unique consumed events
+	Missing    int      `json:"missing"`    // produced but not consumed
+	Duplicates int      `json:"duplicates"` // consumed more than once
+	OutOfOrder int      `json:"out_of_order"`
+	Foreign    int      `json:"foreign"` // messages of other runs or unknown payload
+	Samples    []string `json:"missing_samples,omitempty"`
+}
+
+// Err returns an error if the report contains any violation.
+func (r Report) Err() error {
+	var errs []error
+	if r.Missing > 0 {
+		errs = append(errs, fmt.Errorf("%w: %d %v", ErrMissing, r.Missing, r.Samples))
+	}
+	if r.Duplicates > 0 {
+		errs = append(errs, fmt.Errorf("%w: %d", ErrDuplicate, r.Duplicates))
+	}
+	if r.OutOfOrder > 0 {
+		errs = append(errs, fmt.Errorf("%w: %d", ErrOutOfOrder, r.OutOfOrder))
+	}
+	return errors.Join(errs...)
+}
+
+// Verifier tracks produced and consumed events of a run by key and sequence number.
+type Verifier struct {
+	run string
+
+	mu       sync.Mutex
+	produced map[string]int         // last produced seq by key
+	seen     map[string]map[int]int // consumed count by key and seq
+	last     map[int]map[string]int // last consumed seq by partition and key
+	report   Report
+}
+
+// NewVerifier returns a Verifier for the events of the run.
+func NewVerifier(run string) *Verifier {
+	return &Verifier{
+		run:      run,
+		produced: make(map[string]int),
+		seen:     make(map[string]map[int]int),
+		last:     make(map[int]map[string]int),
+	}
+}
+
+// Next returns the next event for the key.
+func (v *Verifier) Next(key, topic, id string) Event {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.produced[key]++
+	v.report.Produced++
+	return Event{Run: v.run, ID: id, Topic: topic, Key: key, Seq: v.produced[key], Timestamp: time.Now()}
+}
+
+// Consume checks a consumed message and returns the decoded event. It returns false for
+// messages that do not belong to the run.
+func (v *Verifier) Consume(msg Message) (Event, bool) {
+	e, ok := v.decode(msg)
+	if !ok {
+		v.mu.Lock()
+		v.report.Foreign++
+		v.mu.Unlock()
+		return e, false
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.report.Consumed++
+
+	seqs, ok := v.seen[e.Key]
+	if !ok {
+		seqs = make(map[int]int)
+		v.seen[e.Key] = seqs
+	}
+	seqs[e.Seq]++
+	if seqs[e.Seq] > 1 {
+		v.report.Duplicates++
+		return e, true
+	}
+	v.report.Unique++
+
+	last, ok := v.last[msg.Partition]
+	if !ok {
+		last = make(map[string]int)
+		v.last[msg.Partition] = last
+	}
+	if e.Seq < last[e.Key] {
+		v.report.OutOfOrder++
+	} else {
+		last[e.Key] = e.Seq
+	}
+	return e, true
+}
+
+// decode decodes the event of a message without tracking it. It returns false for messages
+// that do not belong to the run.
+func (v *Verifier) decode(msg Message) (Event, bool) {
+	var e Event
+	err := json.Unmarshal(msg.Value, &e)
+	return e, err == nil && e.Run == v.run
+}
+
+// Unique returns the number of unique consumed events.
+func (v *Verifier) Unique() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.report.Unique
+}
+
+// Report returns the current report including the missing events.
+func (v *Verifier) Report() Report {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	r := v.report
+	r.Samples = nil
+	keys := make([]string, 0, len(v.produced))
+	for key := range v.produced {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for seq := 1; seq <= v.produced[key]; seq++ {
+			if v.seen[key][seq] > 0 {
+				continue
+			}
+			r.Missing++
+			if len(r.Samples) < maxSamples {
+				r.Samples = append(r.Samples, fmt.Sprintf("%s#%d", key, seq))
+			}
+		}
+	}
+	return r
+}
@@ -0,0 +1,53 @@
+package buffertest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifier(t *testing.T) {
+	v := NewVerifier("run")
+	var msgs []Message
+	for i := 0; i < 4; i++ {
+		e := v.Next("a", "topic", "")
+		value, _ := json.Marshal(e)
+		msgs = append(msgs, Message{Key: []byte("a"), Value: value})
+	}
+
+	v.Consume(msgs[0])
+	v.Consume(msgs[2])
+	v.Consume(msgs[1]) // out of order
+	v.Consume(msgs[1]) // duplicate
+	v.Consume(Message{Value: []byte(`{"run":"other"}`)})
+
+	r := v.Report()
+	assert.Equal(t, Report{
+		Produced: 4, Consumed: 4, Unique: 3, Missing: 1, Duplicates: 1, OutOfOrder: 1, Foreign: 1,
+		Samples: []string{"a#4"},
+	}, r)
+	assert.ErrorIs(t, r.Err(), ErrMissing)
+	assert.ErrorIs(t, r.Err(), ErrDuplicate)
+	assert.ErrorIs(t, r.Err(), ErrOutOfOrder)
+	assert.NoError(t, Report{Produced: 1, Unique: 1}.Err())
+}
+
+func TestRunPebble(t *testing.T) {
+	cfg := Config{
+		Provider:        ProviderPebble,
+		Dir:             t.TempDir(),
+		Topic:           kafka.TopicConfig{Topic: "buffertest"},
+		NumEvents:       20,
+		NumKeys:         3,
+		PipelineTimeout: 10 * time.Second,
+		WriterTick:      time.Millisecond,
+		WaiterTick:      10 * time.Millisecond,
+	}
+	r, err := Run(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, r.Unique)
+	assert.Equal(t, 0, r.Missing)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"os"
 
 	"github.com/rs/zerolog/log"
@@ -12,15 +13,56 @@ func main() {
 	zerologger.Setup(os.Stderr, "")
 	cfg := bt.LoadConfig()
 
-	log.Info().Strs("brokers", cfg.Writer.Brokers).Msg("starting buffertest")
-	numHandled, err := bt.Run(cfg)
+	log.Info().Str("provider", cfg.Provider).Strs("brokers", cfg.Writer.Brokers).Msg("starting buffertest")
+	if cfg.Bench.Enabled {
+		bench(cfg)
+		return
+	}
+	if cfg.Scenarios != "" {
+		chaos(cfg)
+		return
+	}
+	report, err := bt.Run(cfg)
 
 	switch {
+	case report.Missing > 0 || report.Duplicates > 0 || report.OutOfOrder > 0:
+		log.Fatal().Err(err).Interface("report", report).Msg("[FAIL] buffertest failed with delivery violations")
 	case err != nil:
 		log.Fatal().Err(err).Msg("[FAIL] buffertest failed with errors")
-	case numHandled < cfg.NumEvents:
-		log.Fatal().Msg("[FAIL] buffertest failed with missing events")
 	default:
-		log.Info().Msg("[OK] buffertest successsful")
+		log.Info().Interface("report", report).Msg("[OK] buffertest successsful")
+	}
+}
+
+func bench(cfg bt.Config) {
+	res, err := bt.Bench(cfg)
+	os.Stdout.Write(append(res.JSON(), '\n'))
+	switch {
+	case err != nil:
+		log.Fatal().Err(err).Msg("[FAIL] benchmark failed with errors")
+	case res.Report.Err() != nil:
+		log.Fatal().Err(res.Report.Err()).Msg("[FAIL] benchmark failed with delivery violations")
+	default:
+		log.Info().Msg("[OK] benchmark successful")
+	}
+}
+
+func chaos(cfg bt.Config) {
+	scenarios, err := bt.LoadScenarios(cfg.Scenarios)
+	if err != nil {
+		log.Fatal().Err(err).Msg("[FAIL] failed to load scenarios")
+	}
+	failed := 0
+	for _, res := range bt.RunScenarios(cfg, scenarios) {
+		data, _ := json.Marshal(res)
+		os.Stdout.Write(append(data, '\n'))
+		if !res.Passed {
+			failed++
+			log.Error().Err(res.Err()).Str("scenario", res.Name).Msg("scenario failed")
+		}
+	}
+	if failed > 0 {
+		log.Fatal().Int("failed", failed).Msg("[FAIL] chaos scenarios failed")
 	}
+	log.Info().Int("scenarios", len(scenarios)).Msg("[OK] chaos scenarios successful")
 }
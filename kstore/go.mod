@@ -3,7 +3,7 @@ module github.com/ubntc/go/kstore
 go 1.23
 
 require (
-	github.com/prometheus/client_golang v1.12.0
+	github.com/prometheus/client_golang v1.12.1
 	github.com/prometheus/client_model v0.2.1-0.20210607210712-147c58e9608a
 	github.com/segmentio/kafka-go v0.4.44
 )
@@ -38,4 +38,7 @@ require (
 	github.com/pierrec/lz4/v4 v4.1.15 // indirect
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
 	github.com/stretchr/testify v1.8.4
+	github.com/ubntc/go/metrics v0.0.0
 )
+
+replace github.com/ubntc/go/metrics => ../metrics
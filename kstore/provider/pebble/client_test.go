@@ -6,8 +6,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/ubntc/go/kstore/provider/pebble"
+	"github.com/ubntc/go/metrics/metricstest"
 )
 
 func Setup(t *testing.T) *pebble.Client {
@@ -77,6 +79,7 @@ func TestReadLast(t *testing.T) {
 	defer cancel()
 
 	samples := 10
+	mtx := metricstest.NewRecorder(t, pebble.Metrics)
 
 	// start writer
 	offset := 0
@@ -123,11 +126,12 @@ func TestReadLast(t *testing.T) {
 	assert.ErrorIs(t, ctx.Err(), context.Canceled)
 	assert.Equal(t, samples, received)
 
-	// check shared metrics for sane value
-	reads := pebble.Metrics.GetReads("test")
-	writes := pebble.Metrics.GetWrites("test")
-	assert.GreaterOrEqual(t, reads[pebble.OffsetStatusNewer], samples)
-	assert.GreaterOrEqual(t, writes, samples*2)
+	// check the changes of the shared metrics
+	topic := prometheus.Labels{"topic": "test"}
+	older := prometheus.Labels{"topic": "test", "status": pebble.OffsetStatusOlder.String()}
+	mtx.AssertIncreased(t, "kstore_pebble_writes_total", topic, float64(samples*2))
+	mtx.AssertIncreased(t, "kstore_pebble_reads_total", topic, float64(samples))
+	mtx.AssertUnchanged(t, "kstore_pebble_reads_total", older)
 }
 
 func TestClient(t *testing.T) {
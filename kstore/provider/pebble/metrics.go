@@ -32,6 +32,12 @@ var Metrics = &Mtx{
 	),
 }
 
+// Register registers all metrics.
+func (m *Mtx) Register(reg prometheus.Registerer) {
+	reg.MustRegister(m.Reads)
+	reg.MustRegister(m.Writes)
+}
+
 func (m *Mtx) ObserveRead(msg api.Message, topic string, status OffsetStatus) {
 	m.Reads.WithLabelValues(topic, status.String()).Inc()
 }
@@ -0,0 +1,57 @@
+// Command replay summarizes metrics recordings created by `metrics.Watch`.
+//
+// Usage:
+//
+//	replay [-filter REGEX] [-width N] metrics.jsonl
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/ubntc/go/metrics"
+)
+
+func main() {
+	var (
+		filter = flag.String("filter", "", "only show series matching the regular expression")
+		width  = flag.Int("width", 40, "width of the trend column")
+	)
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: replay [-filter REGEX] [-width N] FILE")
+		os.Exit(2)
+	}
+
+	re, err := regexp.Compile(*filter)
+	exitOnErr(err)
+
+	f, err := os.Open(flag.Arg(0))
+	exitOnErr(err)
+	defer f.Close()
+
+	recs, err := metrics.ReadRecordings(f)
+	exitOnErr(err)
+	if len(recs) > 0 {
+		first, last := recs[0].Time, recs[len(recs)-1].Time
+		fmt.Printf("%d recordings from %s to %s (%s)\n\n", len(recs),
+			first.Format("15:04:05"), last.Format("15:04:05"), last.Sub(first))
+	}
+
+	var sums []*metrics.SeriesSummary
+	for _, s := range metrics.Summarize(recs) {
+		if re.MatchString(s.Series) {
+			sums = append(sums, s)
+		}
+	}
+	exitOnErr(metrics.WriteSummary(os.Stdout, sums, *width))
+}
+
+func exitOnErr(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,237 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// DefaultInterval defines the default refresh interval of the dashboard.
+var DefaultInterval = time.Second
+
+// Quantiles are the quantiles estimated for histograms.
+var Quantiles = []float64{0.5, 0.95, 0.99}
+
+// Dashboard renders gathered metrics as aligned table with one row per series.
+// Counters show the per-second rate since the previous rendering. Histograms show the count,
+// the sum, and estimated quantiles of all observations.
+type Dashboard struct {
+	gatherer prometheus.Gatherer
+	interval time.Duration
+	filters  []*regexp.Regexp
+	clear    bool
+	out      io.Writer
+
+	record     io.Writer // optional recording output
+	recordFile string    // optional recording file
+
+	last     map[string]float64 // previous counter values by series
+	lastTime time.Time
+
+	errs []error // errors of invalid options
+}
+
+// NewDashboard returns a Dashboard for the gatherer. It returns an error for invalid options.
+func NewDashboard(g prometheus.Gatherer, opts ...WatchOption) (*Dashboard, error) {
+	d := &Dashboard{gatherer: g}
+	for _, o := range opts {
+		o.apply(d)
+	}
+	if err := errors.Join(d.errs...); err != nil {
+		return nil, err
+	}
+	if d.interval <= 0 {
+		d.interval = DefaultInterval
+	}
+	if d.out == nil {
+		d.out = os.Stderr
+	}
+	return d, nil
+}
+
+// Run renders the dashboard in the configured interval until the context is done.
+// If recording is enabled, a Recording is appended to the recording output after each rendering.
+func (d *Dashboard) Run(ctx context.Context) {
+	if d.recordFile != "" {
+		f, err := d.openRecordFile()
+		if err != nil {
+			log.Printf("failed to open record file: %v", err)
+		} else {
+			defer f.Close()
+			d.record = f
+		}
+	}
+
+	tick := time.NewTicker(d.interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-tick.C:
+			if d.clear {
+				fmt.Fprint(d.out, "\033[H\033[2J")
+			}
+			if err := d.Render(d.out, t); err != nil {
+				log.Printf("failed to render metrics: %v", err)
+			}
+			if d.record != nil {
+				if err := d.Record(d.record, t); err != nil {
+					log.Printf("failed to record metrics: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// Render gathers the metrics and writes the table.
+func (d *Dashboard) Render(w io.Writer, now time.Time) error {
+	families, err := d.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return err
+	}
+
+	elapsed := now.Sub(d.lastTime).Seconds()
+	if d.last == nil {
+		elapsed = 0 // no rates for the first rendering
+	}
+	current := make(map[string]float64)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "METRIC\tLABELS\tVALUE\tRATE/s\tCOUNT\tSUM\tP50\tP95\tP99\t\n")
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			labels := formatLabels(m.GetLabel())
+			key := mf.GetName() + "{" + labels + "}"
+			if !d.match(key) {
+				continue
+			}
+			row := []string{mf.GetName(), labels, "", "", "", "", "", "", ""}
+			rate := func(v float64) string {
+				current[key] = v
+				prev, ok := d.last[key]
+				if !ok || elapsed <= 0 {
+					return "-"
+				}
+				return formatValue((v - prev) / elapsed)
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				v := m.GetCounter().GetValue()
+				row[2], row[3] = formatValue(v), rate(v)
+			case dto.MetricType_GAUGE:
+				row[2] = formatValue(m.GetGauge().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				row[3] = rate(float64(h.GetSampleCount()))
+				row[4] = fmt.Sprint(h.GetSampleCount())
+				row[5] = formatValue(h.GetSampleSum())
+				for i, q := range Quantiles {
+					row[6+i] = formatValue(HistogramQuantile(q, h))
+				}
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				row[3] = rate(float64(s.GetSampleCount()))
+				row[4] = fmt.Sprint(s.GetSampleCount())
+				row[5] = formatValue(s.GetSampleSum())
+				for _, sq := range s.GetQuantile() {
+					for i, q := range Quantiles {
+						if sq.GetQuantile() == q {
+							row[6+i] = formatValue(sq.GetValue())
+						}
+					}
+				}
+			default:
+				row[2] = formatValue(m.GetUntyped().GetValue())
+			}
+			fmt.Fprintf(tw, "%s\t\n", strings.Join(row, "\t"))
+		}
+	}
+
+	d.last, d.lastTime = current, now
+	return tw.Flush()
+}
+
+// Record gathers the metrics and writes them as Recording. The filters of the dashboard are
+// not applied to recordings.
+func (d *Dashboard) Record(w io.Writer, now time.Time) error {
+	s, err := TakeSnapshot(d.gatherer)
+	if err != nil {
+		return err
+	}
+	return WriteRecording(w, NewRecording(now, s))
+}
+
+func (d *Dashboard) match(series string) bool {
+	if len(d.filters) == 0 {
+		return true
+	}
+	for _, re := range d.filters {
+		if re.MatchString(series) {
+			return true
+		}
+	}
+	return false
+}
+
+// HistogramQuantile estimates the q-quantile of the histogram by linear interpolation within
+// the bucket that contains the quantile. It returns NaN if the histogram has no observations.
+// Quantiles above the highest bucket bound return the highest bound.
+func HistogramQuantile(q float64, h *dto.Histogram) float64 {
+	total := h.GetSampleCount()
+	buckets := h.GetBucket()
+	if total == 0 || len(buckets) == 0 {
+		return math.NaN()
+	}
+	buckets = append([]*dto.Bucket(nil), buckets...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].GetUpperBound() < buckets[j].GetUpperBound() })
+
+	rank := q * float64(total)
+	lower, prevCount := 0.0, 0.0
+	for _, b := range buckets {
+		upper, count := b.GetUpperBound(), float64(b.GetCumulativeCount())
+		if count >= rank && !math.IsInf(upper, 1) {
+			if count == prevCount {
+				return upper
+			}
+			return lower + (upper-lower)*(rank-prevCount)/(count-prevCount)
+		}
+		if !math.IsInf(upper, 1) {
+			lower = upper
+		}
+		prevCount = count
+	}
+	return lower
+}
+
+func formatLabels(labels []*dto.LabelPair) string {
+	pairs := make([]string, len(labels))
+	for i, l := range labels {
+		pairs[i] = fmt.Sprintf("%s=%q", l.GetName(), l.GetValue())
+	}
+	return strings.Join(pairs, ",")
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "-"
+	case v == math.Trunc(v) && math.Abs(v) < 1e15:
+		return fmt.Sprintf("%.0f", v)
+	default:
+		return fmt.Sprintf("%.4g", v)
+	}
+}
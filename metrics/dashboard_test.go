@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDashboard(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "msgs_total", Help: "h"}, []string{"sub", "kind"})
+	g := prometheus.NewGauge(prometheus.GaugeOpts{Name: "pending", Help: "h"})
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "latency", Help: "h", Buckets: []float64{1, 2, 4}})
+	reg.MustRegister(c, g, h)
+
+	c.WithLabelValues("a", "x").Add(10)
+	for i := 0; i < 10; i++ {
+		h.Observe(1.5)
+	}
+
+	d, err := NewDashboard(reg)
+	assert.NoError(t, err)
+	now := time.Now()
+	buf := &bytes.Buffer{}
+	assert.NoError(t, d.Render(buf, now))
+	lines := strings.Split(buf.String(), "\n")
+	assert.Contains(t, lines[0], "METRIC")
+	assert.Regexp(t, `msgs_total\s+kind="x",sub="a"\s+10\s+-`, buf.String(), "first rendering has no rate")
+	assert.Regexp(t, `pending\s+0`, buf.String(), "zero values are shown")
+	assert.Regexp(t, `latency\s+-\s+10\s+15\s+1.5\s+1.95\s+1.99`, buf.String())
+
+	c.WithLabelValues("a", "x").Add(5)
+	buf.Reset()
+	assert.NoError(t, d.Render(buf, now.Add(500*time.Millisecond)))
+	assert.Regexp(t, `msgs_total\s+kind="x",sub="a"\s+15\s+10\s`, buf.String())
+	assert.Regexp(t, `latency\s+0\s+10`, buf.String())
+
+	buf.Reset()
+	d, err = NewDashboard(reg, Filter("pend"), Filter(`sub="b"`))
+	assert.NoError(t, err)
+	c.WithLabelValues("b", "x").Inc()
+	assert.NoError(t, d.Render(buf, now))
+	assert.Contains(t, buf.String(), "pending")
+	assert.Contains(t, buf.String(), `sub="b"`)
+	assert.NotContains(t, buf.String(), `sub="a"`)
+	assert.NotContains(t, buf.String(), "latency")
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "h", Buckets: []float64{1, 2, 4}})
+	hist := func() *dto.Histogram {
+		m := &dto.Metric{}
+		assert.NoError(t, h.Write(m))
+		return m.GetHistogram()
+	}
+	assert.True(t, math.IsNaN(HistogramQuantile(0.5, hist())))
+
+	for i := 0; i < 50; i++ {
+		h.Observe(0.5)
+		h.Observe(3)
+	}
+	assert.Equal(t, 1.0, HistogramQuantile(0.5, hist()))
+	assert.Equal(t, 3.0, HistogramQuantile(0.75, hist()))
+
+	h.Observe(100)
+	assert.Equal(t, 4.0, HistogramQuantile(1, hist()), "quantiles in the +Inf bucket return the highest bound")
+}
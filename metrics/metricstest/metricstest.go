@@ -0,0 +1,75 @@
+// Package metricstest provides assertions for the changes of Prometheus metrics in tests.
+package metricstest
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/metrics"
+)
+
+// Recorder takes a snapshot of the metrics and asserts the changes since the snapshot.
+//
+// Example:
+//
+//	rec := metricstest.NewRecorder(t, batcher.Metrics())
+//	// TODO: run the test
+//	rec.AssertIncreased(t, "batbq_processed_messages_total", prometheus.Labels{"batcher": "a"}, 10)
+type Recorder struct {
+	gatherer prometheus.Gatherer
+	before   metrics.Snapshot
+}
+
+// NewRecorder registers the metrics in a new registry and takes the initial snapshot.
+func NewRecorder(t assert.TestingT, m metrics.Metrics) *Recorder {
+	return NewGathererRecorder(t, metrics.Gatherer(m))
+}
+
+// NewGathererRecorder returns a Recorder for the metrics of the gatherer.
+func NewGathererRecorder(t assert.TestingT, g prometheus.Gatherer) *Recorder {
+	r := &Recorder{gatherer: g}
+	r.Reset(t)
+	return r
+}
+
+// Reset takes a new initial snapshot.
+func (r *Recorder) Reset(t assert.TestingT) {
+	s, err := metrics.TakeSnapshot(r.gatherer)
+	assert.NoError(t, err)
+	r.before = s
+}
+
+// Delta returns the changes since the initial snapshot.
+func (r *Recorder) Delta(t assert.TestingT) metrics.Snapshot {
+	s, err := metrics.TakeSnapshot(r.gatherer)
+	assert.NoError(t, err)
+	return s.Delta(r.before)
+}
+
+func (r *Recorder) selectDelta(t assert.TestingT, name string, labels prometheus.Labels) (metrics.Sample, bool) {
+	smp, ok := r.Delta(t).Select(name, labels)
+	if !ok {
+		assert.Fail(t, fmt.Sprintf("no series %s %v", name, labels))
+	}
+	return smp, ok
+}
+
+// AssertIncreased asserts that the value of the counter or gauge changed by the given delta.
+// Series that match the labels are summed up.
+func (r *Recorder) AssertIncreased(t assert.TestingT, name string, labels prometheus.Labels, delta float64) bool {
+	smp, ok := r.selectDelta(t, name, labels)
+	return ok && assert.Equal(t, delta, smp.Value, "delta of %s %v", name, labels)
+}
+
+// AssertObserved asserts that the histogram or summary observed n samples.
+func (r *Recorder) AssertObserved(t assert.TestingT, name string, labels prometheus.Labels, n int) bool {
+	smp, ok := r.selectDelta(t, name, labels)
+	return ok && assert.Equal(t, float64(n), smp.Count, "observations of %s %v", name, labels)
+}
+
+// AssertUnchanged asserts that the matching series did not change. Missing series are unchanged.
+func (r *Recorder) AssertUnchanged(t assert.TestingT, name string, labels prometheus.Labels) bool {
+	smp, _ := r.Delta(t).Select(name, labels)
+	return assert.Equal(t, metrics.Sample{Name: name, Labels: labels}, smp, "changes of %s %v", name, labels)
+}
@@ -0,0 +1,41 @@
+package metricstest_test
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/ubntc/go/metrics/metricstest"
+)
+
+type testMetrics struct {
+	count *prometheus.CounterVec
+	hist  *prometheus.HistogramVec
+}
+
+func (m *testMetrics) Register(reg prometheus.Registerer) {
+	reg.MustRegister(m.count, m.hist)
+}
+
+func TestRecorder(t *testing.T) {
+	m := &testMetrics{
+		count: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "count"}, []string{"batcher"}),
+		hist:  prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "latency"}, []string{"batcher"}),
+	}
+	m.count.WithLabelValues("a").Add(5)
+
+	rec := metricstest.NewRecorder(t, m)
+	m.count.WithLabelValues("a").Add(10)
+	m.hist.WithLabelValues("a").Observe(1)
+
+	rec.AssertIncreased(t, "count", prometheus.Labels{"batcher": "a"}, 10)
+	rec.AssertObserved(t, "latency", prometheus.Labels{"batcher": "a"}, 1)
+	rec.AssertUnchanged(t, "count", prometheus.Labels{"batcher": "b"})
+
+	mock := &assert.CollectT{}
+	assert.False(t, rec.AssertIncreased(mock, "count", prometheus.Labels{"batcher": "b"}, 1), "missing series")
+	assert.False(t, rec.AssertUnchanged(mock, "latency", nil))
+
+	rec.Reset(t)
+	rec.AssertUnchanged(t, "count", nil)
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Recording is a timestamped snapshot. Recordings are stored as JSON lines.
+type Recording struct {
+	Time    time.Time `json:"time"`
+	Samples []Sample  `json:"samples"`
+}
+
+// NewRecording returns a Recording of the snapshot with the samples sorted by series.
+func NewRecording(t time.Time, s Snapshot) Recording {
+	rec := Recording{Time: t, Samples: make([]Sample, 0, len(s))}
+	for _, key := range s.Series() {
+		rec.Samples = append(rec.Samples, s[key])
+	}
+	return rec
+}
+
+// WriteRecording writes the recording as single JSON line.
+func WriteRecording(w io.Writer, rec Recording) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// ReadRecordings reads all recordings from JSON lines.
+func ReadRecordings(r io.Reader) ([]Recording, error) {
+	var res []Recording
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var rec Recording
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return res, fmt.Errorf("line %d: %w", line, err)
+		}
+		res = append(res, rec)
+	}
+	return res, scanner.Err()
+}
+
+// Series returns the series string of the sample, e.g., `name{label="value"}`.
+func (s Sample) Series() string {
+	keys := make([]string, 0, len(s.Labels))
+	for k := range s.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%q", k, s.Labels[k])
+	}
+	return s.Name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+type recordOption struct{ io.Writer }
+
+func (opt recordOption) apply(d *Dashboard) { d.record = opt.Writer }
+
+// RecordTo appends a Recording of all metrics to the writer on each refresh of the dashboard.
+// Recordings can be summarized using `Summarize` or the `replay` command.
+func RecordTo(w io.Writer) WatchOption {
+	return recordOption{w}
+}
+
+// RecordFile opens or creates the file and appends the recordings to it.
+// The file is closed when the context of `Watch` is done.
+type RecordFile string
+
+func (opt RecordFile) apply(d *Dashboard) { d.recordFile = string(opt) }
+
+// openRecordFile opens the configured record file for appending.
+func (d *Dashboard) openRecordFile() (io.WriteCloser, error) {
+	return os.OpenFile(d.recordFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAndSummarize(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	g := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "workers"}, []string{"batcher"})
+	c := prometheus.NewCounter(prometheus.CounterOpts{Name: "msgs_total"})
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "latency"})
+	reg.MustRegister(g, c, h)
+
+	d, err := NewDashboard(reg)
+	assert.NoError(t, err)
+	buf := &bytes.Buffer{}
+	start := time.Now()
+	for i, workers := range []float64{1, 4, 2} {
+		g.WithLabelValues("a").Set(workers)
+		c.Add(10 * float64(i))
+		if i > 0 {
+			h.Observe(float64(i))
+		}
+		assert.NoError(t, d.Record(buf, start.Add(time.Duration(i)*time.Second)))
+	}
+
+	recs, err := ReadRecordings(buf)
+	assert.NoError(t, err)
+	assert.Len(t, recs, 3)
+	assert.Len(t, recs[0].Samples, 3)
+
+	sums := Summarize(recs)
+	assert.Len(t, sums, 3)
+	byKey := make(map[string]*SeriesSummary)
+	for _, s := range sums {
+		byKey[s.Series] = s
+	}
+
+	workers := byKey[`workers{batcher="a"}`]
+	assert.Equal(t, KindValue, workers.Kind)
+	assert.Equal(t, []float64{1, 4, 2}, workers.Values)
+	assert.Equal(t, 4.0, workers.Max, "peak value")
+	assert.Equal(t, 1.0, workers.Min)
+	assert.Equal(t, 2.0, workers.Last)
+	assert.Equal(t, "▁█▃", workers.Trend(10))
+
+	rate := byKey["msgs_total{}"]
+	assert.Equal(t, KindRate, rate.Kind)
+	assert.Equal(t, []float64{10, 20}, rate.Values)
+
+	latency := byKey["latency{}"]
+	assert.Equal(t, KindMean, latency.Kind)
+	assert.Equal(t, []float64{1, 2}, latency.Values)
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, WriteSummary(out, sums, 10))
+	assert.Regexp(t, `workers\{batcher="a"\}\s+value\s+3\s+1\s+4\s+2.333\s+2\s+▁█▃`, out.String())
+}
+
+func TestSummarizeCounterReset(t *testing.T) {
+	start := time.Now()
+	var recs []Recording
+	for i, v := range []float64{10, 30, 5} { // the process restarts before the last recording
+		recs = append(recs, Recording{Time: start.Add(time.Duration(i) * time.Second), Samples: []Sample{
+			{Name: "msgs_total", Type: "counter", Value: v},
+			{Name: "latency", Type: "histogram", Count: v, Sum: 2 * v},
+		}})
+	}
+	byKey := make(map[string]*SeriesSummary)
+	for _, s := range Summarize(recs) {
+		byKey[s.Series] = s
+	}
+	assert.Equal(t, []float64{20, 5}, byKey["msgs_total{}"].Values)
+	assert.Equal(t, []float64{2, 2}, byKey["latency{}"].Values)
+}
+
+func TestRecordFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "metrics.jsonl")
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: "pending"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	d, err := NewDashboard(reg, Interval(10*time.Millisecond), RecordFile(file), WithOutput(io.Discard))
+	assert.NoError(t, err)
+	d.Run(ctx)
+
+	f, err := os.Open(file)
+	assert.NoError(t, err)
+	defer f.Close()
+	recs, err := ReadRecordings(f)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, len(recs), 2)
+	assert.Equal(t, "pending", recs[0].Samples[0].Name)
+	assert.Equal(t, "gauge", recs[0].Samples[0].Type)
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Sample is the state of a single series. Counters, gauges, and untyped metrics use the Value.
+// Histograms and summaries use the Count and Sum of the observations.
+type Sample struct {
+	Name   string            `json:"name"`
+	Type   string            `json:"type,omitempty"`
+	Labels prometheus.Labels `json:"labels,omitempty"`
+	Value  float64           `json:"value,omitempty"`
+	Count  float64           `json:"count,omitempty"`
+	Sum    float64           `json:"sum,omitempty"`
+}
+
+// Snapshot stores the samples of all gathered series by series string, e.g., `name{label="value"}`.
+type Snapshot map[string]Sample
+
+// Gatherer registers the metrics in a new registry and returns the registry.
+func Gatherer(m Metrics) prometheus.Gatherer {
+	reg := prometheus.NewRegistry()
+	m.Register(reg)
+	return reg
+}
+
+// TakeSnapshot gathers the current samples.
+func TakeSnapshot(g prometheus.Gatherer) (Snapshot, error) {
+	families, err := g.Gather()
+	if err != nil {
+		return nil, err
+	}
+	s := make(Snapshot)
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			smp := Sample{
+				Name:   mf.GetName(),
+				Type:   strings.ToLower(mf.GetType().String()),
+				Labels: make(prometheus.Labels),
+			}
+			for _, l := range m.GetLabel() {
+				smp.Labels[l.GetName()] = l.GetValue()
+			}
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				smp.Value = m.GetCounter().GetValue()
+			case dto.MetricType_GAUGE:
+				smp.Value = m.GetGauge().GetValue()
+			case dto.MetricType_HISTOGRAM:
+				smp.Count = float64(m.GetHistogram().GetSampleCount())
+				smp.Sum = m.GetHistogram().GetSampleSum()
+			case dto.MetricType_SUMMARY:
+				smp.Count = float64(m.GetSummary().GetSampleCount())
+				smp.Sum = m.GetSummary().GetSampleSum()
+			default:
+				smp.Value = m.GetUntyped().GetValue()
+			}
+			s[mf.GetName()+"{"+formatLabels(m.GetLabel())+"}"] = smp
+		}
+	}
+	return s, nil
+}
+
+// Delta returns the difference of all samples to the samples of the previous snapshot.
+// Series that are missing in the previous snapshot are compared to zero.
+func (s Snapshot) Delta(before Snapshot) Snapshot {
+	d := make(Snapshot, len(s))
+	for key, smp := range s {
+		prev := before[key]
+		smp.Value -= prev.Value
+		smp.Count -= prev.Count
+		smp.Sum -= prev.Sum
+		d[key] = smp
+	}
+	return d
+}
+
+// Select returns the sum of all samples of the named metric that have the given labels.
+// Labels that are not given are not compared. It returns false if no series matched.
+func (s Snapshot) Select(name string, labels prometheus.Labels) (Sample, bool) {
+	res := Sample{Name: name, Labels: labels}
+	found := false
+	for _, smp := range s {
+		if smp.Name != name || !hasLabels(smp.Labels, labels) {
+			continue
+		}
+		found = true
+		res.Value += smp.Value
+		res.Count += smp.Count
+		res.Sum += smp.Sum
+	}
+	return res, found
+}
+
+// Series returns the sorted series strings of the snapshot.
+func (s Snapshot) Series() []string {
+	keys := make([]string, 0, len(s))
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func hasLabels(have, want prometheus.Labels) bool {
+	for k, v := range want {
+		if have[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+type testMetrics struct {
+	count *prometheus.CounterVec
+	hist  prometheus.Histogram
+}
+
+func (m *testMetrics) Register(reg prometheus.Registerer) {
+	reg.MustRegister(m.count, m.hist)
+}
+
+func TestSnapshot(t *testing.T) {
+	m := &testMetrics{
+		count: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "count"}, []string{"a", "b"}),
+		hist:  prometheus.NewHistogram(prometheus.HistogramOpts{Name: "hist"}),
+	}
+	g := Gatherer(m)
+	m.count.WithLabelValues("1", "x").Add(3)
+
+	before, err := TakeSnapshot(g)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`count{a="1",b="x"}`, "hist{}"}, before.Series())
+
+	m.count.WithLabelValues("1", "x").Add(2)
+	m.count.WithLabelValues("1", "y").Add(4)
+	m.hist.Observe(1.5)
+	m.hist.Observe(0.5)
+
+	after, err := TakeSnapshot(g)
+	assert.NoError(t, err)
+	d := after.Delta(before)
+
+	smp, ok := d.Select("count", prometheus.Labels{"a": "1", "b": "x"})
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, smp.Value)
+
+	smp, _ = d.Select("count", prometheus.Labels{"a": "1"})
+	assert.Equal(t, 6.0, smp.Value, "partial labels match all series")
+
+	smp, _ = d.Select("hist", nil)
+	assert.Equal(t, 2.0, smp.Count)
+	assert.Equal(t, 2.0, smp.Sum)
+
+	_, ok = d.Select("count", prometheus.Labels{"a": "2"})
+	assert.False(t, ok)
+}
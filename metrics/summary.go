@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Summary kinds describe the values of a SeriesSummary.
+const (
+	KindValue = "value"  // gauge and untyped values
+	KindRate  = "rate/s" // per-second rates of counters
+	KindMean  = "mean"   // mean observation per interval of histograms and summaries
+)
+
+// SeriesSummary summarizes the values of a series over a sequence of recordings.
+// Missing values, e.g., intervals without observations, are stored as NaN and ignored
+// by the statistics.
+type SeriesSummary struct {
+	Series string
+	Kind   string
+	Times  []time.Time
+	Values []float64
+
+	Min, Max, Mean, Last float64
+}
+
+// Summarize converts the recordings into per-series timelines.
+// Gauges use the recorded values, counters use the rate between two recordings, and histograms
+// and summaries use the mean of the observations between two recordings. Decreasing counters
+// are treated as resets, e.g., after a restart, and count from zero like Prometheus `rate()`.
+func Summarize(recs []Recording) []*SeriesSummary {
+	var (
+		byKey = make(map[string]*SeriesSummary)
+		prev  = make(map[string]Sample)
+		prevT = make(map[string]time.Time)
+	)
+	for _, rec := range recs {
+		for _, smp := range rec.Samples {
+			key := smp.Series()
+			s, ok := byKey[key]
+			if !ok {
+				s = &SeriesSummary{Series: key, Kind: kind(smp.Type)}
+				byKey[key] = s
+			}
+			last, seen := prev[key]
+			dt := rec.Time.Sub(prevT[key]).Seconds()
+			prev[key], prevT[key] = smp, rec.Time
+
+			switch s.Kind {
+			case KindValue:
+				s.add(rec.Time, smp.Value)
+			case KindRate:
+				if seen && dt > 0 {
+					if smp.Value < last.Value {
+						last.Value = 0 // counter reset
+					}
+					s.add(rec.Time, (smp.Value-last.Value)/dt)
+				}
+			case KindMean:
+				if !seen {
+					continue
+				}
+				if smp.Count < last.Count {
+					last.Count, last.Sum = 0, 0 // counter reset
+				}
+				if n := smp.Count - last.Count; n > 0 {
+					s.add(rec.Time, (smp.Sum-last.Sum)/n)
+				} else {
+					s.add(rec.Time, math.NaN())
+				}
+			}
+		}
+	}
+
+	res := make([]*SeriesSummary, 0, len(byKey))
+	for _, s := range byKey {
+		s.stats()
+		res = append(res, s)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Series < res[j].Series })
+	return res
+}
+
+func kind(typ string) string {
+	switch typ {
+	case "counter":
+		return KindRate
+	case "histogram", "summary":
+		return KindMean
+	default:
+		return KindValue
+	}
+}
+
+func (s *SeriesSummary) add(t time.Time, v float64) {
+	s.Times = append(s.Times, t)
+	s.Values = append(s.Values, v)
+}
+
+func (s *SeriesSummary) stats() {
+	s.Min, s.Max, s.Mean, s.Last = math.NaN(), math.NaN(), math.NaN(), math.NaN()
+	sum, n := 0.0, 0
+	for _, v := range s.Values {
+		if math.IsNaN(v) {
+			continue
+		}
+		if n == 0 || v < s.Min {
+			s.Min = v
+		}
+		if n == 0 || v > s.Max {
+			s.Max = v
+		}
+		sum += v
+		n++
+		s.Last = v
+	}
+	if n > 0 {
+		s.Mean = sum / float64(n)
+	}
+}
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// Trend returns a sparkline of the values with up to width characters. Values are averaged
+// into width buckets. Buckets without values are shown as space.
+func (s *SeriesSummary) Trend(width int) string {
+	if len(s.Values) == 0 || width <= 0 {
+		return ""
+	}
+	if width > len(s.Values) {
+		width = len(s.Values)
+	}
+	var b strings.Builder
+	for i := 0; i < width; i++ {
+		from, to := i*len(s.Values)/width, (i+1)*len(s.Values)/width
+		sum, n := 0.0, 0
+		for _, v := range s.Values[from:to] {
+			if !math.IsNaN(v) {
+				sum += v
+				n++
+			}
+		}
+		switch {
+		case n == 0:
+			b.WriteRune(' ')
+		case s.Max == s.Min:
+			b.WriteRune(sparks[0])
+		default:
+			level := int((sum/float64(n) - s.Min) / (s.Max - s.Min) * float64(len(sparks)-1))
+			b.WriteRune(sparks[level])
+		}
+	}
+	return b.String()
+}
+
+// WriteSummary writes the summaries as aligned table including a trend of the given width.
+func WriteSummary(w io.Writer, sums []*SeriesSummary, width int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "SERIES\tKIND\tPOINTS\tMIN\tMAX\tMEAN\tLAST\tTREND\t\n")
+	for _, s := range sums {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t\n", s.Series, s.Kind, len(s.Values),
+			formatValue(s.Min), formatValue(s.Max), formatValue(s.Mean), formatValue(s.Last), s.Trend(width))
+	}
+	return tw.Flush()
+}
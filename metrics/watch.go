@@ -2,7 +2,10 @@ package metrics
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log"
+	"regexp"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -13,53 +16,57 @@ type Metrics interface {
 	Register(reg prometheus.Registerer)
 }
 
-// Watch registers the metrics and continuously logs them to the console.
-func Watch(ctx context.Context, m Metrics) {
-	go func() {
-		tick := time.NewTicker(time.Second)
-		defer tick.Stop()
-		log.Print("start watching metrics")
-		defer log.Print("stopped watching metrics")
-		reg := prometheus.NewPedanticRegistry()
-		m.Register(reg)
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-tick.C:
-				printMetrics(reg)
-			}
-		}
-	}()
+// WatchOption configures the dashboard of `Watch`.
+type WatchOption interface {
+	apply(*Dashboard)
+}
+
+// Interval sets the refresh interval of the dashboard.
+type Interval time.Duration
+
+func (opt Interval) apply(d *Dashboard) { d.interval = time.Duration(opt) }
+
+// Filter only shows series that match the regular expression. The expression is matched against
+// the series string `name{label="value",...}`, i.e., a plain metric name also works as filter.
+// Series that match any of the filters are shown. Invalid expressions fail NewDashboard and Watch.
+type Filter string
+
+func (opt Filter) apply(d *Dashboard) {
+	re, err := regexp.Compile(string(opt))
+	if err != nil {
+		d.errs = append(d.errs, fmt.Errorf("invalid filter %q: %w", string(opt), err))
+		return
+	}
+	d.filters = append(d.filters, re)
 }
 
-// printMetrics gathers and prints registered metrics.
-func printMetrics(reg prometheus.Gatherer) {
-	metrics, _ := reg.Gather()
-	for _, mf := range metrics {
-		for _, m := range mf.GetMetric() {
-			val := 0.0
-			count := m.GetCounter().GetValue()
-			gauge := m.GetGauge().GetValue()
-			buckets := m.GetHistogram().GetBucket()
+// ClearScreen clears the terminal before each refresh of the dashboard.
+type ClearScreen bool
+
+func (opt ClearScreen) apply(d *Dashboard) { d.clear = bool(opt) }
+
+type outputOption struct{ io.Writer }
 
-			switch {
-			case gauge != 0:
-				val = gauge
-			case count != 0:
-				val = count
-			case len(buckets) > 0:
-				val = buckets[0].GetExemplar().GetValue()
-			}
+func (opt outputOption) apply(d *Dashboard) { d.out = opt.Writer }
+
+// WithOutput sets the writer of the dashboard. The default output is os.Stderr.
+func WithOutput(w io.Writer) WatchOption {
+	return outputOption{w}
+}
 
-			if val != 0 {
-				labels := m.GetLabel()
-				label := ""
-				if len(labels) > 0 {
-					label = labels[0].GetValue()
-				}
-				log.Printf("%s[%s] %f", mf.GetName(), label, val)
-			}
-		}
+// Watch registers the metrics and continuously prints them as table to the console.
+// It returns an error for invalid options.
+func Watch(ctx context.Context, m Metrics, opts ...WatchOption) error {
+	reg := prometheus.NewPedanticRegistry()
+	m.Register(reg)
+	d, err := NewDashboard(reg, opts...)
+	if err != nil {
+		return err
 	}
+	go func() {
+		log.Print("start watching metrics")
+		defer log.Print("stopped watching metrics")
+		d.Run(ctx)
+	}()
+	return nil
 }
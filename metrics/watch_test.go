@@ -1,15 +1,25 @@
 package metrics
 
 import (
+	"context"
+	"io"
 	"testing"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
 )
 
-func Test_printMetrics(t *testing.T) {
-	c := prometheus.NewCounter(prometheus.CounterOpts{Name: "count"})
-	reg := prometheus.NewPedanticRegistry()
-	reg.Register(c)
-	c.Inc()
-	printMetrics(reg)
+type counterMetrics struct{ c prometheus.Counter }
+
+func (m counterMetrics) Register(reg prometheus.Registerer) { reg.MustRegister(m.c) }
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := counterMetrics{prometheus.NewCounter(prometheus.CounterOpts{Name: "count"})}
+	m.c.Inc()
+	assert.NoError(t, Watch(ctx, m, Filter("count"), WithOutput(io.Discard)))
+
+	m = counterMetrics{prometheus.NewCounter(prometheus.CounterOpts{Name: "count"})}
+	assert.ErrorContains(t, Watch(ctx, m, Filter("count(")), `invalid filter "count("`)
 }